@@ -1,28 +1,262 @@
 package admin
 
 import (
-	"errors"
+	"database/sql"
 	"fmt"
 	"net/http"
 	"net/http/httptest"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/bytesbanana/assessment-tax/money"
 	"github.com/bytesbanana/assessment-tax/postgres"
 	"github.com/labstack/echo/v4"
 )
 
 type StubAdminHandler struct {
-	Configs map[string]*postgres.TaxConfig
+	Configs   map[string]*postgres.TaxConfig
+	Schedules []postgres.TaxConfig
+	History   []postgres.TaxConfigHistory
+	Proposals []postgres.TaxConfigProposal
+	Users     map[string]*postgres.AdminUser
+	Revoked   map[string]time.Time
+	APIKeys   []postgres.APIKey
+	// ProposalErr fails CreateTaxConfigProposal when set.
+	ProposalErr error
+	// ConfigAt is the date of the last GetTaxConfig.
+	ConfigAt time.Time
 }
 
-func (h *StubAdminHandler) SetTaxConfig(key string, value float64) (*postgres.TaxConfig, error) {
+func (h *StubAdminHandler) record(config postgres.TaxConfig, oldValue *money.Money, audit postgres.ConfigAudit) {
+	var approvedBy *string
+	if audit.ApprovedBy != "" {
+		approvedBy = &audit.ApprovedBy
+	}
+	h.History = append(h.History, postgres.TaxConfigHistory{
+		ID:            len(h.History) + 1,
+		TaxYear:       config.TaxYear,
+		Key:           config.Key,
+		EffectiveFrom: config.EffectiveFrom,
+		EffectiveTo:   config.EffectiveTo,
+		OldValue:      oldValue,
+		NewValue:      config.Value,
+		Action:        audit.Action,
+		Reason:        audit.Reason,
+		ChangedBy:     audit.Actor,
+		ApprovedBy:    approvedBy,
+	})
+}
+
+func (h *StubAdminHandler) SetTaxConfig(taxYear int, key string, value money.Money, audit postgres.ConfigAudit) (*postgres.TaxConfig, error) {
 	if h.Configs[key] != nil {
+		oldValue := h.Configs[key].Value
 		h.Configs[key].Value = value
+		h.Configs[key].UpdatedBy = &audit.Actor
+		h.record(*h.Configs[key], &oldValue, audit)
+		return h.Configs[key], nil
+	}
+
+	return nil, sql.ErrNoRows
+}
+
+func (h *StubAdminHandler) ScheduleTaxConfig(taxYear int, key string, value money.Money, from time.Time, to *time.Time, audit postgres.ConfigAudit) (*postgres.TaxConfig, error) {
+	if h.Configs[key] == nil {
+		return nil, sql.ErrNoRows
+	}
+
+	config := postgres.TaxConfig{TaxYear: taxYear, Key: key, Value: value, EffectiveFrom: &from, EffectiveTo: to}
+	h.Schedules = append(h.Schedules, config)
+	h.record(config, nil, audit)
+	return &config, nil
+}
+
+func (h *StubAdminHandler) GetTaxConfigHistory(taxYear int, key string) ([]postgres.TaxConfigHistory, error) {
+	history := []postgres.TaxConfigHistory{}
+	for i := len(h.History) - 1; i >= 0; i-- {
+		if h.History[i].TaxYear == taxYear && h.History[i].Key == key {
+			history = append(history, h.History[i])
+		}
+	}
+	return history, nil
+}
+
+func (h *StubAdminHandler) GetTaxConfigChange(id int) (*postgres.TaxConfigHistory, error) {
+	if id < 1 || id > len(h.History) {
+		return nil, sql.ErrNoRows
+	}
+	change := h.History[id-1]
+	return &change, nil
+}
+
+func (h *StubAdminHandler) GetTaxConfig(taxYear int, key string, at time.Time) (*postgres.TaxConfig, error) {
+	h.ConfigAt = at
+	if h.Configs[key] != nil {
 		return h.Configs[key], nil
 	}
 
-	return nil, errors.New("config not found")
+	return nil, sql.ErrNoRows
+}
+
+func (h *StubAdminHandler) ListTaxConfigs(taxYear int) ([]postgres.TaxConfig, error) {
+	configs := []postgres.TaxConfig{}
+	for _, config := range h.Configs {
+		configs = append(configs, *config)
+	}
+	return configs, nil
+}
+
+func (h *StubAdminHandler) CreateTaxConfigProposal(proposal postgres.TaxConfigProposal) (*postgres.TaxConfigProposal, error) {
+	if h.ProposalErr != nil {
+		return nil, h.ProposalErr
+	}
+	proposal.ID = len(h.Proposals) + 1
+	proposal.Status = postgres.PROPOSAL_STATUS_PENDING
+	h.Proposals = append(h.Proposals, proposal)
+	return &proposal, nil
+}
+
+func (h *StubAdminHandler) GetTaxConfigProposal(id int) (*postgres.TaxConfigProposal, error) {
+	if id < 1 || id > len(h.Proposals) {
+		return nil, sql.ErrNoRows
+	}
+	proposal := h.Proposals[id-1]
+	return &proposal, nil
+}
+
+func (h *StubAdminHandler) ListTaxConfigProposals(status string) ([]postgres.TaxConfigProposal, error) {
+	proposals := []postgres.TaxConfigProposal{}
+	for i := len(h.Proposals) - 1; i >= 0; i-- {
+		if status == "" || h.Proposals[i].Status == status {
+			proposals = append(proposals, h.Proposals[i])
+		}
+	}
+	return proposals, nil
+}
+
+func (h *StubAdminHandler) ExpireTaxConfigProposals(at time.Time) error {
+	for i := range h.Proposals {
+		if h.Proposals[i].Status == postgres.PROPOSAL_STATUS_PENDING && !h.Proposals[i].ExpiresAt.After(at) {
+			h.Proposals[i].Status = postgres.PROPOSAL_STATUS_EXPIRED
+		}
+	}
+	return nil
+}
+
+func (h *StubAdminHandler) ApproveTaxConfigProposal(id int, reviewer string, at time.Time) (*postgres.TaxConfig, error) {
+	if id < 1 || id > len(h.Proposals) {
+		return nil, sql.ErrNoRows
+	}
+	proposal := &h.Proposals[id-1]
+	if proposal.Status != postgres.PROPOSAL_STATUS_PENDING || !proposal.ExpiresAt.After(at) {
+		return nil, sql.ErrNoRows
+	}
+	proposal.Status = postgres.PROPOSAL_STATUS_APPROVED
+	proposal.ReviewedBy = &reviewer
+
+	audit := postgres.ConfigAudit{Actor: proposal.ProposedBy, ApprovedBy: reviewer, Action: proposal.Action, Reason: proposal.Reason}
+	if proposal.EffectiveFrom != nil {
+		return h.ScheduleTaxConfig(proposal.TaxYear, proposal.Key, proposal.Value, *proposal.EffectiveFrom, proposal.EffectiveTo, audit)
+	}
+	return h.SetTaxConfig(proposal.TaxYear, proposal.Key, proposal.Value, audit)
+}
+
+func (h *StubAdminHandler) RejectTaxConfigProposal(id int, reviewer string, comment string) (*postgres.TaxConfigProposal, error) {
+	if id < 1 || id > len(h.Proposals) || h.Proposals[id-1].Status != postgres.PROPOSAL_STATUS_PENDING {
+		return nil, sql.ErrNoRows
+	}
+	proposal := &h.Proposals[id-1]
+	proposal.Status = postgres.PROPOSAL_STATUS_REJECTED
+	proposal.ReviewedBy = &reviewer
+	proposal.ReviewComment = comment
+	rejected := *proposal
+	return &rejected, nil
+}
+
+func (h *StubAdminHandler) GetAdminUser(username string) (*postgres.AdminUser, error) {
+	if h.Users[username] == nil {
+		return nil, sql.ErrNoRows
+	}
+	user := *h.Users[username]
+	return &user, nil
+}
+
+func (h *StubAdminHandler) ListAdminUsers() ([]postgres.AdminUser, error) {
+	users := []postgres.AdminUser{}
+	for _, user := range h.Users {
+		users = append(users, *user)
+	}
+	return users, nil
+}
+
+func (h *StubAdminHandler) CountAdminUsers() (int, error) {
+	return len(h.Users), nil
+}
+
+func (h *StubAdminHandler) CreateAdminUser(user postgres.AdminUser) (*postgres.AdminUser, error) {
+	if h.Users[user.Username] != nil {
+		return nil, postgres.ErrAdminUserExists
+	}
+	if h.Users == nil {
+		h.Users = map[string]*postgres.AdminUser{}
+	}
+	user.ID = len(h.Users) + 1
+	h.Users[user.Username] = &user
+	created := user
+	return &created, nil
+}
+
+func (h *StubAdminHandler) UpdateAdminUser(user postgres.AdminUser) (*postgres.AdminUser, error) {
+	if h.Users[user.Username] == nil {
+		return nil, sql.ErrNoRows
+	}
+	h.Users[user.Username] = &user
+	updated := user
+	return &updated, nil
+}
+
+func (h *StubAdminHandler) RevokeToken(id string, expiresAt time.Time, revokedBy string) (bool, error) {
+	if h.Revoked == nil {
+		h.Revoked = map[string]time.Time{}
+	}
+	if _, ok := h.Revoked[id]; ok {
+		return false, nil
+	}
+	h.Revoked[id] = expiresAt
+	return true, nil
+}
+
+func (h *StubAdminHandler) IsTokenRevoked(id string) (bool, error) {
+	_, ok := h.Revoked[id]
+	return ok, nil
+}
+
+func (h *StubAdminHandler) CreateAPIKey(key postgres.APIKey) (*postgres.APIKey, error) {
+	key.ID = len(h.APIKeys) + 1
+	h.APIKeys = append(h.APIKeys, key)
+	return &key, nil
+}
+
+func (h *StubAdminHandler) ListAPIKeys() ([]postgres.APIKey, error) {
+	return append([]postgres.APIKey{}, h.APIKeys...), nil
+}
+
+func (h *StubAdminHandler) RevokeAPIKey(id int, revokedBy string) (*postgres.APIKey, error) {
+	if id < 1 || id > len(h.APIKeys) {
+		return nil, sql.ErrNoRows
+	}
+	key := &h.APIKeys[id-1]
+	if key.RevokedAt == nil {
+		now := time.Now()
+		key.RevokedAt = &now
+		key.RevokedBy = &revokedBy
+	}
+	revoked := *key
+	return &revoked, nil
+}
+
+func (h *StubAdminHandler) GetAPIKeyUsage(id int) ([]postgres.APIKeyUsage, error) {
+	return []postgres.APIKeyUsage{}, nil
 }
 
 func TestPersonalDeduction(t *testing.T) {
@@ -35,7 +269,7 @@ func TestPersonalDeduction(t *testing.T) {
 
 		handler := New(&StubAdminHandler{
 			Configs: map[string]*postgres.TaxConfig{},
-		})
+		}, nil)
 
 		err := handler.SetPersonalDeductionsConfig(c)
 		if err != nil {
@@ -49,7 +283,7 @@ func TestPersonalDeduction(t *testing.T) {
 
 	})
 
-	t.Run("given new personal deduction amount should update personal deduction", func(t *testing.T) {
+	t.Run("given new personal deduction amount should propose it for approval", func(t *testing.T) {
 
 		e := echo.New()
 		reqJSON := fmt.Sprintf(`{"amount": %f}`, 70000.0)
@@ -63,25 +297,30 @@ func TestPersonalDeduction(t *testing.T) {
 				"PERSONAL_DEDUCTION": {
 					Key:   "PERSONAL_DEDUCTION",
 					Name:  "Personal Deduction",
-					Value: 60_000,
+					Value: money.FromBaht(60_000),
 				},
 			},
 		}
-		handler := New(stubAdminHandler)
+		handler := New(stubAdminHandler, nil)
 
 		err := handler.SetPersonalDeductionsConfig(c)
 		if err != nil {
 			t.Errorf("unable to set personal deduction: %v", err)
 		}
 
-		if rec.Code != http.StatusOK {
+		if rec.Code != http.StatusAccepted {
 			t.Errorf("invalid http status: got %v want %v",
-				rec.Code, http.StatusOK)
+				rec.Code, http.StatusAccepted)
 		}
 
-		if stubAdminHandler.Configs["PERSONAL_DEDUCTION"].Value != 70000.0 {
-			t.Errorf("invalid personal deduction amount: got %v want %v",
-				stubAdminHandler.Configs["PERSONAL_DEDUCTION"].Value, 70000.0)
+		if stubAdminHandler.Configs["PERSONAL_DEDUCTION"].Value != money.FromBaht(60_000) {
+			t.Errorf("personal deduction changed before approval: got %v want %v",
+				stubAdminHandler.Configs["PERSONAL_DEDUCTION"].Value, money.FromBaht(60_000))
+		}
+
+		if len(stubAdminHandler.Proposals) != 1 || stubAdminHandler.Proposals[0].Value != money.FromBaht(70_000) {
+			t.Errorf("invalid proposals: got %+v want one proposal of %v",
+				stubAdminHandler.Proposals, money.FromBaht(70_000))
 		}
 
 	})
@@ -100,11 +339,11 @@ func TestPersonalDeduction(t *testing.T) {
 				"PERSONAL_DEDUCTION": {
 					Key:   "PERSONAL_DEDUCTION",
 					Name:  "Personal Deduction",
-					Value: 60_000,
+					Value: money.FromBaht(60_000),
 				},
 			},
 		}
-		handler := New(stubAdminHandler)
+		handler := New(stubAdminHandler, nil)
 
 		err := handler.SetPersonalDeductionsConfig(c)
 		if err != nil {
@@ -132,11 +371,11 @@ func TestPersonalDeduction(t *testing.T) {
 				"PERSONAL_DEDUCTION": {
 					Key:   "PERSONAL_DEDUCTION",
 					Name:  "Personal Deduction",
-					Value: 60_000,
+					Value: money.FromBaht(60_000),
 				},
 			},
 		}
-		handler := New(stubAdminHandler)
+		handler := New(stubAdminHandler, nil)
 
 		err := handler.SetPersonalDeductionsConfig(c)
 		if err != nil {
@@ -162,7 +401,7 @@ func TestMaxKReceiptDeduction(t *testing.T) {
 
 		handler := New(&StubAdminHandler{
 			Configs: map[string]*postgres.TaxConfig{},
-		})
+		}, nil)
 
 		err := handler.SetMaxKReceiptDeduction(c)
 		if err != nil {
@@ -176,7 +415,7 @@ func TestMaxKReceiptDeduction(t *testing.T) {
 
 	})
 
-	t.Run("given new k-receipt deduction amount should update personal deduction", func(t *testing.T) {
+	t.Run("given new k-receipt deduction amount should propose it for approval", func(t *testing.T) {
 
 		e := echo.New()
 		reqJSON := fmt.Sprintf(`{"amount": %f}`, 70000.0)
@@ -190,25 +429,30 @@ func TestMaxKReceiptDeduction(t *testing.T) {
 				"MAX_K_RECEIPT_DEDUCTION": {
 					Key:   "MAX_K_RECEIPT_DEDUCTION",
 					Name:  "Max k-receipt deduction",
-					Value: 50_000,
+					Value: money.FromBaht(50_000),
 				},
 			},
 		}
-		handler := New(stubAdminHandler)
+		handler := New(stubAdminHandler, nil)
 
 		err := handler.SetMaxKReceiptDeduction(c)
 		if err != nil {
 			t.Errorf("unable to set max k-receipt deduction: %v", err)
 		}
 
-		if rec.Code != http.StatusOK {
+		if rec.Code != http.StatusAccepted {
 			t.Errorf("invalid http status: got %v want %v",
-				rec.Code, http.StatusOK)
+				rec.Code, http.StatusAccepted)
+		}
+
+		if stubAdminHandler.Configs["MAX_K_RECEIPT_DEDUCTION"].Value != money.FromBaht(50_000) {
+			t.Errorf("k-receipt deduction changed before approval: got %v want %v",
+				stubAdminHandler.Configs["MAX_K_RECEIPT_DEDUCTION"].Value, money.FromBaht(50_000))
 		}
 
-		if stubAdminHandler.Configs["MAX_K_RECEIPT_DEDUCTION"].Value != 70000.0 {
-			t.Errorf("invalid personal deduction amount: got %v want %v",
-				stubAdminHandler.Configs["PERSONAL_DEDUCTION"].Value, 70000.0)
+		if len(stubAdminHandler.Proposals) != 1 || stubAdminHandler.Proposals[0].Value != money.FromBaht(70_000) {
+			t.Errorf("invalid proposals: got %+v want one proposal of %v",
+				stubAdminHandler.Proposals, money.FromBaht(70_000))
 		}
 
 	})
@@ -227,11 +471,11 @@ func TestMaxKReceiptDeduction(t *testing.T) {
 				"MAX_K_RECEIPT_DEDUCTION": {
 					Key:   "MAX_K_RECEIPT_DEDUCTION",
 					Name:  "Max k-receipt deduction",
-					Value: 50_000,
+					Value: money.FromBaht(50_000),
 				},
 			},
 		}
-		handler := New(stubAdminHandler)
+		handler := New(stubAdminHandler, nil)
 
 		err := handler.SetMaxKReceiptDeduction(c)
 		if err != nil {
@@ -259,11 +503,11 @@ func TestMaxKReceiptDeduction(t *testing.T) {
 				"MAX_K_RECEIPT_DEDUCTION": {
 					Key:   "MAX_K_RECEIPT_DEDUCTION",
 					Name:  "Max k-receipt deduction",
-					Value: 50_000,
+					Value: money.FromBaht(50_000),
 				},
 			},
 		}
-		handler := New(stubAdminHandler)
+		handler := New(stubAdminHandler, nil)
 
 		err := handler.SetMaxKReceiptDeduction(c)
 		if err != nil {
@@ -0,0 +1,217 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/apikey"
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/labstack/echo/v4"
+)
+
+var ErrAPIKeyNotFound = errors.New("API key not found")
+
+type (
+	// CreateAPIKeyRequest issues a key with the scopes, limited to RateLimit requests per
+	// minute, apikey.DEFAULT_RATE_LIMIT by default, and valid until ExpiresAt if set.
+	CreateAPIKeyRequest struct {
+		Name      string     `json:"name"`
+		Scopes    []string   `json:"scopes"`
+		RateLimit *int       `json:"rateLimit,omitempty"`
+		ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	}
+
+	APIKeyResponse struct {
+		ID     int    `json:"id"`
+		Name   string `json:"name"`
+		Prefix string `json:"prefix"`
+		// Key is returned only when the key is created.
+		Key          string     `json:"key,omitempty"`
+		Scopes       []string   `json:"scopes"`
+		RateLimit    int        `json:"rateLimit"`
+		ExpiresAt    *time.Time `json:"expiresAt,omitempty"`
+		CreatedBy    *string    `json:"createdBy,omitempty"`
+		CreatedAt    *time.Time `json:"createdAt,omitempty"`
+		RevokedBy    *string    `json:"revokedBy,omitempty"`
+		RevokedAt    *time.Time `json:"revokedAt,omitempty"`
+		RequestCount int64      `json:"requestCount"`
+		LastUsedAt   *time.Time `json:"lastUsedAt,omitempty"`
+	}
+
+	APIKeyUsageResponse struct {
+		Endpoint string `json:"endpoint"`
+		Day      string `json:"day"`
+		Count    int64  `json:"count"`
+	}
+)
+
+func newAPIKeyResponse(key postgres.APIKey) APIKeyResponse {
+	return APIKeyResponse{
+		ID:           key.ID,
+		Name:         key.Name,
+		Prefix:       key.Prefix,
+		Scopes:       key.Scopes,
+		RateLimit:    key.RateLimit,
+		ExpiresAt:    key.ExpiresAt,
+		CreatedBy:    key.CreatedBy,
+		CreatedAt:    key.CreatedAt,
+		RevokedBy:    key.RevokedBy,
+		RevokedAt:    key.RevokedAt,
+		RequestCount: key.RequestCount,
+		LastUsedAt:   key.LastUsedAt,
+	}
+}
+
+func (r CreateAPIKeyRequest) validate(now time.Time) error {
+	if strings.TrimSpace(r.Name) == "" {
+		return errors.New("name is required")
+	}
+	if len(r.Scopes) == 0 {
+		return errors.New("scopes are required")
+	}
+	for _, scope := range r.Scopes {
+		if !slices.Contains(apikey.Scopes, scope) {
+			return fmt.Errorf("scope must be one of %v", apikey.Scopes)
+		}
+	}
+	if r.RateLimit != nil && (*r.RateLimit < 1 || *r.RateLimit > apikey.MAX_RATE_LIMIT) {
+		return fmt.Errorf("rateLimit must be between 1 and %d", apikey.MAX_RATE_LIMIT)
+	}
+	if r.ExpiresAt != nil && !r.ExpiresAt.After(now) {
+		return errors.New("expiresAt must be in the future")
+	}
+	return nil
+}
+
+// CreateAPIKey issues a key. The key is returned once and only its hash is stored.
+func (h *Handler) CreateAPIKey(c echo.Context) error {
+	var req CreateAPIKeyRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	err = req.validate(time.Now())
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	key, prefix, hash, err := apikey.Generate()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to create API key",
+		})
+	}
+
+	rateLimit := apikey.DEFAULT_RATE_LIMIT
+	if req.RateLimit != nil {
+		rateLimit = *req.RateLimit
+	}
+	slices.Sort(req.Scopes)
+	createdBy := actor(c)
+	created, err := h.store.CreateAPIKey(postgres.APIKey{
+		Name:      strings.TrimSpace(req.Name),
+		Prefix:    prefix,
+		KeyHash:   hash,
+		Scopes:    slices.Compact(req.Scopes),
+		RateLimit: rateLimit,
+		ExpiresAt: req.ExpiresAt,
+		CreatedBy: &createdBy,
+	})
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to create API key",
+		})
+	}
+
+	res := newAPIKeyResponse(*created)
+	res.Key = key
+	return c.JSON(http.StatusCreated, res)
+}
+
+func (h *Handler) ListAPIKeys(c echo.Context) error {
+	keys, err := h.store.ListAPIKeys()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to list API keys",
+		})
+	}
+
+	res := []APIKeyResponse{}
+	for _, key := range keys {
+		res = append(res, newAPIKeyResponse(key))
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		APIKeys []APIKeyResponse `json:"apiKeys"`
+	}{
+		APIKeys: res,
+	})
+}
+
+// RevokeAPIKey rejects further requests with the key. Revoking a revoked key keeps its
+// first revocation.
+func (h *Handler) RevokeAPIKey(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid API key id",
+		})
+	}
+
+	key, err := h.store.RevokeAPIKey(id, actor(c))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: ErrAPIKeyNotFound.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to revoke API key",
+		})
+	}
+
+	return c.JSON(http.StatusOK, newAPIKeyResponse(*key))
+}
+
+// GetAPIKeyUsage returns the request counts of the key by endpoint and day, the latest day first.
+func (h *Handler) GetAPIKeyUsage(c echo.Context) error {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid API key id",
+		})
+	}
+
+	usage, err := h.store.GetAPIKeyUsage(id)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to get API key usage",
+		})
+	}
+
+	res := []APIKeyUsageResponse{}
+	for _, u := range usage {
+		res = append(res, APIKeyUsageResponse{
+			Endpoint: u.Endpoint,
+			Day:      formatDate(&u.Day),
+			Count:    u.Count,
+		})
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		Usage []APIKeyUsageResponse `json:"usage"`
+	}{
+		Usage: res,
+	})
+}
@@ -0,0 +1,121 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/apikey"
+)
+
+func TestCreateAPIKey(t *testing.T) {
+	t.Run("given valid request should return the key once and store its hash", func(t *testing.T) {
+		c, rec := configContext(http.MethodPost, "/admin/api-keys", `{"name": "partner bank", "scopes": ["tax:upload", "tax:calculate", "tax:upload"]}`, "")
+		c.Set(ADMIN_USERNAME_CONTEXT_KEY, "root")
+
+		stub := &StubAdminHandler{}
+		handler := New(stub, nil)
+		err := handler.CreateAPIKey(c)
+		if err != nil {
+			t.Errorf("unable to create API key: %v", err)
+		}
+
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusCreated)
+		}
+
+		var res APIKeyResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		if !strings.HasPrefix(res.Key, apikey.KEY_PREFIX) || res.Prefix != res.Key[:apikey.PREFIX_LENGTH] {
+			t.Errorf("invalid key: got %v with prefix %v", res.Key, res.Prefix)
+		}
+		if res.RateLimit != apikey.DEFAULT_RATE_LIMIT || len(res.Scopes) != 2 || *res.CreatedBy != "root" {
+			t.Errorf("invalid API key: got %+v", res)
+		}
+		stored := stub.APIKeys[0]
+		if stored.KeyHash != apikey.Hash(res.Key) || strings.Contains(stored.KeyHash, res.Key) {
+			t.Errorf("stored key should be the hash of the key")
+		}
+
+		c, rec = configContext(http.MethodGet, "/admin/api-keys", "", "")
+		err = handler.ListAPIKeys(c)
+		if err != nil {
+			t.Errorf("unable to list API keys: %v", err)
+		}
+		if strings.Contains(rec.Body.String(), res.Key) || strings.Contains(rec.Body.String(), stored.KeyHash) {
+			t.Errorf("listed API keys should not contain the key or its hash")
+		}
+	})
+
+	tests := []struct {
+		name string
+		body string
+	}{
+		{"given missing name should return 400", `{"scopes": ["tax:calculate"]}`},
+		{"given no scopes should return 400", `{"name": "partner bank", "scopes": []}`},
+		{"given unknown scope should return 400", `{"name": "partner bank", "scopes": ["admin"]}`},
+		{"given rate limit 0 should return 400", `{"name": "partner bank", "scopes": ["tax:calculate"], "rateLimit": 0}`},
+		{"given past expiry should return 400", `{"name": "partner bank", "scopes": ["tax:calculate"], "expiresAt": "2020-01-01T00:00:00Z"}`},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := configContext(http.MethodPost, "/admin/api-keys", tt.body, "")
+
+			handler := New(&StubAdminHandler{}, nil)
+			err := handler.CreateAPIKey(c)
+			if err != nil {
+				t.Errorf("unable to create API key: %v", err)
+			}
+
+			if rec.Code != http.StatusBadRequest {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+			}
+		})
+	}
+}
+
+func TestRevokeAPIKey(t *testing.T) {
+	tests := []struct {
+		name string
+		id   string
+		want int
+	}{
+		{"given existing key should revoke it", "1", http.StatusOK},
+		{"given unknown key should return 404", "2", http.StatusNotFound},
+		{"given invalid id should return 400", "one", http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			stub := &StubAdminHandler{}
+			handler := New(stub, nil)
+			c, _ := configContext(http.MethodPost, "/admin/api-keys", `{"name": "partner bank", "scopes": ["tax:calculate"]}`, "")
+			err := handler.CreateAPIKey(c)
+			if err != nil {
+				t.Fatalf("unable to create API key: %v", err)
+			}
+
+			c, rec := configContext(http.MethodDelete, "/admin/api-keys/"+tt.id, "", "")
+			c.SetParamNames("id")
+			c.SetParamValues(tt.id)
+			c.Set(ADMIN_USERNAME_CONTEXT_KEY, "root")
+			err = handler.RevokeAPIKey(c)
+			if err != nil {
+				t.Errorf("unable to revoke API key: %v", err)
+			}
+
+			if rec.Code != tt.want {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, tt.want)
+			}
+			if tt.want == http.StatusOK && (stub.APIKeys[0].RevokedAt == nil || *stub.APIKeys[0].RevokedBy != "root") {
+				t.Errorf("API key should be revoked by root: got %+v", stub.APIKeys[0])
+			}
+		})
+	}
+}
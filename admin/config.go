@@ -0,0 +1,282 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/bytesbanana/assessment-tax/tax"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	CONFIG_TYPE_AMOUNT  = "amount"
+	CONFIG_TYPE_PERCENT = "percent"
+)
+
+var ErrConfigNotFound = errors.New("config not found")
+
+type (
+	// ConfigDefinition declares an admin-editable config key, its type and the
+	// values the admin API accepts for it.
+	ConfigDefinition struct {
+		Key  string
+		Name string
+		Type string
+		Min  money.Money
+		Max  money.Money
+	}
+
+	ConfigResponse struct {
+		Key       string      `json:"key"`
+		Name      string      `json:"name"`
+		Type      string      `json:"type"`
+		TaxYear   int         `json:"taxYear"`
+		Value     money.Money `json:"value"`
+		Min       money.Money `json:"min"`
+		Max       money.Money `json:"max"`
+		UpdatedAt *time.Time  `json:"updatedAt,omitempty"`
+		UpdatedBy *string     `json:"updatedBy,omitempty"`
+		// EffectiveFrom and EffectiveTo are set on scheduled values.
+		EffectiveFrom string `json:"effectiveFrom,omitempty"`
+		EffectiveTo   string `json:"effectiveTo,omitempty"`
+	}
+)
+
+var (
+	configRegistry = map[string]ConfigDefinition{}
+	configKeys     []string
+)
+
+// RegisterConfig makes the key editable through the admin config API.
+// Registering the same key twice panics.
+func RegisterConfig(definition ConfigDefinition) {
+	if _, ok := configRegistry[definition.Key]; ok {
+		panic(fmt.Sprintf("config %s is already registered", definition.Key))
+	}
+	configRegistry[definition.Key] = definition
+	configKeys = append(configKeys, definition.Key)
+}
+
+func LookupConfig(key string) (ConfigDefinition, bool) {
+	definition, ok := configRegistry[key]
+	return definition, ok
+}
+
+func (d ConfigDefinition) validate(value money.Money) error {
+	if value < d.Min || value > d.Max {
+		return fmt.Errorf("amount must be between %s and %s", tax.FormatAmount(d.Min), tax.FormatAmount(d.Max))
+	}
+	return nil
+}
+
+func (d ConfigDefinition) response(config postgres.TaxConfig) ConfigResponse {
+	return ConfigResponse{
+		Key:           d.Key,
+		Name:          d.Name,
+		Type:          d.Type,
+		TaxYear:       config.TaxYear,
+		Value:         config.Value,
+		Min:           d.Min,
+		Max:           d.Max,
+		UpdatedAt:     config.UpdatedAt,
+		UpdatedBy:     config.UpdatedBy,
+		EffectiveFrom: formatDate(config.EffectiveFrom),
+		EffectiveTo:   formatDate(config.EffectiveTo),
+	}
+}
+
+func formatDate(date *time.Time) string {
+	if date == nil {
+		return ""
+	}
+	return date.Format(tax.DATE_FORMAT)
+}
+
+// effectiveDates parses the schedule of the request, from is nil for a base value change.
+func (r SetConfigValueRequest) effectiveDates() (from *time.Time, to *time.Time, err error) {
+	parse := func(name, value string) (*time.Time, error) {
+		if value == "" {
+			return nil, nil
+		}
+		date, err := time.Parse(tax.DATE_FORMAT, value)
+		if err != nil {
+			return nil, fmt.Errorf("%s must be formatted as YYYY-MM-DD", name)
+		}
+		return &date, nil
+	}
+
+	from, err = parse("effectiveFrom", r.EffectiveFrom)
+	if err != nil {
+		return nil, nil, err
+	}
+	to, err = parse("effectiveTo", r.EffectiveTo)
+	if err != nil {
+		return nil, nil, err
+	}
+	if to != nil && from == nil {
+		return nil, nil, errors.New("effectiveTo requires effectiveFrom")
+	}
+	if to != nil && to.Before(*from) {
+		return nil, nil, errors.New("effectiveTo must not be before effectiveFrom")
+	}
+	return from, to, nil
+}
+
+func validateConfigValue(key string, value money.Money) error {
+	definition, ok := LookupConfig(key)
+	if !ok {
+		return fmt.Errorf("config %s cannot be changed", key)
+	}
+	return definition.validate(value)
+}
+
+// taxYearParam reads the taxYear query parameter, defaulting to tax.DEFAULT_TAX_YEAR.
+func taxYearParam(c echo.Context) (int, error) {
+	if c.QueryParam("taxYear") == "" {
+		return tax.DEFAULT_TAX_YEAR, nil
+	}
+	taxYear, err := strconv.Atoi(c.QueryParam("taxYear"))
+	if err != nil {
+		return 0, errors.New("invalid taxYear parameter")
+	}
+	return taxYear, nil
+}
+
+// proposeConfig validates the requested value of the config key and stores it as a pending
+// proposal. On failure it returns the HTTP status to respond with.
+func (h *Handler) proposeConfig(c echo.Context, key string) (*postgres.TaxConfigProposal, int, error) {
+	var req SetConfigValueRequest
+	err := c.Bind(&req)
+	if err != nil || req.Amount == nil {
+		return nil, http.StatusBadRequest, errors.New("invalid request body")
+	}
+
+	from, to, err := req.effectiveDates()
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	proposal := postgres.TaxConfigProposal{
+		TaxYear:       req.taxYear(),
+		Key:           key,
+		Value:         *req.Amount,
+		EffectiveFrom: from,
+		EffectiveTo:   to,
+		Action:        postgres.CONFIG_ACTION_UPDATE,
+		Reason:        req.Reason,
+	}
+	if from != nil {
+		proposal.Action = postgres.CONFIG_ACTION_SCHEDULE
+	}
+
+	return h.propose(c, proposal)
+}
+
+// GetConfig returns the value in force on the date of the at query parameter, a date of the
+// tax year, by default tax.DefaultTaxDate.
+func (h *Handler) GetConfig(c echo.Context) error {
+	definition, ok := LookupConfig(c.Param("key"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: ErrConfigNotFound.Error(),
+		})
+	}
+
+	taxYear, err := taxYearParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	at := tax.DefaultTaxDate(taxYear, time.Now())
+	if c.QueryParam("at") != "" {
+		at, err = time.Parse(tax.DATE_FORMAT, c.QueryParam("at"))
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Err{
+				Message: "at must be formatted as YYYY-MM-DD",
+			})
+		}
+		first, last := tax.TaxYearDates(taxYear)
+		if at.Before(first) || at.After(last) {
+			return c.JSON(http.StatusBadRequest, &Err{
+				Message: fmt.Sprintf("at must be within tax year %d", taxYear),
+			})
+		}
+	}
+
+	config, err := h.store.GetTaxConfig(taxYear, definition.Key, at)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && config == nil) {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: ErrConfigNotFound.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to get config",
+		})
+	}
+
+	return c.JSON(http.StatusOK, definition.response(*config))
+}
+
+// SetConfig proposes the change, which applies once a different admin approves it.
+func (h *Handler) SetConfig(c echo.Context) error {
+	definition, ok := LookupConfig(c.Param("key"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: ErrConfigNotFound.Error(),
+		})
+	}
+
+	proposal, status, err := h.proposeConfig(c, definition.Key)
+	if err != nil {
+		return c.JSON(status, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, newProposalResponse(*proposal))
+}
+
+// ListConfigs returns the registered configs stored for the tax year in registration order,
+// each base value followed by its scheduled values.
+func (h *Handler) ListConfigs(c echo.Context) error {
+	taxYear, err := taxYearParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	configs, err := h.store.ListTaxConfigs(taxYear)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to list configs",
+		})
+	}
+
+	stored := map[string][]postgres.TaxConfig{}
+	for _, config := range configs {
+		stored[config.Key] = append(stored[config.Key], config)
+	}
+
+	res := []ConfigResponse{}
+	for _, key := range configKeys {
+		for _, config := range stored[key] {
+			res = append(res, configRegistry[key].response(config))
+		}
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		Configs []ConfigResponse `json:"configs"`
+	}{
+		Configs: res,
+	})
+}
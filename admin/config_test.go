@@ -0,0 +1,209 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/bytesbanana/assessment-tax/tax"
+	"github.com/labstack/echo/v4"
+)
+
+func newConfigStub() *StubAdminHandler {
+	return &StubAdminHandler{
+		Configs: map[string]*postgres.TaxConfig{
+			"PERSONAL_DEDUCTION": {
+				TaxYear: tax.DEFAULT_TAX_YEAR,
+				Key:     "PERSONAL_DEDUCTION",
+				Value:   money.FromBaht(60_000),
+			},
+			"MAX_SSF_DEDUCTION": {
+				TaxYear: tax.DEFAULT_TAX_YEAR,
+				Key:     "MAX_SSF_DEDUCTION",
+				Value:   money.FromBaht(200_000),
+			},
+		},
+	}
+}
+
+func configContext(method, target, body, key string) (echo.Context, *httptest.ResponseRecorder) {
+	e := echo.New()
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	if key != "" {
+		c.SetParamNames("key")
+		c.SetParamValues(key)
+	}
+	return c, rec
+}
+
+func TestConfigRegistry(t *testing.T) {
+	t.Run("given tax config keys should register every one of them", func(t *testing.T) {
+		keys := []string{"PERSONAL_DEDUCTION"}
+		for key := range tax.CapDefaults() {
+			keys = append(keys, key)
+		}
+
+		for _, key := range keys {
+			if _, ok := LookupConfig(key); !ok {
+				t.Errorf("config %s is not registered", key)
+			}
+		}
+	})
+}
+
+func TestConfigAPI(t *testing.T) {
+	t.Run("given registered key should return the config", func(t *testing.T) {
+		c, rec := configContext(http.MethodGet, "/admin/configs/MAX_SSF_DEDUCTION", "", "MAX_SSF_DEDUCTION")
+
+		handler := New(newConfigStub(), nil)
+		err := handler.GetConfig(c)
+		if err != nil {
+			t.Errorf("unable to get config: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		var res ConfigResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		if res.Value != money.FromBaht(200_000) || res.Type != CONFIG_TYPE_AMOUNT || res.Name != "Maximum SSF deduction" {
+			t.Errorf("invalid config: got %+v", res)
+		}
+	})
+
+	t.Run("given past tax year without at should get the value in force at its end", func(t *testing.T) {
+		c, rec := configContext(http.MethodGet, "/admin/configs/MAX_SSF_DEDUCTION?taxYear=2560", "", "MAX_SSF_DEDUCTION")
+
+		stub := newConfigStub()
+		err := New(stub, nil).GetConfig(c)
+		if err != nil {
+			t.Errorf("unable to get config: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+		if got := stub.ConfigAt.Format(tax.DATE_FORMAT); got != "2017-12-31" {
+			t.Errorf("invalid config date: got %v want %v", got, "2017-12-31")
+		}
+	})
+
+	t.Run("given at outside the tax year should return 400", func(t *testing.T) {
+		c, rec := configContext(http.MethodGet, "/admin/configs/MAX_SSF_DEDUCTION?at=2023-12-31", "", "MAX_SSF_DEDUCTION")
+
+		err := New(newConfigStub(), nil).GetConfig(c)
+		if err != nil {
+			t.Errorf("unable to get config: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given unregistered key should return 404", func(t *testing.T) {
+		c, rec := configContext(http.MethodGet, "/admin/configs/UNKNOWN", "", "UNKNOWN")
+
+		handler := New(newConfigStub(), nil)
+		err := handler.GetConfig(c)
+		if err != nil {
+			t.Errorf("unable to get config: %v", err)
+		}
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("given approved value within bounds should update the config", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		changeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 300000.0}`)
+
+		if stub.Configs["MAX_SSF_DEDUCTION"].Value != money.FromBaht(300_000) {
+			t.Errorf("invalid config value: got %v want %v", stub.Configs["MAX_SSF_DEDUCTION"].Value, money.FromBaht(300_000))
+		}
+	})
+
+	t.Run("given value out of bounds should return 400", func(t *testing.T) {
+		c, rec := configContext(http.MethodPut, "/admin/configs/MAX_SSF_DEDUCTION", `{"amount": 2000000.0}`, "MAX_SSF_DEDUCTION")
+
+		handler := New(newConfigStub(), nil)
+		err := handler.SetConfig(c)
+		if err != nil {
+			t.Errorf("unable to set config: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given stored configs should list them in registration order", func(t *testing.T) {
+		c, rec := configContext(http.MethodGet, "/admin/configs", "", "")
+
+		handler := New(newConfigStub(), nil)
+		err := handler.ListConfigs(c)
+		if err != nil {
+			t.Errorf("unable to list configs: %v", err)
+		}
+
+		var res struct {
+			Configs []ConfigResponse `json:"configs"`
+		}
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		if len(res.Configs) != 2 || res.Configs[0].Key != "PERSONAL_DEDUCTION" || res.Configs[1].Key != "MAX_SSF_DEDUCTION" {
+			t.Errorf("invalid configs: got %+v", res.Configs)
+		}
+	})
+}
+
+func TestScheduleConfig(t *testing.T) {
+	t.Run("given effective dates should schedule the value", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		changeConfig(t, handler, "PERSONAL_DEDUCTION", `{"amount": 70000.0, "effectiveFrom": "2024-07-01", "effectiveTo": "2024-12-31"}`)
+
+		if len(stub.Schedules) != 1 {
+			t.Fatalf("invalid schedules length: got %v want %v", len(stub.Schedules), 1)
+		}
+		schedule := stub.Schedules[0]
+		if formatDate(schedule.EffectiveFrom) != "2024-07-01" || formatDate(schedule.EffectiveTo) != "2024-12-31" {
+			t.Errorf("invalid schedule: got %v to %v", formatDate(schedule.EffectiveFrom), formatDate(schedule.EffectiveTo))
+		}
+		if stub.Configs["PERSONAL_DEDUCTION"].Value != money.FromBaht(60_000) {
+			t.Errorf("base value should not change: got %v", stub.Configs["PERSONAL_DEDUCTION"].Value)
+		}
+	})
+
+	t.Run("given effectiveTo without effectiveFrom should return 400", func(t *testing.T) {
+		c, rec := configContext(http.MethodPut, "/admin/configs/PERSONAL_DEDUCTION",
+			`{"amount": 70000.0, "effectiveTo": "2024-12-31"}`, "PERSONAL_DEDUCTION")
+
+		handler := New(newConfigStub(), nil)
+		err := handler.SetConfig(c)
+		if err != nil {
+			t.Errorf("unable to set config: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
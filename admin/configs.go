@@ -0,0 +1,60 @@
+package admin
+
+import (
+	"fmt"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/tax"
+)
+
+// configLimits are the values the admin API accepts for each of tax.ConfigKeys, in baht
+// or percent.
+var configLimits = map[string]struct{ min, max int64 }{
+	"PERSONAL_DEDUCTION":      {10_000, 100_000},
+	"MAX_K_RECEIPT_DEDUCTION": {1, 100_000},
+	"DONATION_INCOME_PERCENT": {0, 100},
+
+	"SPOUSE_DEDUCTION":             {0, 100_000},
+	"CHILD_DEDUCTION":              {0, 100_000},
+	"CHILD_BORN_2018_DEDUCTION":    {0, 100_000},
+	"PARENT_DEDUCTION":             {0, 100_000},
+	"DISABLED_DEPENDANT_DEDUCTION": {0, 100_000},
+
+	"MAX_HEALTH_INSURANCE_DEDUCTION":         {0, 100_000},
+	"MAX_LIFE_INSURANCE_DEDUCTION":           {0, 200_000},
+	"MAX_INSURANCE_DEDUCTION":                {0, 200_000},
+	"MAX_PARENTS_HEALTH_INSURANCE_DEDUCTION": {0, 100_000},
+	"MAX_SOCIAL_SECURITY_DEDUCTION":          {0, 50_000},
+	"MAX_HOME_LOAN_INTEREST_DEDUCTION":       {0, 200_000},
+
+	"MAX_SSF_DEDUCTION":                {0, 1_000_000},
+	"MAX_RMF_DEDUCTION":                {0, 1_000_000},
+	"MAX_PVD_DEDUCTION":                {0, 1_000_000},
+	"MAX_GPF_DEDUCTION":                {0, 1_000_000},
+	"MAX_RETIREMENT_SAVINGS_DEDUCTION": {0, 1_000_000},
+	"MAX_THAI_ESG_DEDUCTION":           {0, 500_000},
+
+	"MAX_EMPLOYMENT_EXPENSE_DEDUCTION": {0, 200_000},
+	"MAX_ROYALTY_EXPENSE_DEDUCTION":    {0, 200_000},
+}
+
+func init() {
+	for _, config := range tax.ConfigKeys() {
+		limit, ok := configLimits[config.Key]
+		if !ok {
+			panic(fmt.Sprintf("config %s has no admin limits", config.Key))
+		}
+
+		configType := CONFIG_TYPE_AMOUNT
+		if config.Percent {
+			configType = CONFIG_TYPE_PERCENT
+		}
+		RegisterConfig(ConfigDefinition{
+			Key:  config.Key,
+			Name: config.Name,
+			Type: configType,
+			Min:  money.FromBaht(limit.min),
+			Max:  money.FromBaht(limit.max),
+		})
+	}
+}
@@ -2,22 +2,59 @@ package admin
 
 import (
 	"net/http"
+	"time"
 
+	"github.com/bytesbanana/assessment-tax/money"
 	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/bytesbanana/assessment-tax/tax"
 	"github.com/labstack/echo/v4"
 )
 
 type (
 	Storer interface {
-		SetTaxConfig(key string, value float64) (*postgres.TaxConfig, error)
+		GetTaxConfig(taxYear int, key string, at time.Time) (*postgres.TaxConfig, error)
+		ListTaxConfigs(taxYear int) ([]postgres.TaxConfig, error)
+		GetTaxConfigHistory(taxYear int, key string) ([]postgres.TaxConfigHistory, error)
+		GetTaxConfigChange(id int) (*postgres.TaxConfigHistory, error)
+		CreateTaxConfigProposal(proposal postgres.TaxConfigProposal) (*postgres.TaxConfigProposal, error)
+		GetTaxConfigProposal(id int) (*postgres.TaxConfigProposal, error)
+		ListTaxConfigProposals(status string) ([]postgres.TaxConfigProposal, error)
+		ExpireTaxConfigProposals(at time.Time) error
+		ApproveTaxConfigProposal(id int, reviewer string, at time.Time) (*postgres.TaxConfig, error)
+		RejectTaxConfigProposal(id int, reviewer string, comment string) (*postgres.TaxConfigProposal, error)
+		GetAdminUser(username string) (*postgres.AdminUser, error)
+		ListAdminUsers() ([]postgres.AdminUser, error)
+		CountAdminUsers() (int, error)
+		CreateAdminUser(user postgres.AdminUser) (*postgres.AdminUser, error)
+		UpdateAdminUser(user postgres.AdminUser) (*postgres.AdminUser, error)
+		RevokeToken(id string, expiresAt time.Time, revokedBy string) (bool, error)
+		IsTokenRevoked(id string) (bool, error)
+		CreateAPIKey(key postgres.APIKey) (*postgres.APIKey, error)
+		ListAPIKeys() ([]postgres.APIKey, error)
+		RevokeAPIKey(id int, revokedBy string) (*postgres.APIKey, error)
+		GetAPIKeyUsage(id int) ([]postgres.APIKeyUsage, error)
+	}
+
+	// ImpactAnalyser recomputes stored assessments with a proposed config change.
+	ImpactAnalyser interface {
+		AnalyseImpact(change tax.ConfigChange, limit int) (*tax.ImpactReport, error)
 	}
 
 	Handler struct {
-		store Storer
+		store    Storer
+		analyser ImpactAnalyser
+		tokens   TokenConfig
 	}
 
+	// SetConfigValueRequest proposes a change of the base value of the tax year, or schedules the value
+	// from EffectiveFrom until EffectiveTo, both inclusive and formatted as YYYY-MM-DD.
 	SetConfigValueRequest struct {
-		Amount *float64 `json:"amount,omitempty" validate:"required"`
+		Amount        *money.Money `json:"amount,omitempty" validate:"required"`
+		TaxYear       *int         `json:"taxYear,omitempty"`
+		EffectiveFrom string       `json:"effectiveFrom,omitempty"`
+		EffectiveTo   string       `json:"effectiveTo,omitempty"`
+		// Reason is recorded in the config history.
+		Reason string `json:"reason,omitempty"`
 	}
 
 	Err struct {
@@ -25,84 +62,51 @@ type (
 	}
 )
 
-func New(db Storer) *Handler {
+// ADMIN_USERNAME_CONTEXT_KEY holds the username of the authenticated admin.
+const ADMIN_USERNAME_CONTEXT_KEY = "adminUsername"
 
-	return &Handler{
-		store: db,
-	}
+// actor returns the username of the authenticated admin making the request.
+func actor(c echo.Context) string {
+	username, _ := c.Get(ADMIN_USERNAME_CONTEXT_KEY).(string)
+	return username
 }
 
-func (h *Handler) SetPersonalDeductionsConfig(c echo.Context) error {
-	var req SetConfigValueRequest
-	var err error
-
-	err = c.Bind(&req)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, &Err{
-			Message: "invalid request body",
-		})
+// taxYear returns the tax year the change applies to, defaulting to tax.DEFAULT_TAX_YEAR.
+func (r SetConfigValueRequest) taxYear() int {
+	if r.TaxYear == nil {
+		return tax.DEFAULT_TAX_YEAR
 	}
+	return *r.TaxYear
+}
 
-	if req.Amount == nil {
-		return c.JSON(http.StatusBadRequest, &Err{
-			Message: "invalid request body",
-		})
-	}
+func New(db Storer, analyser ImpactAnalyser) *Handler {
 
-	if *req.Amount < 10_000 || *req.Amount > 100_000 {
-		return c.JSON(http.StatusBadRequest, &Err{
-			Message: "amount must be between 10,000 and 100,000",
-		})
+	return &Handler{
+		store:    db,
+		analyser: analyser,
 	}
+}
 
-	personalDeduction, err := h.store.SetTaxConfig("PERSONAL_DEDUCTION", *req.Amount)
+// SetPersonalDeductionsConfig proposes a new personal deduction for approval.
+func (h *Handler) SetPersonalDeductionsConfig(c echo.Context) error {
+	proposal, status, err := h.proposeConfig(c, "PERSONAL_DEDUCTION")
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, &Err{
+		return c.JSON(status, &Err{
 			Message: err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, struct {
-		PersonalDeduction float64 `json:"personalDeduction"`
-	}{
-		PersonalDeduction: personalDeduction.Value,
-	})
+	return c.JSON(http.StatusAccepted, newProposalResponse(*proposal))
 }
 
+// SetMaxKReceiptDeduction proposes a new k-receipt deduction cap for approval.
 func (h *Handler) SetMaxKReceiptDeduction(c echo.Context) error {
-
-	var req SetConfigValueRequest
-	var err error
-
-	err = c.Bind(&req)
-	if err != nil {
-		return c.JSON(http.StatusBadRequest, &Err{
-			Message: "invalid request body",
-		})
-	}
-
-	if req.Amount == nil {
-		return c.JSON(http.StatusBadRequest, &Err{
-			Message: "invalid request body",
-		})
-	}
-
-	if *req.Amount < 1 || *req.Amount > 100_000 {
-		return c.JSON(http.StatusBadRequest, &Err{
-			Message: "amount must be between 1 and 100,000",
-		})
-	}
-
-	maxKReceipt, err := h.store.SetTaxConfig("MAX_K_RECEIPT_DEDUCTION", *req.Amount)
+	proposal, status, err := h.proposeConfig(c, "MAX_K_RECEIPT_DEDUCTION")
 	if err != nil {
-		return c.JSON(http.StatusInternalServerError, &Err{
+		return c.JSON(status, &Err{
 			Message: err.Error(),
 		})
 	}
 
-	return c.JSON(http.StatusOK, struct {
-		KReceipt float64 `json:"kReceipt"`
-	}{
-		KReceipt: maxKReceipt.Value,
-	})
+	return c.JSON(http.StatusAccepted, newProposalResponse(*proposal))
 }
@@ -0,0 +1,140 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/labstack/echo/v4"
+)
+
+type (
+	ConfigChangeResponse struct {
+		ID            int          `json:"id"`
+		TaxYear       int          `json:"taxYear"`
+		Key           string       `json:"key"`
+		EffectiveFrom string       `json:"effectiveFrom,omitempty"`
+		EffectiveTo   string       `json:"effectiveTo,omitempty"`
+		OldValue      *money.Money `json:"oldValue"`
+		NewValue      money.Money  `json:"newValue"`
+		Action        string       `json:"action"`
+		Reason        string       `json:"reason"`
+		ChangedBy     string       `json:"changedBy"`
+		ApprovedBy    *string      `json:"approvedBy,omitempty"`
+		ChangedAt     *time.Time   `json:"changedAt"`
+	}
+
+	// RollbackConfigRequest restores the value set by a change of the config history.
+	RollbackConfigRequest struct {
+		ChangeID int    `json:"changeId"`
+		Reason   string `json:"reason"`
+	}
+)
+
+func newConfigChangeResponse(change postgres.TaxConfigHistory) ConfigChangeResponse {
+	return ConfigChangeResponse{
+		ID:            change.ID,
+		TaxYear:       change.TaxYear,
+		Key:           change.Key,
+		EffectiveFrom: formatDate(change.EffectiveFrom),
+		EffectiveTo:   formatDate(change.EffectiveTo),
+		OldValue:      change.OldValue,
+		NewValue:      change.NewValue,
+		Action:        change.Action,
+		Reason:        change.Reason,
+		ChangedBy:     change.ChangedBy,
+		ApprovedBy:    change.ApprovedBy,
+		ChangedAt:     change.ChangedAt,
+	}
+}
+
+func (h *Handler) GetConfigHistory(c echo.Context) error {
+	definition, ok := LookupConfig(c.Param("key"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: ErrConfigNotFound.Error(),
+		})
+	}
+
+	taxYear, err := taxYearParam(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	history, err := h.store.GetTaxConfigHistory(taxYear, definition.Key)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to get config history",
+		})
+	}
+
+	res := []ConfigChangeResponse{}
+	for _, change := range history {
+		res = append(res, newConfigChangeResponse(change))
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		History []ConfigChangeResponse `json:"history"`
+	}{
+		History: res,
+	})
+}
+
+// RollbackConfig proposes setting the config back to the value of an earlier change, for
+// the same tax year and schedule. Once approved it is recorded as a new change.
+func (h *Handler) RollbackConfig(c echo.Context) error {
+	definition, ok := LookupConfig(c.Param("key"))
+	if !ok {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: ErrConfigNotFound.Error(),
+		})
+	}
+
+	var req RollbackConfigRequest
+	err := c.Bind(&req)
+	if err != nil || req.ChangeID == 0 {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	change, err := h.store.GetTaxConfigChange(req.ChangeID)
+	if errors.Is(err, sql.ErrNoRows) || (err == nil && change.Key != definition.Key) {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: "config change not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to get config change",
+		})
+	}
+
+	reason := fmt.Sprintf("rollback to change %d", change.ID)
+	if req.Reason != "" {
+		reason += ": " + req.Reason
+	}
+
+	proposal, status, err := h.propose(c, postgres.TaxConfigProposal{
+		TaxYear:       change.TaxYear,
+		Key:           change.Key,
+		Value:         change.NewValue,
+		EffectiveFrom: change.EffectiveFrom,
+		EffectiveTo:   change.EffectiveTo,
+		Action:        postgres.CONFIG_ACTION_ROLLBACK,
+		Reason:        reason,
+	})
+	if err != nil {
+		return c.JSON(status, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusAccepted, newProposalResponse(*proposal))
+}
@@ -0,0 +1,153 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestConfigHistory(t *testing.T) {
+	t.Run("given config change should record the admin and reason", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		changeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000, "reason": "budget act"}`)
+
+		if len(stub.History) != 1 {
+			t.Fatalf("invalid history length: got %v want %v", len(stub.History), 1)
+		}
+		change := stub.History[0]
+		if change.Action != postgres.CONFIG_ACTION_UPDATE || change.ChangedBy != MAKER || change.Reason != "budget act" {
+			t.Errorf("invalid audit: got %+v", change)
+		}
+		if *change.OldValue != money.FromBaht(200_000) || change.NewValue != money.FromBaht(150_000) {
+			t.Errorf("invalid values: got old %v new %v want old %v new %v", *change.OldValue, change.NewValue, money.FromBaht(200_000), money.FromBaht(150_000))
+		}
+	})
+
+	t.Run("given config changes should list the history latest first", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		changeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000}`)
+		changeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 100000, "effectiveFrom": "2024-07-01"}`)
+
+		c, rec := configContext(http.MethodGet, "/admin/configs/MAX_SSF_DEDUCTION/history", "", "MAX_SSF_DEDUCTION")
+		err := handler.GetConfigHistory(c)
+		if err != nil {
+			t.Errorf("unable to get config history: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		var res struct {
+			History []ConfigChangeResponse `json:"history"`
+		}
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		if len(res.History) != 2 {
+			t.Fatalf("invalid history length: got %v want %v", len(res.History), 2)
+		}
+		if res.History[0].Action != postgres.CONFIG_ACTION_SCHEDULE || res.History[0].EffectiveFrom != "2024-07-01" || res.History[0].OldValue != nil {
+			t.Errorf("invalid latest change: got %+v", res.History[0])
+		}
+		if res.History[1].Action != postgres.CONFIG_ACTION_UPDATE || res.History[1].NewValue != money.FromBaht(150_000) {
+			t.Errorf("invalid earlier change: got %+v", res.History[1])
+		}
+	})
+
+	t.Run("given unknown key should return 404", func(t *testing.T) {
+		c, rec := configContext(http.MethodGet, "/admin/configs/UNKNOWN/history", "", "UNKNOWN")
+
+		handler := New(newConfigStub(), nil)
+		err := handler.GetConfigHistory(c)
+		if err != nil {
+			t.Errorf("unable to get config history: %v", err)
+		}
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestRollbackConfig(t *testing.T) {
+	t.Run("given approved rollback should restore the value of the earlier change", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		changeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000}`)
+		changeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 120000}`)
+
+		c, rec := configContext(http.MethodPost, "/admin/configs/MAX_SSF_DEDUCTION/rollback", `{"changeId": 1, "reason": "wrong amount"}`, "MAX_SSF_DEDUCTION")
+		c.Set(ADMIN_USERNAME_CONTEXT_KEY, MAKER)
+		err := handler.RollbackConfig(c)
+		if err != nil {
+			t.Errorf("unable to rollback config: %v", err)
+		}
+
+		if rec.Code != http.StatusAccepted {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusAccepted)
+		}
+
+		c, rec = proposalContext("approve", 3, "", CHECKER)
+		err = handler.ApproveProposal(c)
+		if err != nil {
+			t.Errorf("unable to approve proposal: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		var res ConfigResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+		if res.Value != money.FromBaht(150_000) {
+			t.Errorf("invalid value: got %v want %v", res.Value, money.FromBaht(150_000))
+		}
+
+		change := stub.History[len(stub.History)-1]
+		if change.Action != postgres.CONFIG_ACTION_ROLLBACK || change.ChangedBy != MAKER ||
+			change.Reason != "rollback to change 1: wrong amount" || *change.OldValue != money.FromBaht(120_000) {
+			t.Errorf("invalid rollback change: got %+v", change)
+		}
+	})
+
+	t.Run("given change of another key should return 404", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		changeConfig(t, handler, "PERSONAL_DEDUCTION", `{"amount": 70000}`)
+
+		c, rec := configContext(http.MethodPost, "/admin/configs/MAX_SSF_DEDUCTION/rollback", `{"changeId": 1}`, "MAX_SSF_DEDUCTION")
+		err := handler.RollbackConfig(c)
+		if err != nil {
+			t.Errorf("unable to rollback config: %v", err)
+		}
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("given missing change id should return 400", func(t *testing.T) {
+		c, rec := configContext(http.MethodPost, "/admin/configs/MAX_SSF_DEDUCTION/rollback", `{}`, "MAX_SSF_DEDUCTION")
+
+		handler := New(newConfigStub(), nil)
+		err := handler.RollbackConfig(c)
+		if err != nil {
+			t.Errorf("unable to rollback config: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,79 @@
+package admin
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/tax"
+	"github.com/labstack/echo/v4"
+)
+
+// ConfigImpactRequest is a proposed config change to dry-run against stored
+// assessments, a base value or a scheduled one like SetConfigValueRequest. Sample limits
+// the run to the latest assessments, tax.DEFAULT_IMPACT_SAMPLE by default and at most
+// tax.MAX_IMPACT_SAMPLE.
+type ConfigImpactRequest struct {
+	Key           string       `json:"key"`
+	Amount        *money.Money `json:"amount"`
+	TaxYear       *int         `json:"taxYear,omitempty"`
+	EffectiveFrom string       `json:"effectiveFrom,omitempty"`
+	EffectiveTo   string       `json:"effectiveTo,omitempty"`
+	Sample        int          `json:"sample"`
+}
+
+func (r ConfigImpactRequest) taxYear() int {
+	if r.TaxYear == nil {
+		return tax.DEFAULT_TAX_YEAR
+	}
+	return *r.TaxYear
+}
+
+func (h *Handler) AnalyseConfigImpact(c echo.Context) error {
+	var req ConfigImpactRequest
+	err := c.Bind(&req)
+	if err != nil || req.Amount == nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	err = validateConfigValue(req.Key, *req.Amount)
+	var effectiveFrom, effectiveTo *time.Time
+	if err == nil {
+		effectiveFrom, effectiveTo, err = SetConfigValueRequest{
+			EffectiveFrom: req.EffectiveFrom,
+			EffectiveTo:   req.EffectiveTo,
+		}.effectiveDates()
+	}
+	if err == nil && (req.Sample < 0 || req.Sample > tax.MAX_IMPACT_SAMPLE) {
+		err = fmt.Errorf("sample must be between 0 and %d", tax.MAX_IMPACT_SAMPLE)
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	report, err := h.analyser.AnalyseImpact(tax.ConfigChange{
+		TaxYear:       req.taxYear(),
+		Key:           req.Key,
+		Value:         *req.Amount,
+		EffectiveFrom: effectiveFrom,
+		EffectiveTo:   effectiveTo,
+	}, req.Sample)
+	if errors.Is(err, tax.ErrTaxYearNotSupported) || errors.Is(err, tax.ErrUnknownConfigKey) {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to analyse config impact",
+		})
+	}
+
+	return c.JSON(http.StatusOK, report)
+}
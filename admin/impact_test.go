@@ -0,0 +1,108 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/tax"
+	"github.com/labstack/echo/v4"
+)
+
+type StubImpactAnalyser struct {
+	change tax.ConfigChange
+	limit  int
+}
+
+func (s *StubImpactAnalyser) AnalyseImpact(change tax.ConfigChange, limit int) (*tax.ImpactReport, error) {
+	if change.TaxYear != tax.DEFAULT_TAX_YEAR {
+		return nil, tax.ErrTaxYearNotSupported
+	}
+	s.change, s.limit = change, limit
+	return &tax.ImpactReport{TaxYear: change.TaxYear, Key: change.Key, ProposedValue: change.Value}, nil
+}
+
+func TestAnalyseConfigImpact(t *testing.T) {
+	analyseImpact := func(t *testing.T, analyser ImpactAnalyser, reqJSON string) *httptest.ResponseRecorder {
+		e := echo.New()
+		req := httptest.NewRequest(http.MethodPost, "/admin/configs/impact", strings.NewReader(reqJSON))
+		req.Header.Set(echo.HeaderContentType, echo.MIMEApplicationJSON)
+		rec := httptest.NewRecorder()
+		c := e.NewContext(req, rec)
+
+		handler := New(&StubAdminHandler{}, analyser)
+		err := handler.AnalyseConfigImpact(c)
+		if err != nil {
+			t.Errorf("unable to analyse config impact: %v", err)
+		}
+		return rec
+	}
+
+	t.Run("given proposed personal deduction should return the impact report", func(t *testing.T) {
+		analyser := &StubImpactAnalyser{}
+		rec := analyseImpact(t, analyser, `{"key": "PERSONAL_DEDUCTION", "amount": 70000.0, "sample": 100}`)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		var report tax.ImpactReport
+		err := json.Unmarshal(rec.Body.Bytes(), &report)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		if report.ProposedValue != money.FromBaht(70_000) || analyser.limit != 100 {
+			t.Errorf("invalid impact analysis: got %+v with limit %v", analyser.change, analyser.limit)
+		}
+	})
+
+	t.Run("given effective dates should analyse the scheduled change", func(t *testing.T) {
+		analyser := &StubImpactAnalyser{}
+		rec := analyseImpact(t, analyser, `{"key": "PERSONAL_DEDUCTION", "amount": 70000.0, "effectiveFrom": "2024-07-01", "effectiveTo": "2024-09-30"}`)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+		change := analyser.change
+		if change.EffectiveFrom == nil || change.EffectiveFrom.Format(tax.DATE_FORMAT) != "2024-07-01" ||
+			change.EffectiveTo == nil || change.EffectiveTo.Format(tax.DATE_FORMAT) != "2024-09-30" {
+			t.Errorf("invalid effective dates: got %+v", change)
+		}
+	})
+
+	t.Run("given effectiveTo without effectiveFrom should return 400", func(t *testing.T) {
+		rec := analyseImpact(t, &StubImpactAnalyser{}, `{"key": "PERSONAL_DEDUCTION", "amount": 70000.0, "effectiveTo": "2024-09-30"}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given amount out of range should return 400", func(t *testing.T) {
+		rec := analyseImpact(t, &StubImpactAnalyser{}, `{"key": "PERSONAL_DEDUCTION", "amount": 5000.0}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given sample above the maximum should return 400", func(t *testing.T) {
+		rec := analyseImpact(t, &StubImpactAnalyser{}, `{"key": "PERSONAL_DEDUCTION", "amount": 70000.0, "sample": 10001}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given unsupported tax year should return 400", func(t *testing.T) {
+		rec := analyseImpact(t, &StubImpactAnalyser{}, `{"key": "PERSONAL_DEDUCTION", "amount": 70000.0, "taxYear": 2500}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
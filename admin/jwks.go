@@ -0,0 +1,205 @@
+package admin
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// JWKS_REFETCH_INTERVAL limits how often a remote key set is fetched again for an unknown key id.
+const JWKS_REFETCH_INTERVAL = time.Minute
+
+var ErrUnknownSigningKey = errors.New("unknown signing key")
+
+type (
+	// KeyProvider returns the public key that verifies tokens signed with the key id.
+	KeyProvider interface {
+		PublicKey(kid string) (crypto.PublicKey, error)
+	}
+
+	// KeySet is a parsed JSON Web Key Set of RSA and EC signing keys.
+	KeySet struct {
+		keys map[string]crypto.PublicKey
+	}
+
+	// RemoteKeySet fetches the key set of an identity provider, as published at its
+	// jwks_uri, and fetches it again when a token is signed with an unknown key.
+	RemoteKeySet struct {
+		url    string
+		client *http.Client
+
+		mu        sync.Mutex
+		keys      *KeySet
+		fetchedAt time.Time
+	}
+
+	jsonWebKey struct {
+		Kty string `json:"kty"`
+		Kid string `json:"kid"`
+		Use string `json:"use"`
+		N   string `json:"n"`
+		E   string `json:"e"`
+		Crv string `json:"crv"`
+		X   string `json:"x"`
+		Y   string `json:"y"`
+	}
+)
+
+// ParseKeySet parses a JSON Web Key Set. Keys that are not for signatures or of other
+// types than RSA and EC are skipped.
+func ParseKeySet(data []byte) (*KeySet, error) {
+	var set struct {
+		Keys []jsonWebKey `json:"keys"`
+	}
+	err := json.Unmarshal(data, &set)
+	if err != nil {
+		return nil, fmt.Errorf("invalid key set: %w", err)
+	}
+
+	keys := map[string]crypto.PublicKey{}
+	for _, jwk := range set.Keys {
+		if jwk.Use != "" && jwk.Use != "sig" {
+			continue
+		}
+
+		var key crypto.PublicKey
+		switch jwk.Kty {
+		case "RSA":
+			key, err = jwk.rsaPublicKey()
+		case "EC":
+			key, err = jwk.ecPublicKey()
+		default:
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid key %s: %w", jwk.Kid, err)
+		}
+		keys[jwk.Kid] = key
+	}
+
+	return &KeySet{keys: keys}, nil
+}
+
+func decodeBigInt(value string) (*big.Int, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil || len(data) == 0 {
+		return nil, errors.New("invalid base64url value")
+	}
+	return new(big.Int).SetBytes(data), nil
+}
+
+func (k jsonWebKey) rsaPublicKey() (*rsa.PublicKey, error) {
+	n, err := decodeBigInt(k.N)
+	if err != nil {
+		return nil, err
+	}
+	e, err := decodeBigInt(k.E)
+	if err != nil {
+		return nil, err
+	}
+	if !e.IsInt64() || e.Int64() < 3 || e.Int64() > 1<<31-1 {
+		return nil, errors.New("invalid RSA exponent")
+	}
+
+	return &rsa.PublicKey{N: n, E: int(e.Int64())}, nil
+}
+
+func (k jsonWebKey) ecPublicKey() (*ecdsa.PublicKey, error) {
+	var curve elliptic.Curve
+	switch k.Crv {
+	case "P-256":
+		curve = elliptic.P256()
+	case "P-384":
+		curve = elliptic.P384()
+	case "P-521":
+		curve = elliptic.P521()
+	default:
+		return nil, fmt.Errorf("unsupported curve %q", k.Crv)
+	}
+
+	x, err := decodeBigInt(k.X)
+	if err != nil {
+		return nil, err
+	}
+	y, err := decodeBigInt(k.Y)
+	if err != nil {
+		return nil, err
+	}
+	if !curve.IsOnCurve(x, y) {
+		return nil, errors.New("point is not on the curve")
+	}
+
+	return &ecdsa.PublicKey{Curve: curve, X: x, Y: y}, nil
+}
+
+// PublicKey returns the key with the id. A token without a key id is verified with the
+// only key of the set.
+func (s *KeySet) PublicKey(kid string) (crypto.PublicKey, error) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, nil
+		}
+	}
+
+	key, ok := s.keys[kid]
+	if !ok {
+		return nil, ErrUnknownSigningKey
+	}
+	return key, nil
+}
+
+func NewRemoteKeySet(url string) *RemoteKeySet {
+	return &RemoteKeySet{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (r *RemoteKeySet) PublicKey(kid string) (crypto.PublicKey, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.keys != nil {
+		key, err := r.keys.PublicKey(kid)
+		if err == nil || time.Since(r.fetchedAt) < JWKS_REFETCH_INTERVAL {
+			return key, err
+		}
+	}
+
+	keys, err := r.fetch()
+	r.fetchedAt = time.Now()
+	if err != nil {
+		return nil, err
+	}
+	r.keys = keys
+
+	return r.keys.PublicKey(kid)
+}
+
+func (r *RemoteKeySet) fetch() (*KeySet, error) {
+	res, err := r.client.Get(r.url)
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch key set: %w", err)
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("unable to fetch key set: status %d", res.StatusCode)
+	}
+	data, err := io.ReadAll(io.LimitReader(res.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("unable to fetch key set: %w", err)
+	}
+
+	return ParseKeySet(data)
+}
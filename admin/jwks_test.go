@@ -0,0 +1,252 @@
+package admin
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+const (
+	TEST_OIDC_ISSUER   = "https://idp.example.com"
+	TEST_OIDC_AUDIENCE = "assessment-tax-admin"
+)
+
+func encodeBigInt(value *big.Int) string {
+	return base64.RawURLEncoding.EncodeToString(value.Bytes())
+}
+
+func rsaJWK(kid string, key *rsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "RSA", "kid": kid, "use": "sig", "n": encodeBigInt(key.N), "e": encodeBigInt(big.NewInt(int64(key.E)))}
+}
+
+func ecJWK(kid string, key *ecdsa.PublicKey) map[string]string {
+	return map[string]string{"kty": "EC", "kid": kid, "crv": key.Curve.Params().Name, "x": encodeBigInt(key.X), "y": encodeBigInt(key.Y)}
+}
+
+func keySetJSON(t *testing.T, keys ...map[string]string) []byte {
+	t.Helper()
+
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("unable to marshal key set: %v", err)
+	}
+	return data
+}
+
+type testKeys struct {
+	rsa *rsa.PrivateKey
+	ec  *ecdsa.PrivateKey
+	set []byte
+}
+
+func newTestKeys(t *testing.T) testKeys {
+	t.Helper()
+
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("unable to generate EC key: %v", err)
+	}
+
+	return testKeys{
+		rsa: rsaKey,
+		ec:  ecKey,
+		set: keySetJSON(t,
+			rsaJWK("rsa-1", &rsaKey.PublicKey),
+			ecJWK("ec-1", &ecKey.PublicKey),
+			map[string]string{"kty": "RSA", "kid": "enc-1", "use": "enc", "n": "AQAB", "e": "AQAB"},
+		),
+	}
+}
+
+func signExternalToken(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("unable to sign token: %v", err)
+	}
+	return signed
+}
+
+func externalClaims(overrides jwt.MapClaims) jwt.MapClaims {
+	claims := jwt.MapClaims{
+		"iss":                TEST_OIDC_ISSUER,
+		"aud":                []string{"other-client", TEST_OIDC_AUDIENCE},
+		"sub":                "00u1a2b3c4",
+		"preferred_username": MAKER,
+		"groups":             []string{"tax-approvers"},
+		"exp":                time.Now().Add(time.Minute).Unix(),
+	}
+	for key, value := range overrides {
+		claims[key] = value
+	}
+	return claims
+}
+
+func TestParseKeySet(t *testing.T) {
+	keys := newTestKeys(t)
+
+	set, err := ParseKeySet(keys.set)
+	if err != nil {
+		t.Fatalf("unable to parse key set: %v", err)
+	}
+
+	rsaKey, err := set.PublicKey("rsa-1")
+	if err != nil || !keys.rsa.PublicKey.Equal(rsaKey) {
+		t.Errorf("invalid RSA key: %v", err)
+	}
+	ecKey, err := set.PublicKey("ec-1")
+	if err != nil || !keys.ec.PublicKey.Equal(ecKey) {
+		t.Errorf("invalid EC key: %v", err)
+	}
+	if _, err := set.PublicKey("enc-1"); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("encryption key should be skipped: got %v", err)
+	}
+	if _, err := set.PublicKey(""); !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("key id should be required with several keys: got %v", err)
+	}
+
+	_, err = ParseKeySet([]byte(`{"keys": [{"kty": "EC", "kid": "ec-2", "crv": "P-256", "x": "AQ", "y": "AQ"}]}`))
+	if err == nil {
+		t.Errorf("point off the curve should be rejected")
+	}
+}
+
+func TestExternalToken(t *testing.T) {
+	keys := newTestKeys(t)
+	set, err := ParseKeySet(keys.set)
+	if err != nil {
+		t.Fatalf("unable to parse key set: %v", err)
+	}
+
+	newHandler := func() *Handler {
+		handler := New(&StubAdminHandler{}, nil)
+		handler.UseTokens(TokenConfig{
+			Secret:   TEST_TOKEN_SECRET,
+			Keys:     set,
+			Issuer:   TEST_OIDC_ISSUER,
+			Audience: TEST_OIDC_AUDIENCE,
+			GroupRoles: map[string]string{
+				"tax-editors":   ROLE_EDITOR,
+				"tax-approvers": ROLE_APPROVER,
+			},
+		})
+		return handler
+	}
+
+	accepted := []struct {
+		name  string
+		token string
+	}{
+		{"given RS256 token of the identity provider should authenticate the admin",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, externalClaims(nil))},
+		{"given ES256 token of the identity provider should authenticate the admin",
+			signExternalToken(t, jwt.SigningMethodES256, "ec-1", keys.ec, externalClaims(nil))},
+		{"given token with several mapped groups should use the most privileged role",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, externalClaims(jwt.MapClaims{"groups": []string{"tax-editors", "staff", "tax-approvers"}}))},
+		{"given token with a role claim should ignore it",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, externalClaims(jwt.MapClaims{"role": ROLE_SUPERADMIN}))},
+	}
+
+	for _, tt := range accepted {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := bearerContext(t, newHandler(), tt.token)
+
+			if c == nil {
+				t.Fatalf("token rejected: %v", rec.Body.String())
+			}
+			// The username is taken from the issuer and subject, never preferred_username,
+			// so the admin cannot pass as the local user of the same name.
+			if actor(c) != "oidc:"+TEST_OIDC_ISSUER+"#00u1a2b3c4" || c.Get(ADMIN_ROLE_CONTEXT_KEY) != ROLE_APPROVER {
+				t.Errorf("invalid context: got %v %v", actor(c), c.Get(ADMIN_ROLE_CONTEXT_KEY))
+			}
+		})
+	}
+
+	otherKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("unable to generate RSA key: %v", err)
+	}
+
+	rejected := []struct {
+		name  string
+		token string
+	}{
+		{"given token of another issuer should return 401",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, externalClaims(jwt.MapClaims{"iss": "https://evil.example.com"}))},
+		{"given token for another audience should return 401",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, externalClaims(jwt.MapClaims{"aud": "other-client"}))},
+		{"given token signed with an unknown key should return 401",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-2", otherKey, externalClaims(nil))},
+		{"given token signed with another key of the same id should return 401",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", otherKey, externalClaims(nil))},
+		{"given token verified with a key of another type should return 401",
+			signExternalToken(t, jwt.SigningMethodRS256, "ec-1", keys.rsa, externalClaims(nil))},
+		{"given token without a mapped group should return 401",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, externalClaims(jwt.MapClaims{"groups": []string{"staff"}}))},
+		{"given token with only a role claim should return 401",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, externalClaims(jwt.MapClaims{"groups": nil, "role": ROLE_SUPERADMIN}))},
+		{"given token without a subject should return 401",
+			signExternalToken(t, jwt.SigningMethodRS256, "rsa-1", keys.rsa, externalClaims(jwt.MapClaims{"sub": nil}))},
+	}
+
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := bearerContext(t, newHandler(), tt.token)
+
+			if c != nil {
+				t.Errorf("token should be rejected")
+			}
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+}
+
+func TestRemoteKeySet(t *testing.T) {
+	keys := newTestKeys(t)
+	fetches := 0
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches++
+		w.Header().Set("Content-Type", "application/json")
+		w.Write(keys.set)
+	}))
+	defer server.Close()
+
+	remote := NewRemoteKeySet(server.URL)
+
+	key, err := remote.PublicKey("rsa-1")
+	if err != nil || !keys.rsa.PublicKey.Equal(key) {
+		t.Errorf("invalid RSA key: %v", err)
+	}
+	_, err = remote.PublicKey("ec-1")
+	if err != nil {
+		t.Errorf("invalid EC key: %v", err)
+	}
+	_, err = remote.PublicKey("rsa-2")
+	if !errors.Is(err, ErrUnknownSigningKey) {
+		t.Errorf("unknown key: got %v want %v", err, ErrUnknownSigningKey)
+	}
+
+	if fetches != 1 {
+		t.Errorf("key set should be fetched again at most every %v: got %v fetches", JWKS_REFETCH_INTERVAL, fetches)
+	}
+}
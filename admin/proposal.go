@@ -0,0 +1,249 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/labstack/echo/v4"
+)
+
+// PROPOSAL_TTL is how long a proposal waits for review before it expires.
+const PROPOSAL_TTL = 7 * 24 * time.Hour
+
+var (
+	ErrProposalNotFound = errors.New("proposal not found")
+	ErrSelfApproval     = errors.New("proposal must be approved by a different admin")
+	ErrNotProposer      = errors.New("only the proposer or an approver may reject a proposal")
+)
+
+type (
+	ProposalResponse struct {
+		ID            int         `json:"id"`
+		TaxYear       int         `json:"taxYear"`
+		Key           string      `json:"key"`
+		Value         money.Money `json:"value"`
+		EffectiveFrom string      `json:"effectiveFrom,omitempty"`
+		EffectiveTo   string      `json:"effectiveTo,omitempty"`
+		Action        string      `json:"action"`
+		Reason        string      `json:"reason"`
+		Status        string      `json:"status"`
+		ProposedBy    string      `json:"proposedBy"`
+		ProposedAt    *time.Time  `json:"proposedAt,omitempty"`
+		ExpiresAt     time.Time   `json:"expiresAt"`
+		ReviewedBy    *string     `json:"reviewedBy,omitempty"`
+		ReviewedAt    *time.Time  `json:"reviewedAt,omitempty"`
+		ReviewComment string      `json:"reviewComment,omitempty"`
+	}
+
+	RejectProposalRequest struct {
+		Reason string `json:"reason"`
+	}
+)
+
+func newProposalResponse(proposal postgres.TaxConfigProposal) ProposalResponse {
+	return ProposalResponse{
+		ID:            proposal.ID,
+		TaxYear:       proposal.TaxYear,
+		Key:           proposal.Key,
+		Value:         proposal.Value,
+		EffectiveFrom: formatDate(proposal.EffectiveFrom),
+		EffectiveTo:   formatDate(proposal.EffectiveTo),
+		Action:        proposal.Action,
+		Reason:        proposal.Reason,
+		Status:        proposal.Status,
+		ProposedBy:    proposal.ProposedBy,
+		ProposedAt:    proposal.ProposedAt,
+		ExpiresAt:     proposal.ExpiresAt,
+		ReviewedBy:    proposal.ReviewedBy,
+		ReviewedAt:    proposal.ReviewedAt,
+		ReviewComment: proposal.ReviewComment,
+	}
+}
+
+// propose stores the config change as a pending proposal of the authenticated admin. The
+// key must have a value in the tax year. On failure it returns the HTTP status to respond with.
+func (h *Handler) propose(c echo.Context, proposal postgres.TaxConfigProposal) (*postgres.TaxConfigProposal, int, error) {
+	err := validateConfigValue(proposal.Key, proposal.Value)
+	if err != nil {
+		return nil, http.StatusBadRequest, err
+	}
+
+	now := time.Now()
+	_, err = h.store.GetTaxConfig(proposal.TaxYear, proposal.Key, now)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, http.StatusNotFound, ErrConfigNotFound
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("unable to get config")
+	}
+
+	proposal.ProposedBy = actor(c)
+	proposal.ExpiresAt = now.Add(PROPOSAL_TTL)
+	created, err := h.store.CreateTaxConfigProposal(proposal)
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("unable to create proposal")
+	}
+
+	return created, http.StatusAccepted, nil
+}
+
+// ListProposals returns the proposals with the status query parameter, or every proposal
+// when it is empty, the latest first.
+func (h *Handler) ListProposals(c echo.Context) error {
+	status := c.QueryParam("status")
+	switch status {
+	case "", postgres.PROPOSAL_STATUS_PENDING, postgres.PROPOSAL_STATUS_APPROVED,
+		postgres.PROPOSAL_STATUS_REJECTED, postgres.PROPOSAL_STATUS_EXPIRED:
+	default:
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid status parameter",
+		})
+	}
+
+	err := h.store.ExpireTaxConfigProposals(time.Now())
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to list proposals",
+		})
+	}
+
+	proposals, err := h.store.ListTaxConfigProposals(status)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to list proposals",
+		})
+	}
+
+	res := []ProposalResponse{}
+	for _, proposal := range proposals {
+		res = append(res, newProposalResponse(proposal))
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		Proposals []ProposalResponse `json:"proposals"`
+	}{
+		Proposals: res,
+	})
+}
+
+// pendingProposal loads the proposal of the id path parameter and checks it can still be
+// reviewed. On failure it returns the HTTP status to respond with.
+func (h *Handler) pendingProposal(c echo.Context) (*postgres.TaxConfigProposal, int, error) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return nil, http.StatusBadRequest, errors.New("invalid proposal id")
+	}
+
+	proposal, err := h.store.GetTaxConfigProposal(id)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, http.StatusNotFound, ErrProposalNotFound
+	}
+	if err != nil {
+		return nil, http.StatusInternalServerError, errors.New("unable to get proposal")
+	}
+
+	now := time.Now()
+	if proposal.Status == postgres.PROPOSAL_STATUS_PENDING && !proposal.ExpiresAt.After(now) {
+		err = h.store.ExpireTaxConfigProposals(now)
+		if err != nil {
+			return nil, http.StatusInternalServerError, errors.New("unable to expire proposal")
+		}
+		proposal.Status = postgres.PROPOSAL_STATUS_EXPIRED
+	}
+	if proposal.Status != postgres.PROPOSAL_STATUS_PENDING {
+		return nil, http.StatusConflict, fmt.Errorf("proposal is %s", proposal.Status)
+	}
+
+	return proposal, http.StatusOK, nil
+}
+
+// ApproveProposal applies a pending proposal. The approver must be a different admin
+// than the one who proposed the change.
+func (h *Handler) ApproveProposal(c echo.Context) error {
+	proposal, status, err := h.pendingProposal(c)
+	if err != nil {
+		return c.JSON(status, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	reviewer := actor(c)
+	if reviewer == proposal.ProposedBy {
+		return c.JSON(http.StatusForbidden, &Err{
+			Message: ErrSelfApproval.Error(),
+		})
+	}
+
+	definition, ok := LookupConfig(proposal.Key)
+	if !ok {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: fmt.Sprintf("config %s cannot be changed", proposal.Key),
+		})
+	}
+	err = definition.validate(proposal.Value)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	config, err := h.store.ApproveTaxConfigProposal(proposal.ID, reviewer, time.Now())
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusConflict, &Err{
+			Message: "proposal is no longer pending",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to approve proposal",
+		})
+	}
+
+	return c.JSON(http.StatusOK, definition.response(*config))
+}
+
+// RejectProposal closes a pending proposal without applying it. Approvers may reject any
+// proposal and the proposer may reject their own proposal to withdraw it.
+func (h *Handler) RejectProposal(c echo.Context) error {
+	proposal, status, err := h.pendingProposal(c)
+	if err != nil {
+		return c.JSON(status, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	role, _ := c.Get(ADMIN_ROLE_CONTEXT_KEY).(string)
+	if proposal.ProposedBy != actor(c) && role != ROLE_APPROVER && role != ROLE_SUPERADMIN {
+		return c.JSON(http.StatusForbidden, &Err{
+			Message: ErrNotProposer.Error(),
+		})
+	}
+
+	var req RejectProposalRequest
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	rejected, err := h.store.RejectTaxConfigProposal(proposal.ID, actor(c), req.Reason)
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusConflict, &Err{
+			Message: "proposal is no longer pending",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to reject proposal",
+		})
+	}
+
+	return c.JSON(http.StatusOK, newProposalResponse(*rejected))
+}
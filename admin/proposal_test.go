@@ -0,0 +1,305 @@
+package admin
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	MAKER   = "adminTax"
+	CHECKER = "adminChecker"
+)
+
+func proposalContext(action string, id int, body string, reviewer string) (echo.Context, *httptest.ResponseRecorder) {
+	return proposalContextAs(action, id, body, reviewer, ROLE_APPROVER)
+}
+
+func proposalContextAs(action string, id int, body string, reviewer string, role string) (echo.Context, *httptest.ResponseRecorder) {
+	c, rec := configContext(http.MethodPost, "/admin/proposals/"+strconv.Itoa(id)+"/"+action, body, "")
+	c.SetParamNames("id")
+	c.SetParamValues(strconv.Itoa(id))
+	c.Set(ADMIN_USERNAME_CONTEXT_KEY, reviewer)
+	c.Set(ADMIN_ROLE_CONTEXT_KEY, role)
+	return c, rec
+}
+
+// changeConfig proposes the change as MAKER and approves it as CHECKER.
+func changeConfig(t *testing.T, handler *Handler, key string, body string) {
+	t.Helper()
+
+	c, rec := configContext(http.MethodPut, "/admin/configs/"+key, body, key)
+	c.Set(ADMIN_USERNAME_CONTEXT_KEY, MAKER)
+	err := handler.SetConfig(c)
+	if err != nil {
+		t.Fatalf("unable to set config: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusAccepted)
+	}
+
+	var proposal ProposalResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &proposal)
+	if err != nil {
+		t.Fatalf("unable to unmarshal response: %v", err)
+	}
+
+	c, rec = proposalContext("approve", proposal.ID, "", CHECKER)
+	err = handler.ApproveProposal(c)
+	if err != nil {
+		t.Fatalf("unable to approve proposal: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+	}
+}
+
+func proposeConfig(t *testing.T, handler *Handler, key string, body string) {
+	t.Helper()
+
+	c, rec := configContext(http.MethodPut, "/admin/configs/"+key, body, key)
+	c.Set(ADMIN_USERNAME_CONTEXT_KEY, MAKER)
+	err := handler.SetConfig(c)
+	if err != nil {
+		t.Fatalf("unable to set config: %v", err)
+	}
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusAccepted)
+	}
+}
+
+func TestCreateProposal(t *testing.T) {
+	t.Run("given proposal that cannot be stored should return 500 without the store error", func(t *testing.T) {
+		stub := newConfigStub()
+		stub.ProposalErr = errors.New(`pq: relation "tax_config_proposals" does not exist`)
+		handler := New(stub, nil)
+
+		c, rec := configContext(http.MethodPut, "/admin/configs/MAX_SSF_DEDUCTION", `{"amount": 150000}`, "MAX_SSF_DEDUCTION")
+		c.Set(ADMIN_USERNAME_CONTEXT_KEY, MAKER)
+		err := handler.SetConfig(c)
+		if err != nil {
+			t.Errorf("unable to set config: %v", err)
+		}
+
+		if rec.Code != http.StatusInternalServerError {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusInternalServerError)
+		}
+		if !strings.Contains(rec.Body.String(), "unable to create proposal") || strings.Contains(rec.Body.String(), "pq:") {
+			t.Errorf("invalid response body: %s", rec.Body.String())
+		}
+	})
+}
+
+func TestApproveProposal(t *testing.T) {
+	t.Run("given proposal approved by another admin should apply the change", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		proposeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000, "reason": "budget act"}`)
+
+		if stub.Configs["MAX_SSF_DEDUCTION"].Value != money.FromBaht(200_000) {
+			t.Errorf("config changed before approval: got %v want %v", stub.Configs["MAX_SSF_DEDUCTION"].Value, money.FromBaht(200_000))
+		}
+
+		c, rec := proposalContext("approve", 1, "", CHECKER)
+		err := handler.ApproveProposal(c)
+		if err != nil {
+			t.Errorf("unable to approve proposal: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+		if stub.Configs["MAX_SSF_DEDUCTION"].Value != money.FromBaht(150_000) {
+			t.Errorf("invalid config value: got %v want %v", stub.Configs["MAX_SSF_DEDUCTION"].Value, money.FromBaht(150_000))
+		}
+		if stub.Proposals[0].Status != postgres.PROPOSAL_STATUS_APPROVED {
+			t.Errorf("invalid proposal status: got %v want %v", stub.Proposals[0].Status, postgres.PROPOSAL_STATUS_APPROVED)
+		}
+		change := stub.History[0]
+		if change.ChangedBy != MAKER || change.ApprovedBy == nil || *change.ApprovedBy != CHECKER || change.Reason != "budget act" {
+			t.Errorf("invalid audit: got %+v", change)
+		}
+	})
+
+	t.Run("given proposal approved by its proposer should return 403", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		proposeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000}`)
+
+		c, rec := proposalContext("approve", 1, "", MAKER)
+		err := handler.ApproveProposal(c)
+		if err != nil {
+			t.Errorf("unable to approve proposal: %v", err)
+		}
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusForbidden)
+		}
+		if stub.Configs["MAX_SSF_DEDUCTION"].Value != money.FromBaht(200_000) {
+			t.Errorf("config changed by self approval: got %v", stub.Configs["MAX_SSF_DEDUCTION"].Value)
+		}
+	})
+
+	t.Run("given expired proposal should return 409 and mark it expired", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		proposeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000}`)
+		stub.Proposals[0].ExpiresAt = time.Now().Add(-time.Minute)
+
+		c, rec := proposalContext("approve", 1, "", CHECKER)
+		err := handler.ApproveProposal(c)
+		if err != nil {
+			t.Errorf("unable to approve proposal: %v", err)
+		}
+
+		if rec.Code != http.StatusConflict {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusConflict)
+		}
+		if stub.Proposals[0].Status != postgres.PROPOSAL_STATUS_EXPIRED {
+			t.Errorf("invalid proposal status: got %v want %v", stub.Proposals[0].Status, postgres.PROPOSAL_STATUS_EXPIRED)
+		}
+	})
+
+	t.Run("given unknown proposal should return 404", func(t *testing.T) {
+		c, rec := proposalContext("approve", 7, "", CHECKER)
+
+		handler := New(newConfigStub(), nil)
+		err := handler.ApproveProposal(c)
+		if err != nil {
+			t.Errorf("unable to approve proposal: %v", err)
+		}
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusNotFound)
+		}
+	})
+}
+
+func TestRejectProposal(t *testing.T) {
+	t.Run("given pending proposal should reject it without applying the change", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		proposeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000}`)
+
+		c, rec := proposalContext("reject", 1, `{"reason": "not in the budget act"}`, CHECKER)
+		err := handler.RejectProposal(c)
+		if err != nil {
+			t.Errorf("unable to reject proposal: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		var res ProposalResponse
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+		if res.Status != postgres.PROPOSAL_STATUS_REJECTED || res.ReviewComment != "not in the budget act" {
+			t.Errorf("invalid proposal: got %+v", res)
+		}
+		if stub.Configs["MAX_SSF_DEDUCTION"].Value != money.FromBaht(200_000) {
+			t.Errorf("config changed by rejected proposal: got %v", stub.Configs["MAX_SSF_DEDUCTION"].Value)
+		}
+
+		c, rec = proposalContext("approve", 1, "", CHECKER)
+		err = handler.ApproveProposal(c)
+		if err != nil {
+			t.Errorf("unable to approve proposal: %v", err)
+		}
+		if rec.Code != http.StatusConflict {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusConflict)
+		}
+	})
+}
+
+func TestWithdrawProposal(t *testing.T) {
+	t.Run("given editor rejecting their own proposal should withdraw it", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		proposeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000}`)
+
+		c, rec := proposalContextAs("reject", 1, `{"reason": "typo"}`, MAKER, ROLE_EDITOR)
+		err := handler.RejectProposal(c)
+		if err != nil {
+			t.Errorf("unable to reject proposal: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+		if stub.Proposals[0].Status != postgres.PROPOSAL_STATUS_REJECTED {
+			t.Errorf("invalid proposal status: got %v", stub.Proposals[0].Status)
+		}
+	})
+
+	t.Run("given editor rejecting another admin's proposal should return 403", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		proposeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000}`)
+
+		c, rec := proposalContextAs("reject", 1, `{"reason": "no"}`, "otherEditor", ROLE_EDITOR)
+		err := handler.RejectProposal(c)
+		if err != nil {
+			t.Errorf("unable to reject proposal: %v", err)
+		}
+
+		if rec.Code != http.StatusForbidden {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusForbidden)
+		}
+		if stub.Proposals[0].Status != postgres.PROPOSAL_STATUS_PENDING {
+			t.Errorf("invalid proposal status: got %v", stub.Proposals[0].Status)
+		}
+	})
+}
+
+func TestListProposals(t *testing.T) {
+	t.Run("given status should list the proposals with the status", func(t *testing.T) {
+		stub := newConfigStub()
+		handler := New(stub, nil)
+		proposeConfig(t, handler, "MAX_SSF_DEDUCTION", `{"amount": 150000}`)
+		changeConfig(t, handler, "PERSONAL_DEDUCTION", `{"amount": 70000}`)
+
+		c, rec := configContext(http.MethodGet, "/admin/proposals?status=pending", "", "")
+		err := handler.ListProposals(c)
+		if err != nil {
+			t.Errorf("unable to list proposals: %v", err)
+		}
+
+		var res struct {
+			Proposals []ProposalResponse `json:"proposals"`
+		}
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		if len(res.Proposals) != 1 || res.Proposals[0].Key != "MAX_SSF_DEDUCTION" || res.Proposals[0].ProposedBy != MAKER {
+			t.Errorf("invalid proposals: got %+v", res.Proposals)
+		}
+	})
+
+	t.Run("given unknown status should return 400", func(t *testing.T) {
+		c, rec := configContext(http.MethodGet, "/admin/proposals?status=done", "", "")
+
+		handler := New(newConfigStub(), nil)
+		err := handler.ListProposals(c)
+		if err != nil {
+			t.Errorf("unable to list proposals: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
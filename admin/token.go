@@ -0,0 +1,436 @@
+package admin
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	// TOKEN_ISSUER is the issuer of the tokens signed by the login endpoint.
+	TOKEN_ISSUER = "assessment-tax"
+
+	ACCESS_TOKEN_TTL  = 15 * time.Minute
+	REFRESH_TOKEN_TTL = 24 * time.Hour
+
+	TOKEN_TYPE_ACCESS  = "access"
+	TOKEN_TYPE_REFRESH = "refresh"
+
+	// ADMIN_TOKEN_CONTEXT_KEY holds the verified bearer token of the request.
+	ADMIN_TOKEN_CONTEXT_KEY = "adminToken"
+
+	// EXTERNAL_USERNAME_PREFIX starts the username of admins of an external identity
+	// provider. Local usernames cannot contain the colon, so the two never collide.
+	EXTERNAL_USERNAME_PREFIX = "oidc:"
+)
+
+var (
+	ErrInvalidToken        = errors.New("invalid or expired token")
+	ErrInvalidCredentials  = errors.New("invalid username or password")
+	ErrTokensNotConfigured = errors.New("token authentication is not configured")
+	ErrInvalidGroupRoles   = errors.New("group roles must be a comma separated list of group:role")
+
+	localSigningMethods    = []string{"HS256"}
+	externalSigningMethods = []string{"RS256", "RS384", "RS512", "ES256", "ES384", "ES512"}
+)
+
+type (
+	// TokenConfig verifies the HS256 tokens this API signs with Secret and, when Keys is
+	// set, the RS and ES tokens of an external identity provider. Their iss claim must be
+	// Issuer and, when Audience is set, their aud claim must contain it. GroupRoles maps
+	// the groups claim of external tokens to admin roles.
+	TokenConfig struct {
+		Secret     []byte
+		Keys       KeyProvider
+		Issuer     string
+		Audience   string
+		GroupRoles map[string]string
+	}
+
+	LoginRequest struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+	}
+
+	RefreshTokenRequest struct {
+		RefreshToken string `json:"refreshToken"`
+	}
+
+	TokenResponse struct {
+		AccessToken  string `json:"accessToken"`
+		RefreshToken string `json:"refreshToken"`
+		TokenType    string `json:"tokenType"`
+		// ExpiresIn is the lifetime of the access token in seconds.
+		ExpiresIn int `json:"expiresIn"`
+	}
+
+	// adminToken is a verified token. ID is empty for external tokens without a jti claim.
+	adminToken struct {
+		ID        string
+		Type      string
+		Username  string
+		Role      string
+		ExpiresAt time.Time
+		// Local is set on tokens signed by this API.
+		Local bool
+	}
+)
+
+// ParseGroupRoles parses a comma separated list of group:role pairs.
+func ParseGroupRoles(value string) (map[string]string, error) {
+	groupRoles := map[string]string{}
+	for _, pair := range strings.Split(value, ",") {
+		if strings.TrimSpace(pair) == "" {
+			continue
+		}
+		group, role, ok := strings.Cut(pair, ":")
+		group, role = strings.TrimSpace(group), strings.TrimSpace(role)
+		if !ok || group == "" || validateRole(role) != nil {
+			return nil, ErrInvalidGroupRoles
+		}
+		groupRoles[group] = role
+	}
+	return groupRoles, nil
+}
+
+// UseTokens enables bearer token authentication.
+func (h *Handler) UseTokens(config TokenConfig) {
+	h.tokens = config
+}
+
+func newTokenID() (string, error) {
+	id := make([]byte, 16)
+	_, err := rand.Read(id)
+	if err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(id), nil
+}
+
+func (h *Handler) signToken(user postgres.AdminUser, tokenType string, now time.Time, ttl time.Duration) (string, error) {
+	id, err := newTokenID()
+	if err != nil {
+		return "", err
+	}
+
+	claims := jwt.MapClaims{
+		"iss":        TOKEN_ISSUER,
+		"sub":        user.Username,
+		"role":       user.Role,
+		"token_type": tokenType,
+		"jti":        id,
+		"iat":        now.Unix(),
+		"exp":        now.Add(ttl).Unix(),
+	}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(h.tokens.Secret)
+}
+
+func (h *Handler) issueTokens(user postgres.AdminUser) (*TokenResponse, error) {
+	now := time.Now()
+	accessToken, err := h.signToken(user, TOKEN_TYPE_ACCESS, now, ACCESS_TOKEN_TTL)
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := h.signToken(user, TOKEN_TYPE_REFRESH, now, REFRESH_TOKEN_TTL)
+	if err != nil {
+		return nil, err
+	}
+
+	return &TokenResponse{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		TokenType:    "Bearer",
+		ExpiresIn:    int(ACCESS_TOKEN_TTL.Seconds()),
+	}, nil
+}
+
+// verifyToken checks the signature, claims and revocation of the token. Tokens of external
+// identity providers are access tokens only.
+func (h *Handler) verifyToken(tokenString string, tokenType string) (*adminToken, error) {
+	token, claims, err := h.parseToken(tokenString)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+
+	verified := adminToken{Type: TOKEN_TYPE_ACCESS}
+	verified.ID, _ = claims["jti"].(string)
+	if exp, err := claims.GetExpirationTime(); err == nil && exp != nil {
+		verified.ExpiresAt = exp.Time
+	}
+
+	if _, verified.Local = token.Method.(*jwt.SigningMethodHMAC); verified.Local {
+		verified.Type, _ = claims["token_type"].(string)
+		verified.Username, _ = claims["sub"].(string)
+		verified.Role, _ = claims["role"].(string)
+		if verified.ID == "" {
+			return nil, ErrInvalidToken
+		}
+	} else {
+		if subject, _ := claims["sub"].(string); subject != "" {
+			verified.Username = externalUsername(h.tokens.Issuer, subject)
+		}
+		verified.Role = h.groupRole(claims["groups"])
+	}
+	if verified.Type != tokenType || verified.Username == "" || validateRole(verified.Role) != nil {
+		return nil, ErrInvalidToken
+	}
+
+	if verified.ID != "" {
+		revoked, err := h.store.IsTokenRevoked(verified.ID)
+		if err != nil {
+			return nil, err
+		}
+		if revoked {
+			return nil, ErrInvalidToken
+		}
+	}
+
+	return &verified, nil
+}
+
+// parseToken verifies the signature, expiry and issuer of the token. The algorithm in its
+// header picks the parser: HS256 tokens are signed by this API, RS and ES tokens by the
+// external identity provider, whose parser also checks the audience when one is set.
+func (h *Handler) parseToken(tokenString string) (*jwt.Token, jwt.MapClaims, error) {
+	unverified, _, err := jwt.NewParser().ParseUnverified(tokenString, jwt.MapClaims{})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var options []jwt.ParserOption
+	var key jwt.Keyfunc
+	if slices.Contains(localSigningMethods, unverified.Method.Alg()) {
+		if len(h.tokens.Secret) == 0 {
+			return nil, nil, ErrTokensNotConfigured
+		}
+		options = []jwt.ParserOption{jwt.WithValidMethods(localSigningMethods), jwt.WithIssuer(TOKEN_ISSUER)}
+		key = func(token *jwt.Token) (interface{}, error) {
+			return h.tokens.Secret, nil
+		}
+	} else {
+		if h.tokens.Keys == nil {
+			return nil, nil, ErrUnknownSigningKey
+		}
+		options = []jwt.ParserOption{jwt.WithValidMethods(externalSigningMethods), jwt.WithIssuer(h.tokens.Issuer)}
+		if h.tokens.Audience != "" {
+			options = append(options, jwt.WithAudience(h.tokens.Audience))
+		}
+		key = func(token *jwt.Token) (interface{}, error) {
+			kid, _ := token.Header["kid"].(string)
+			return h.tokens.Keys.PublicKey(kid)
+		}
+	}
+
+	claims := jwt.MapClaims{}
+	options = append(options, jwt.WithExpirationRequired())
+	token, err := jwt.NewParser(options...).ParseWithClaims(tokenString, claims, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return token, claims, nil
+}
+
+// externalUsername identifies an admin of the identity provider by the immutable issuer
+// and subject of their token rather than a display name they may be able to change.
+func externalUsername(issuer, subject string) string {
+	return EXTERNAL_USERNAME_PREFIX + issuer + "#" + subject
+}
+
+// groupRole returns the most privileged role mapped to the groups of an external token,
+// or an empty role when none of them is mapped.
+func (h *Handler) groupRole(claim interface{}) string {
+	groups, _ := claim.([]interface{})
+	role := ""
+	for _, group := range groups {
+		name, _ := group.(string)
+		mapped, ok := h.tokens.GroupRoles[name]
+		if ok && slices.Index(roles, mapped) > slices.Index(roles, role) {
+			role = mapped
+		}
+	}
+	return role
+}
+
+// activeUser returns the admin user of a token signed by this API, so that deactivating
+// the user also rejects their tokens.
+func (h *Handler) activeUser(token *adminToken) (*postgres.AdminUser, error) {
+	user, err := h.store.GetAdminUser(token.Username)
+	if err != nil {
+		return nil, err
+	}
+	if !user.Active {
+		return nil, ErrInvalidToken
+	}
+	return user, nil
+}
+
+func bearerToken(c echo.Context) (string, bool) {
+	scheme, token, ok := strings.Cut(c.Request().Header.Get(echo.HeaderAuthorization), " ")
+	if !ok || !strings.EqualFold(scheme, "Bearer") {
+		return "", false
+	}
+	return strings.TrimSpace(token), true
+}
+
+// TokenAuth authenticates requests with a bearer access token and stores the username and
+// role of the admin in the context. Requests without a bearer token are passed on to the
+// next authentication middleware.
+func (h *Handler) TokenAuth() echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			tokenString, ok := bearerToken(c)
+			if !ok {
+				return next(c)
+			}
+
+			token, err := h.verifyToken(tokenString, TOKEN_TYPE_ACCESS)
+			if err == nil && token.Local {
+				// The role is read from the user, so changing it takes effect before the
+				// access token expires.
+				var user *postgres.AdminUser
+				user, err = h.activeUser(token)
+				if err == nil {
+					token.Role = user.Role
+				}
+			}
+			if err != nil {
+				c.Response().Header().Set(echo.HeaderWWWAuthenticate, `Bearer error="invalid_token"`)
+				return c.JSON(http.StatusUnauthorized, &Err{
+					Message: ErrInvalidToken.Error(),
+				})
+			}
+
+			c.Set(ADMIN_USERNAME_CONTEXT_KEY, token.Username)
+			c.Set(ADMIN_ROLE_CONTEXT_KEY, token.Role)
+			c.Set(ADMIN_TOKEN_CONTEXT_KEY, token)
+			return next(c)
+		}
+	}
+}
+
+// Login exchanges admin credentials for an access token and a refresh token.
+func (h *Handler) Login(c echo.Context) error {
+	if len(h.tokens.Secret) == 0 {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: ErrTokensNotConfigured.Error(),
+		})
+	}
+
+	var req LoginRequest
+	err := c.Bind(&req)
+	if err != nil || req.Username == "" || req.Password == "" {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	user, err := h.checkCredentials(req.Username, req.Password)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to login",
+		})
+	}
+	if user == nil {
+		return c.JSON(http.StatusUnauthorized, &Err{
+			Message: ErrInvalidCredentials.Error(),
+		})
+	}
+
+	res, err := h.issueTokens(*user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to issue tokens",
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// RefreshToken exchanges a refresh token for new tokens carrying the current role of the
+// admin. The refresh token is revoked so it can be used only once.
+func (h *Handler) RefreshToken(c echo.Context) error {
+	var req RefreshTokenRequest
+	err := c.Bind(&req)
+	if err != nil || req.RefreshToken == "" {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	token, err := h.verifyToken(req.RefreshToken, TOKEN_TYPE_REFRESH)
+	var user *postgres.AdminUser
+	if err == nil {
+		user, err = h.activeUser(token)
+	}
+	if err != nil {
+		return c.JSON(http.StatusUnauthorized, &Err{
+			Message: ErrInvalidToken.Error(),
+		})
+	}
+
+	revoked, err := h.store.RevokeToken(token.ID, token.ExpiresAt, token.Username)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to refresh token",
+		})
+	}
+	// A concurrent refresh with the same token revoked it first.
+	if !revoked {
+		return c.JSON(http.StatusUnauthorized, &Err{
+			Message: ErrInvalidToken.Error(),
+		})
+	}
+
+	res, err := h.issueTokens(*user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to issue tokens",
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+// Logout revokes the access token of the request and the refresh token of the body, if any.
+func (h *Handler) Logout(c echo.Context) error {
+	var req RefreshTokenRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	revoke := []*adminToken{}
+	if token, ok := c.Get(ADMIN_TOKEN_CONTEXT_KEY).(*adminToken); ok && token.ID != "" {
+		revoke = append(revoke, token)
+	}
+	if req.RefreshToken != "" {
+		token, err := h.verifyToken(req.RefreshToken, TOKEN_TYPE_REFRESH)
+		if err != nil || token.Username != actor(c) {
+			return c.JSON(http.StatusBadRequest, &Err{
+				Message: ErrInvalidToken.Error(),
+			})
+		}
+		revoke = append(revoke, token)
+	}
+
+	for _, token := range revoke {
+		_, err = h.store.RevokeToken(token.ID, token.ExpiresAt, actor(c))
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, &Err{
+				Message: "unable to revoke token",
+			})
+		}
+	}
+
+	return c.NoContent(http.StatusNoContent)
+}
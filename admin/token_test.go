@@ -0,0 +1,354 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/labstack/echo/v4"
+)
+
+var TEST_TOKEN_SECRET = []byte("test-secret")
+
+func newTokenHandler(t *testing.T) (*Handler, *StubAdminHandler) {
+	t.Helper()
+
+	stub := newUserStub(t)
+	handler := New(stub, nil)
+	handler.UseTokens(TokenConfig{Secret: TEST_TOKEN_SECRET})
+	return handler, stub
+}
+
+func login(t *testing.T, handler *Handler, username, password string) TokenResponse {
+	t.Helper()
+
+	c, rec := configContext(http.MethodPost, "/admin/auth/login", `{"username": "`+username+`", "password": "`+password+`"}`, "")
+	err := handler.Login(c)
+	if err != nil {
+		t.Fatalf("unable to login: %v", err)
+	}
+	if rec.Code != http.StatusOK {
+		t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+	}
+
+	var res TokenResponse
+	err = json.Unmarshal(rec.Body.Bytes(), &res)
+	if err != nil {
+		t.Fatalf("unable to unmarshal response: %v", err)
+	}
+	return res
+}
+
+func refresh(t *testing.T, handler *Handler, refreshToken string) (*httptest.ResponseRecorder, TokenResponse) {
+	t.Helper()
+
+	c, rec := configContext(http.MethodPost, "/admin/auth/refresh", `{"refreshToken": "`+refreshToken+`"}`, "")
+	err := handler.RefreshToken(c)
+	if err != nil {
+		t.Fatalf("unable to refresh token: %v", err)
+	}
+
+	var res TokenResponse
+	if rec.Code == http.StatusOK {
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Fatalf("unable to unmarshal response: %v", err)
+		}
+	}
+	return rec, res
+}
+
+// bearerContext runs TokenAuth on a request with the bearer token and returns the context
+// the next handler was called with, nil when it was not called.
+func bearerContext(t *testing.T, handler *Handler, token string) (echo.Context, *httptest.ResponseRecorder) {
+	t.Helper()
+
+	c, rec := configContext(http.MethodGet, "/admin/configs", "", "")
+	if token != "" {
+		c.Request().Header.Set(echo.HeaderAuthorization, "Bearer "+token)
+	}
+
+	var called echo.Context
+	err := handler.TokenAuth()(func(c echo.Context) error {
+		called = c
+		return c.NoContent(http.StatusOK)
+	})(c)
+	if err != nil {
+		t.Fatalf("unable to authenticate: %v", err)
+	}
+	return called, rec
+}
+
+func signTestToken(t *testing.T, claims jwt.MapClaims) string {
+	t.Helper()
+
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(TEST_TOKEN_SECRET)
+	if err != nil {
+		t.Fatalf("unable to sign token: %v", err)
+	}
+	return token
+}
+
+// concurrentRevocationStub never reports a token as revoked before it is revoked again,
+// as with two refreshes of the same token at once.
+type concurrentRevocationStub struct {
+	*StubAdminHandler
+}
+
+func (h *concurrentRevocationStub) IsTokenRevoked(id string) (bool, error) {
+	return false, nil
+}
+
+func TestParseGroupRoles(t *testing.T) {
+	groupRoles, err := ParseGroupRoles(" tax-editors:editor, tax-approvers : approver,")
+	if err != nil {
+		t.Fatalf("unable to parse group roles: %v", err)
+	}
+	if len(groupRoles) != 2 || groupRoles["tax-editors"] != ROLE_EDITOR || groupRoles["tax-approvers"] != ROLE_APPROVER {
+		t.Errorf("invalid group roles: got %v", groupRoles)
+	}
+
+	for _, value := range []string{"tax-editors", ":editor", "tax-owners:owner"} {
+		if _, err := ParseGroupRoles(value); err == nil {
+			t.Errorf("invalid group roles %q should be rejected", value)
+		}
+	}
+}
+
+func TestLogin(t *testing.T) {
+	t.Run("given valid credentials should issue tokens that authenticate the admin", func(t *testing.T) {
+		handler, _ := newTokenHandler(t)
+
+		res := login(t, handler, MAKER, MAKER+"-password")
+
+		if res.TokenType != "Bearer" || res.ExpiresIn != int(ACCESS_TOKEN_TTL.Seconds()) || res.RefreshToken == "" {
+			t.Errorf("invalid token response: got %+v", res)
+		}
+		c, rec := bearerContext(t, handler, res.AccessToken)
+		if c == nil {
+			t.Fatalf("access token rejected: %v", rec.Body.String())
+		}
+		if actor(c) != MAKER || c.Get(ADMIN_ROLE_CONTEXT_KEY) != ROLE_EDITOR {
+			t.Errorf("invalid context: got %v %v", actor(c), c.Get(ADMIN_ROLE_CONTEXT_KEY))
+		}
+	})
+
+	tests := []struct {
+		name     string
+		body     string
+		want     int
+		inactive bool
+	}{
+		{"given wrong password should return 401", `{"username": "adminTax", "password": "wrong-password"}`, http.StatusUnauthorized, false},
+		{"given inactive user should return 401", `{"username": "adminTax", "password": "adminTax-password"}`, http.StatusUnauthorized, true},
+		{"given missing password should return 400", `{"username": "adminTax"}`, http.StatusBadRequest, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, stub := newTokenHandler(t)
+			stub.Users[MAKER].Active = !tt.inactive
+
+			c, rec := configContext(http.MethodPost, "/admin/auth/login", tt.body, "")
+			err := handler.Login(c)
+			if err != nil {
+				t.Errorf("unable to login: %v", err)
+			}
+
+			if rec.Code != tt.want {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestTokenAuth(t *testing.T) {
+	t.Run("given no bearer token should pass the request on", func(t *testing.T) {
+		handler, _ := newTokenHandler(t)
+
+		c, _ := bearerContext(t, handler, "")
+
+		if c == nil || IsAuthenticated(c) {
+			t.Errorf("request should be passed on unauthenticated")
+		}
+	})
+
+	now := time.Now()
+	claims := func(overrides jwt.MapClaims) jwt.MapClaims {
+		claims := jwt.MapClaims{
+			"iss":        TOKEN_ISSUER,
+			"sub":        MAKER,
+			"role":       ROLE_EDITOR,
+			"token_type": TOKEN_TYPE_ACCESS,
+			"jti":        "token-1",
+			"exp":        now.Add(time.Minute).Unix(),
+		}
+		for key, value := range overrides {
+			claims[key] = value
+		}
+		return claims
+	}
+
+	tests := []struct {
+		name  string
+		token func(t *testing.T) string
+	}{
+		{"given expired token should return 401", func(t *testing.T) string {
+			return signTestToken(t, claims(jwt.MapClaims{"exp": now.Add(-time.Minute).Unix()}))
+		}},
+		{"given refresh token should return 401", func(t *testing.T) string {
+			return signTestToken(t, claims(jwt.MapClaims{"token_type": TOKEN_TYPE_REFRESH}))
+		}},
+		{"given token of another issuer should return 401", func(t *testing.T) string {
+			return signTestToken(t, claims(jwt.MapClaims{"iss": "someone-else"}))
+		}},
+		{"given token of unknown role should return 401", func(t *testing.T) string {
+			return signTestToken(t, claims(jwt.MapClaims{"role": "owner"}))
+		}},
+		{"given token signed with another secret should return 401", func(t *testing.T) string {
+			token, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, claims(nil)).SignedString([]byte("another-secret"))
+			return token
+		}},
+		{"given malformed token should return 401", func(t *testing.T) string {
+			return "not-a-token"
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			handler, _ := newTokenHandler(t)
+
+			c, rec := bearerContext(t, handler, tt.token(t))
+
+			if c != nil {
+				t.Errorf("token should be rejected")
+			}
+			if rec.Code != http.StatusUnauthorized {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusUnauthorized)
+			}
+		})
+	}
+
+	t.Run("given token of demoted admin should use the current role", func(t *testing.T) {
+		handler, stub := newTokenHandler(t)
+		res := login(t, handler, MAKER, MAKER+"-password")
+		stub.Users[MAKER].Role = ROLE_VIEWER
+
+		c, _ := bearerContext(t, handler, res.AccessToken)
+
+		if c == nil || c.Get(ADMIN_ROLE_CONTEXT_KEY) != ROLE_VIEWER {
+			t.Errorf("token should carry the current role")
+		}
+	})
+
+	t.Run("given token of deactivated admin should return 401", func(t *testing.T) {
+		handler, stub := newTokenHandler(t)
+		res := login(t, handler, MAKER, MAKER+"-password")
+		stub.Users[MAKER].Active = false
+
+		c, rec := bearerContext(t, handler, res.AccessToken)
+
+		if c != nil || rec.Code != http.StatusUnauthorized {
+			t.Errorf("token of deactivated admin should be rejected: got %v", rec.Code)
+		}
+	})
+}
+
+func TestRefreshToken(t *testing.T) {
+	t.Run("given refresh token should issue tokens with the current role once", func(t *testing.T) {
+		handler, stub := newTokenHandler(t)
+		tokens := login(t, handler, MAKER, MAKER+"-password")
+		stub.Users[MAKER].Role = ROLE_APPROVER
+
+		rec, refreshed := refresh(t, handler, tokens.RefreshToken)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		c, _ := bearerContext(t, handler, refreshed.AccessToken)
+		if c == nil || c.Get(ADMIN_ROLE_CONTEXT_KEY) != ROLE_APPROVER {
+			t.Errorf("refreshed token should carry the current role")
+		}
+
+		rec, _ = refresh(t, handler, tokens.RefreshToken)
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("reused refresh token: got %v want %v", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("given refresh token revoked by a concurrent refresh should return 401", func(t *testing.T) {
+		handler, stub := newTokenHandler(t)
+		tokens := login(t, handler, MAKER, MAKER+"-password")
+		// The concurrent refresh revokes the token after this one checked it.
+		handler.store = &concurrentRevocationStub{stub}
+		rec, _ := refresh(t, handler, tokens.RefreshToken)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		rec, _ = refresh(t, handler, tokens.RefreshToken)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("given access token should return 401", func(t *testing.T) {
+		handler, _ := newTokenHandler(t)
+		tokens := login(t, handler, MAKER, MAKER+"-password")
+
+		rec, _ := refresh(t, handler, tokens.AccessToken)
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusUnauthorized)
+		}
+	})
+}
+
+func TestLogout(t *testing.T) {
+	t.Run("given logout should revoke the access and refresh tokens", func(t *testing.T) {
+		handler, stub := newTokenHandler(t)
+		tokens := login(t, handler, MAKER, MAKER+"-password")
+
+		c, _ := bearerContext(t, handler, tokens.AccessToken)
+		logout, rec := configContext(http.MethodPost, "/admin/auth/logout", `{"refreshToken": "`+tokens.RefreshToken+`"}`, "")
+		logout.Set(ADMIN_USERNAME_CONTEXT_KEY, actor(c))
+		logout.Set(ADMIN_TOKEN_CONTEXT_KEY, c.Get(ADMIN_TOKEN_CONTEXT_KEY))
+		err := handler.Logout(logout)
+		if err != nil {
+			t.Errorf("unable to logout: %v", err)
+		}
+
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("invalid http status: got %v want %v", rec.Code, http.StatusNoContent)
+		}
+		if len(stub.Revoked) != 2 {
+			t.Errorf("invalid revoked tokens: got %v want %v", len(stub.Revoked), 2)
+		}
+		if c, _ := bearerContext(t, handler, tokens.AccessToken); c != nil {
+			t.Errorf("revoked access token should be rejected")
+		}
+		if rec, _ := refresh(t, handler, tokens.RefreshToken); rec.Code != http.StatusUnauthorized {
+			t.Errorf("revoked refresh token: got %v want %v", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("given refresh token of another admin should return 400", func(t *testing.T) {
+		handler, _ := newTokenHandler(t)
+		tokens := login(t, handler, "root", "root-password")
+
+		c, rec := configContext(http.MethodPost, "/admin/auth/logout", `{"refreshToken": "`+tokens.RefreshToken+`"}`, "")
+		c.Set(ADMIN_USERNAME_CONTEXT_KEY, MAKER)
+		err := handler.Logout(c)
+		if err != nil {
+			t.Errorf("unable to logout: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
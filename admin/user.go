@@ -0,0 +1,350 @@
+package admin
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"net/http"
+	"regexp"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// Viewers read configs, editors propose config changes, approvers review proposals and
+// superadmins may do everything, including managing admin users.
+const (
+	ROLE_VIEWER     = "viewer"
+	ROLE_EDITOR     = "editor"
+	ROLE_APPROVER   = "approver"
+	ROLE_SUPERADMIN = "superadmin"
+)
+
+const (
+	// ADMIN_ROLE_CONTEXT_KEY holds the role of the authenticated admin.
+	ADMIN_ROLE_CONTEXT_KEY = "adminRole"
+
+	MIN_PASSWORD_LENGTH = 8
+	// MAX_PASSWORD_LENGTH is the most bytes bcrypt hashes.
+	MAX_PASSWORD_LENGTH = 72
+)
+
+var (
+	roles           = []string{ROLE_VIEWER, ROLE_EDITOR, ROLE_APPROVER, ROLE_SUPERADMIN}
+	usernamePattern = regexp.MustCompile(`^[A-Za-z0-9._-]{3,64}$`)
+
+	// passwordHashCost is the bcrypt cost of stored password hashes.
+	passwordHashCost = bcrypt.DefaultCost
+
+	// unknownUserHash is compared against for unknown usernames so they take as long to
+	// reject as a wrong password.
+	unknownUserHash = sync.OnceValue(func() []byte {
+		hash, _ := bcrypt.GenerateFromPassword([]byte("unknown admin user"), passwordHashCost)
+		return hash
+	})
+
+	ErrAdminUserNotFound = errors.New("admin user not found")
+	ErrInsufficientRole  = errors.New("insufficient role")
+)
+
+type (
+	AdminUserResponse struct {
+		Username  string     `json:"username"`
+		Role      string     `json:"role"`
+		Active    bool       `json:"active"`
+		CreatedBy *string    `json:"createdBy,omitempty"`
+		CreatedAt *time.Time `json:"createdAt,omitempty"`
+		UpdatedBy *string    `json:"updatedBy,omitempty"`
+		UpdatedAt *time.Time `json:"updatedAt,omitempty"`
+	}
+
+	CreateAdminUserRequest struct {
+		Username string `json:"username"`
+		Password string `json:"password"`
+		Role     string `json:"role"`
+	}
+
+	// UpdateAdminUserRequest changes only the fields it sets.
+	UpdateAdminUserRequest struct {
+		Password *string `json:"password,omitempty"`
+		Role     *string `json:"role,omitempty"`
+		Active   *bool   `json:"active,omitempty"`
+	}
+)
+
+func newAdminUserResponse(user postgres.AdminUser) AdminUserResponse {
+	return AdminUserResponse{
+		Username:  user.Username,
+		Role:      user.Role,
+		Active:    user.Active,
+		CreatedBy: user.CreatedBy,
+		CreatedAt: user.CreatedAt,
+		UpdatedBy: user.UpdatedBy,
+		UpdatedAt: user.UpdatedAt,
+	}
+}
+
+func validateRole(role string) error {
+	if !slices.Contains(roles, role) {
+		return fmt.Errorf("role must be one of %v", roles)
+	}
+	return nil
+}
+
+func validatePassword(password string) error {
+	if len(password) < MIN_PASSWORD_LENGTH || len(password) > MAX_PASSWORD_LENGTH {
+		return fmt.Errorf("password must be between %d and %d characters", MIN_PASSWORD_LENGTH, MAX_PASSWORD_LENGTH)
+	}
+	return nil
+}
+
+func hashPassword(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), passwordHashCost)
+	return string(hash), err
+}
+
+// checkCredentials returns the active admin user with the credentials, or nil when they
+// do not match one.
+func (h *Handler) checkCredentials(username, password string) (*postgres.AdminUser, error) {
+	user, err := h.store.GetAdminUser(username)
+	if errors.Is(err, sql.ErrNoRows) {
+		bcrypt.CompareHashAndPassword(unknownUserHash(), []byte(password))
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)) != nil || !user.Active {
+		return nil, nil
+	}
+	return user, nil
+}
+
+// Authenticate checks the Basic Auth credentials against the active admin users and
+// stores the username and role of the admin in the context.
+func (h *Handler) Authenticate(username, password string, c echo.Context) (bool, error) {
+	user, err := h.checkCredentials(username, password)
+	if err != nil || user == nil {
+		return false, err
+	}
+
+	c.Set(ADMIN_USERNAME_CONTEXT_KEY, user.Username)
+	c.Set(ADMIN_ROLE_CONTEXT_KEY, user.Role)
+	return true, nil
+}
+
+// IsAuthenticated tells whether an earlier middleware authenticated the admin.
+func IsAuthenticated(c echo.Context) bool {
+	return actor(c) != ""
+}
+
+// RequireRole allows the request when the authenticated admin has one of the roles.
+// Superadmins are always allowed.
+func RequireRole(allowed ...string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			role, _ := c.Get(ADMIN_ROLE_CONTEXT_KEY).(string)
+			if role != ROLE_SUPERADMIN && !slices.Contains(allowed, role) {
+				return c.JSON(http.StatusForbidden, &Err{
+					Message: ErrInsufficientRole.Error(),
+				})
+			}
+			return next(c)
+		}
+	}
+}
+
+// BootstrapUser seeds a superadmin with the credentials while there are no admin users,
+// so that the first admin can sign in and create the others.
+func (h *Handler) BootstrapUser(username, password string) error {
+	if username == "" || password == "" {
+		return nil
+	}
+
+	count, err := h.store.CountAdminUsers()
+	if err != nil || count > 0 {
+		return err
+	}
+
+	return h.seedUser(username, password, ROLE_SUPERADMIN)
+}
+
+// ImportUsers seeds a superadmin for each of the username:password pairs that is not an
+// admin user yet. It carries over the admins of the former ADMIN_CREDENTIALS setting, who
+// had the same access as the bootstrap admin. Existing users keep their password and role.
+func (h *Handler) ImportUsers(credentials map[string]string) error {
+	for username, password := range credentials {
+		if username == "" || password == "" {
+			continue
+		}
+		err := h.seedUser(username, password, ROLE_SUPERADMIN)
+		if err != nil {
+			return fmt.Errorf("admin user %s: %w", username, err)
+		}
+	}
+	return nil
+}
+
+// seedUser creates the active admin user unless the username is taken.
+func (h *Handler) seedUser(username, password, role string) error {
+	hash, err := hashPassword(password)
+	if err != nil {
+		return err
+	}
+	_, err = h.store.CreateAdminUser(postgres.AdminUser{
+		Username:     username,
+		PasswordHash: hash,
+		Role:         role,
+		Active:       true,
+	})
+	if errors.Is(err, postgres.ErrAdminUserExists) {
+		return nil
+	}
+	return err
+}
+
+func (h *Handler) ListUsers(c echo.Context) error {
+	users, err := h.store.ListAdminUsers()
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to list admin users",
+		})
+	}
+
+	res := []AdminUserResponse{}
+	for _, user := range users {
+		res = append(res, newAdminUserResponse(user))
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		Users []AdminUserResponse `json:"users"`
+	}{
+		Users: res,
+	})
+}
+
+func (h *Handler) CreateUser(c echo.Context) error {
+	var req CreateAdminUserRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	if !usernamePattern.MatchString(req.Username) {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "username must be 3 to 64 letters, digits, '.', '_' or '-'",
+		})
+	}
+	err = validateRole(req.Role)
+	if err == nil {
+		err = validatePassword(req.Password)
+	}
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	hash, err := hashPassword(req.Password)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to create admin user",
+		})
+	}
+
+	createdBy := actor(c)
+	user, err := h.store.CreateAdminUser(postgres.AdminUser{
+		Username:     req.Username,
+		PasswordHash: hash,
+		Role:         req.Role,
+		Active:       true,
+		CreatedBy:    &createdBy,
+	})
+	if errors.Is(err, postgres.ErrAdminUserExists) {
+		return c.JSON(http.StatusConflict, &Err{
+			Message: err.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to create admin user",
+		})
+	}
+
+	return c.JSON(http.StatusCreated, newAdminUserResponse(*user))
+}
+
+// UpdateUser changes the password, role or active flag of the user. Admins cannot change
+// their own role or deactivate themselves, so the last superadmin is not locked out.
+func (h *Handler) UpdateUser(c echo.Context) error {
+	user, err := h.store.GetAdminUser(c.Param("username"))
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: ErrAdminUserNotFound.Error(),
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to get admin user",
+		})
+	}
+
+	var req UpdateAdminUserRequest
+	err = c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	updatedBy := actor(c)
+	if updatedBy == user.Username && ((req.Role != nil && *req.Role != user.Role) || (req.Active != nil && !*req.Active)) {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "unable to change your own role or deactivate yourself",
+		})
+	}
+
+	if req.Role != nil {
+		err = validateRole(*req.Role)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Err{
+				Message: err.Error(),
+			})
+		}
+		user.Role = *req.Role
+	}
+	if req.Active != nil {
+		user.Active = *req.Active
+	}
+	if req.Password != nil {
+		err = validatePassword(*req.Password)
+		if err != nil {
+			return c.JSON(http.StatusBadRequest, &Err{
+				Message: err.Error(),
+			})
+		}
+		user.PasswordHash, err = hashPassword(*req.Password)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, &Err{
+				Message: "unable to update admin user",
+			})
+		}
+	}
+	user.UpdatedBy = &updatedBy
+
+	user, err = h.store.UpdateAdminUser(*user)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to update admin user",
+		})
+	}
+
+	return c.JSON(http.StatusOK, newAdminUserResponse(*user))
+}
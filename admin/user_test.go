@@ -0,0 +1,260 @@
+package admin
+
+import (
+	"encoding/json"
+	"net/http"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func init() {
+	passwordHashCost = bcrypt.MinCost
+}
+
+func newUserStub(t *testing.T) *StubAdminHandler {
+	t.Helper()
+
+	stub := &StubAdminHandler{Users: map[string]*postgres.AdminUser{}}
+	for username, role := range map[string]string{"root": ROLE_SUPERADMIN, MAKER: ROLE_EDITOR, "auditor": ROLE_VIEWER} {
+		hash, err := hashPassword(username + "-password")
+		if err != nil {
+			t.Fatalf("unable to hash password: %v", err)
+		}
+		stub.Users[username] = &postgres.AdminUser{Username: username, PasswordHash: hash, Role: role, Active: true}
+	}
+	return stub
+}
+
+func TestAuthenticate(t *testing.T) {
+	stub := newUserStub(t)
+	stub.Users["auditor"].Active = false
+	handler := New(stub, nil)
+
+	tests := []struct {
+		name     string
+		username string
+		password string
+		want     bool
+	}{
+		{"given valid credentials should authenticate", MAKER, MAKER + "-password", true},
+		{"given wrong password should not authenticate", MAKER, "wrong-password", false},
+		{"given unknown username should not authenticate", "nobody", "nobody-password", false},
+		{"given inactive user should not authenticate", "auditor", "auditor-password", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := configContext(http.MethodGet, "/admin/configs", "", "")
+
+			got, err := handler.Authenticate(tt.username, tt.password, c)
+			if err != nil {
+				t.Errorf("unable to authenticate: %v", err)
+			}
+
+			if got != tt.want {
+				t.Errorf("invalid authentication: got %v want %v", got, tt.want)
+			}
+			if got && (actor(c) != tt.username || c.Get(ADMIN_ROLE_CONTEXT_KEY) != ROLE_EDITOR) {
+				t.Errorf("invalid context: got %v %v", actor(c), c.Get(ADMIN_ROLE_CONTEXT_KEY))
+			}
+		})
+	}
+}
+
+func TestRequireRole(t *testing.T) {
+	tests := []struct {
+		name string
+		role string
+		want int
+	}{
+		{"given allowed role should call the handler", ROLE_EDITOR, http.StatusOK},
+		{"given superadmin should call the handler", ROLE_SUPERADMIN, http.StatusOK},
+		{"given other role should return 403", ROLE_VIEWER, http.StatusForbidden},
+		{"given no role should return 403", "", http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := configContext(http.MethodPut, "/admin/configs/PERSONAL_DEDUCTION", "", "PERSONAL_DEDUCTION")
+			if tt.role != "" {
+				c.Set(ADMIN_ROLE_CONTEXT_KEY, tt.role)
+			}
+
+			next := func(c echo.Context) error {
+				return c.NoContent(http.StatusOK)
+			}
+			err := RequireRole(ROLE_EDITOR)(next)(c)
+			if err != nil {
+				t.Errorf("unable to check role: %v", err)
+			}
+
+			if rec.Code != tt.want {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestBootstrapUser(t *testing.T) {
+	t.Run("given no admin users should seed a superadmin", func(t *testing.T) {
+		stub := &StubAdminHandler{}
+		handler := New(stub, nil)
+
+		err := handler.BootstrapUser("adminTax", "admin!")
+		if err != nil {
+			t.Errorf("unable to bootstrap admin user: %v", err)
+		}
+
+		user := stub.Users["adminTax"]
+		if user == nil || user.Role != ROLE_SUPERADMIN || !user.Active {
+			t.Fatalf("invalid bootstrap user: got %+v", user)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("admin!")) != nil {
+			t.Errorf("password hash does not match the password")
+		}
+	})
+
+	t.Run("given existing admin users should not seed", func(t *testing.T) {
+		stub := newUserStub(t)
+		handler := New(stub, nil)
+
+		err := handler.BootstrapUser("bootstrapAdmin", "admin!")
+		if err != nil {
+			t.Errorf("unable to bootstrap admin user: %v", err)
+		}
+
+		if _, ok := stub.Users["bootstrapAdmin"]; ok {
+			t.Errorf("bootstrap user should not be created")
+		}
+	})
+}
+
+func TestImportUsers(t *testing.T) {
+	t.Run("given credentials should seed the missing admins and keep the existing ones", func(t *testing.T) {
+		stub := newUserStub(t)
+		existingHash := stub.Users["adminTax"].PasswordHash
+		handler := New(stub, nil)
+
+		err := handler.ImportUsers(map[string]string{
+			"adminChecker": "checker!",
+			"adminTax":     "changed!",
+		})
+		if err != nil {
+			t.Errorf("unable to import admin users: %v", err)
+		}
+
+		user := stub.Users["adminChecker"]
+		if user == nil || user.Role != ROLE_SUPERADMIN || !user.Active {
+			t.Fatalf("invalid imported user: got %+v", user)
+		}
+		if bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte("checker!")) != nil {
+			t.Errorf("password hash does not match the password")
+		}
+		if stub.Users["adminTax"].PasswordHash != existingHash || stub.Users["adminTax"].Role != ROLE_EDITOR {
+			t.Errorf("existing user should be left as it is: got %+v", stub.Users["adminTax"])
+		}
+	})
+}
+
+func TestCreateUser(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+		want int
+	}{
+		{"given valid user should create it", `{"username": "checker", "password": "checker-password", "role": "approver"}`, http.StatusCreated},
+		{"given taken username should return 409", `{"username": "auditor", "password": "auditor-password", "role": "viewer"}`, http.StatusConflict},
+		{"given unknown role should return 400", `{"username": "checker", "password": "checker-password", "role": "owner"}`, http.StatusBadRequest},
+		{"given short password should return 400", `{"username": "checker", "password": "short", "role": "approver"}`, http.StatusBadRequest},
+		{"given invalid username should return 400", `{"username": "a b", "password": "checker-password", "role": "approver"}`, http.StatusBadRequest},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := configContext(http.MethodPost, "/admin/users", tt.body, "")
+			c.Set(ADMIN_USERNAME_CONTEXT_KEY, "root")
+
+			stub := newUserStub(t)
+			handler := New(stub, nil)
+			err := handler.CreateUser(c)
+			if err != nil {
+				t.Errorf("unable to create user: %v", err)
+			}
+
+			if rec.Code != tt.want {
+				t.Fatalf("invalid http status: got %v want %v", rec.Code, tt.want)
+			}
+			if tt.want != http.StatusCreated {
+				return
+			}
+
+			var res AdminUserResponse
+			err = json.Unmarshal(rec.Body.Bytes(), &res)
+			if err != nil {
+				t.Errorf("unable to unmarshal response: %v", err)
+			}
+			if res.Username != "checker" || res.Role != ROLE_APPROVER || !res.Active || res.CreatedBy == nil || *res.CreatedBy != "root" {
+				t.Errorf("invalid user: got %+v", res)
+			}
+			if bcrypt.CompareHashAndPassword([]byte(stub.Users["checker"].PasswordHash), []byte("checker-password")) != nil {
+				t.Errorf("password hash does not match the password")
+			}
+		})
+	}
+}
+
+func TestUpdateUser(t *testing.T) {
+	tests := []struct {
+		name     string
+		username string
+		body     string
+		want     int
+	}{
+		{"given new role should update the user", MAKER, `{"role": "approver"}`, http.StatusOK},
+		{"given inactive flag should deactivate the user", MAKER, `{"active": false}`, http.StatusOK},
+		{"given own role change should return 400", "root", `{"role": "viewer"}`, http.StatusBadRequest},
+		{"given own deactivation should return 400", "root", `{"active": false}`, http.StatusBadRequest},
+		{"given unknown user should return 404", "nobody", `{"role": "viewer"}`, http.StatusNotFound},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, rec := configContext(http.MethodPut, "/admin/users/"+tt.username, tt.body, "")
+			c.SetParamNames("username")
+			c.SetParamValues(tt.username)
+			c.Set(ADMIN_USERNAME_CONTEXT_KEY, "root")
+
+			stub := newUserStub(t)
+			handler := New(stub, nil)
+			err := handler.UpdateUser(c)
+			if err != nil {
+				t.Errorf("unable to update user: %v", err)
+			}
+
+			if rec.Code != tt.want {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, tt.want)
+			}
+		})
+	}
+
+	t.Run("given new password should authenticate with it", func(t *testing.T) {
+		c, _ := configContext(http.MethodPut, "/admin/users/"+MAKER, `{"password": "new-password"}`, "")
+		c.SetParamNames("username")
+		c.SetParamValues(MAKER)
+		c.Set(ADMIN_USERNAME_CONTEXT_KEY, "root")
+
+		handler := New(newUserStub(t), nil)
+		err := handler.UpdateUser(c)
+		if err != nil {
+			t.Errorf("unable to update user: %v", err)
+		}
+
+		ok, err := handler.Authenticate(MAKER, "new-password", c)
+		if err != nil || !ok {
+			t.Errorf("unable to authenticate with new password: %v %v", ok, err)
+		}
+	})
+}
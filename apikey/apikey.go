@@ -0,0 +1,204 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"math"
+	"net/http"
+	"slices"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/bytesbanana/assessment-tax/tax"
+	"github.com/labstack/echo/v4"
+	"golang.org/x/time/rate"
+)
+
+// Scopes grant a key access to the calculation endpoints.
+const (
+	SCOPE_CALCULATE = "tax:calculate"
+	SCOPE_UPLOAD    = "tax:upload"
+	SCOPE_READ      = "tax:read"
+)
+
+const (
+	// HEADER carries the API key of a request.
+	HEADER = "X-API-Key"
+	// KEY_PREFIX starts every key so leaked keys are easy to recognise.
+	KEY_PREFIX = "atk_"
+	// PREFIX_LENGTH is how much of a key is stored in plain text to tell keys apart.
+	PREFIX_LENGTH = len(KEY_PREFIX) + 8
+
+	// DEFAULT_RATE_LIMIT and MAX_RATE_LIMIT are in requests per minute.
+	DEFAULT_RATE_LIMIT = 60
+	MAX_RATE_LIMIT     = 6_000
+
+	// API_KEY_CONTEXT_KEY holds the authenticated *postgres.APIKey, its ID is also set
+	// under tax.API_KEY_ID_CONTEXT_KEY for the tax handlers.
+	API_KEY_CONTEXT_KEY = "apiKey"
+
+	// LIMITER_IDLE_TIMEOUT is how long the rate limit of an unused key is kept. A limit
+	// is full again a minute after the last request, so dropping it later changes nothing.
+	LIMITER_IDLE_TIMEOUT = 10 * time.Minute
+)
+
+var (
+	Scopes = []string{SCOPE_CALCULATE, SCOPE_UPLOAD, SCOPE_READ}
+
+	ErrMissingKey   = errors.New("missing API key")
+	ErrInvalidKey   = errors.New("invalid, expired or revoked API key")
+	ErrMissingScope = errors.New("API key is not allowed to use this endpoint")
+	ErrRateLimited  = errors.New("rate limit exceeded")
+)
+
+type (
+	Storer interface {
+		GetAPIKeyByHash(hash string) (*postgres.APIKey, error)
+		RecordAPIKeyUsage(id int, endpoint string, at time.Time) error
+	}
+
+	// Guard authenticates, throttles and counts the requests of API keys. Rate limits are
+	// kept in memory, so each instance of the API applies them separately.
+	Guard struct {
+		store Storer
+
+		mu        sync.Mutex
+		limiters  map[int]*limiter
+		lastSweep time.Time
+	}
+
+	Err struct {
+		Message string `json:"message"`
+	}
+
+	limiter struct {
+		perMinute int
+		lastSeen  time.Time
+		*rate.Limiter
+	}
+)
+
+// Generate returns a new key with its stored prefix and hash. The key itself is not stored.
+func Generate() (key string, prefix string, hash string, err error) {
+	secret := make([]byte, 32)
+	_, err = rand.Read(secret)
+	if err != nil {
+		return "", "", "", err
+	}
+
+	key = KEY_PREFIX + base64.RawURLEncoding.EncodeToString(secret)
+	return key, key[:PREFIX_LENGTH], Hash(key), nil
+}
+
+// Hash is the SHA-256 hash of the key. Keys are random, so a slow password hash is not needed.
+func Hash(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return hex.EncodeToString(sum[:])
+}
+
+func New(store Storer) *Guard {
+	return &Guard{
+		store:    store,
+		limiters: map[int]*limiter{},
+	}
+}
+
+// allow takes a request from the rate limit of the key, or returns how long to wait.
+func (g *Guard) allow(key *postgres.APIKey, now time.Time) (bool, time.Duration) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+
+	g.sweep(now)
+	l, ok := g.limiters[key.ID]
+	if !ok || l.perMinute != key.RateLimit {
+		l = &limiter{
+			perMinute: key.RateLimit,
+			Limiter:   rate.NewLimiter(rate.Limit(float64(key.RateLimit)/time.Minute.Seconds()), key.RateLimit),
+		}
+		g.limiters[key.ID] = l
+	}
+	l.lastSeen = now
+
+	reservation := l.ReserveN(now, 1)
+	delay := reservation.DelayFrom(now)
+	if delay > 0 {
+		reservation.CancelAt(now)
+		return false, delay
+	}
+	return true, 0
+}
+
+// sweep drops the rate limits of keys idle for LIMITER_IDLE_TIMEOUT, so revoked and
+// expired keys do not keep theirs. It runs at most once per timeout.
+func (g *Guard) sweep(now time.Time) {
+	if now.Sub(g.lastSweep) < LIMITER_IDLE_TIMEOUT {
+		return
+	}
+	g.lastSweep = now
+	for id, l := range g.limiters {
+		if now.Sub(l.lastSeen) >= LIMITER_IDLE_TIMEOUT {
+			delete(g.limiters, id)
+		}
+	}
+}
+
+// Require allows requests with an active key granted the scope, within the rate limit of
+// the key, and counts them by route.
+func (g *Guard) Require(scope string) echo.MiddlewareFunc {
+	return func(next echo.HandlerFunc) echo.HandlerFunc {
+		return func(c echo.Context) error {
+			value := c.Request().Header.Get(HEADER)
+			if value == "" {
+				return c.JSON(http.StatusUnauthorized, &Err{
+					Message: ErrMissingKey.Error(),
+				})
+			}
+
+			now := time.Now()
+			key, err := g.store.GetAPIKeyByHash(Hash(value))
+			if errors.Is(err, sql.ErrNoRows) || (err == nil && !active(key, now)) {
+				return c.JSON(http.StatusUnauthorized, &Err{
+					Message: ErrInvalidKey.Error(),
+				})
+			}
+			if err != nil {
+				return c.JSON(http.StatusInternalServerError, &Err{
+					Message: "unable to check API key",
+				})
+			}
+
+			if !slices.Contains(key.Scopes, scope) {
+				return c.JSON(http.StatusForbidden, &Err{
+					Message: ErrMissingScope.Error(),
+				})
+			}
+
+			allowed, wait := g.allow(key, now)
+			if !allowed {
+				c.Response().Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(wait.Seconds()))))
+				return c.JSON(http.StatusTooManyRequests, &Err{
+					Message: ErrRateLimited.Error(),
+				})
+			}
+
+			err = g.store.RecordAPIKeyUsage(key.ID, c.Request().Method+" "+c.Path(), now)
+			if err != nil {
+				c.Logger().Errorf("unable to record usage of API key %d: %v", key.ID, err)
+			}
+
+			c.Set(API_KEY_CONTEXT_KEY, key)
+			c.Set(tax.API_KEY_ID_CONTEXT_KEY, key.ID)
+			return next(c)
+		}
+	}
+}
+
+func active(key *postgres.APIKey, now time.Time) bool {
+	return key.RevokedAt == nil && (key.ExpiresAt == nil || key.ExpiresAt.After(now))
+}
@@ -0,0 +1,194 @@
+package apikey
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/bytesbanana/assessment-tax/tax"
+	"github.com/labstack/echo/v4"
+)
+
+type StubAPIKeyStore struct {
+	mu    sync.Mutex
+	keys  map[string]*postgres.APIKey
+	usage map[string]int
+}
+
+func (s *StubAPIKeyStore) GetAPIKeyByHash(hash string) (*postgres.APIKey, error) {
+	key, ok := s.keys[hash]
+	if !ok {
+		return nil, sql.ErrNoRows
+	}
+	return key, nil
+}
+
+func (s *StubAPIKeyStore) RecordAPIKeyUsage(id int, endpoint string, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usage[endpoint]++
+	return nil
+}
+
+func newStubStore(t *testing.T, keys ...postgres.APIKey) (*StubAPIKeyStore, []string) {
+	t.Helper()
+
+	store := &StubAPIKeyStore{keys: map[string]*postgres.APIKey{}, usage: map[string]int{}}
+	values := []string{}
+	for i, key := range keys {
+		value, prefix, hash, err := Generate()
+		if err != nil {
+			t.Fatalf("unable to generate API key: %v", err)
+		}
+		key.ID = i + 1
+		key.Prefix = prefix
+		key.KeyHash = hash
+		store.keys[hash] = &key
+		values = append(values, value)
+	}
+	return store, values
+}
+
+func request(t *testing.T, guard *Guard, scope string, value string) *httptest.ResponseRecorder {
+	t.Helper()
+
+	e := echo.New()
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(""))
+	if value != "" {
+		req.Header.Set(HEADER, value)
+	}
+	rec := httptest.NewRecorder()
+	c := e.NewContext(req, rec)
+	c.SetPath("/tax/calculations")
+
+	err := guard.Require(scope)(func(c echo.Context) error {
+		if _, ok := c.Get(API_KEY_CONTEXT_KEY).(*postgres.APIKey); !ok {
+			t.Errorf("API key should be set in the context")
+		}
+		if _, ok := c.Get(tax.API_KEY_ID_CONTEXT_KEY).(int); !ok {
+			t.Errorf("API key ID should be set in the context")
+		}
+		return c.NoContent(http.StatusOK)
+	})(c)
+	if err != nil {
+		t.Fatalf("unable to check API key: %v", err)
+	}
+	return rec
+}
+
+func TestGenerate(t *testing.T) {
+	key, prefix, hash, err := Generate()
+	if err != nil {
+		t.Fatalf("unable to generate API key: %v", err)
+	}
+	other, _, _, err := Generate()
+	if err != nil {
+		t.Fatalf("unable to generate API key: %v", err)
+	}
+
+	if !strings.HasPrefix(key, KEY_PREFIX) || len(prefix) != PREFIX_LENGTH || !strings.HasPrefix(key, prefix) {
+		t.Errorf("invalid key %v with prefix %v", key, prefix)
+	}
+	if hash != Hash(key) || len(hash) != 64 {
+		t.Errorf("invalid hash: got %v want %v", hash, Hash(key))
+	}
+	if key == other {
+		t.Errorf("generated keys should differ")
+	}
+}
+
+func TestRequire(t *testing.T) {
+	past := time.Now().Add(-time.Hour)
+	store, values := newStubStore(t,
+		postgres.APIKey{Name: "partner", Scopes: []string{SCOPE_CALCULATE}, RateLimit: 60},
+		postgres.APIKey{Name: "revoked", Scopes: []string{SCOPE_CALCULATE}, RateLimit: 60, RevokedAt: &past},
+		postgres.APIKey{Name: "expired", Scopes: []string{SCOPE_CALCULATE}, RateLimit: 60, ExpiresAt: &past},
+	)
+
+	tests := []struct {
+		name  string
+		scope string
+		value string
+		want  int
+	}{
+		{"given key with the scope should call the handler", SCOPE_CALCULATE, values[0], http.StatusOK},
+		{"given no key should return 401", SCOPE_CALCULATE, "", http.StatusUnauthorized},
+		{"given unknown key should return 401", SCOPE_CALCULATE, KEY_PREFIX + "unknown", http.StatusUnauthorized},
+		{"given revoked key should return 401", SCOPE_CALCULATE, values[1], http.StatusUnauthorized},
+		{"given expired key should return 401", SCOPE_CALCULATE, values[2], http.StatusUnauthorized},
+		{"given key without the scope should return 403", SCOPE_UPLOAD, values[0], http.StatusForbidden},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := request(t, New(store), tt.scope, tt.value)
+
+			if rec.Code != tt.want {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, tt.want)
+			}
+		})
+	}
+}
+
+func TestRateLimit(t *testing.T) {
+	t.Run("given requests over the rate limit should return 429 and count allowed requests", func(t *testing.T) {
+		store, values := newStubStore(t, postgres.APIKey{Name: "partner", Scopes: []string{SCOPE_CALCULATE}, RateLimit: 3})
+		guard := New(store)
+
+		for i := 0; i < 3; i++ {
+			rec := request(t, guard, SCOPE_CALCULATE, values[0])
+			if rec.Code != http.StatusOK {
+				t.Fatalf("request %d: invalid http status: got %v want %v", i+1, rec.Code, http.StatusOK)
+			}
+		}
+
+		rec := request(t, guard, SCOPE_CALCULATE, values[0])
+		if rec.Code != http.StatusTooManyRequests {
+			t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusTooManyRequests)
+		}
+		if rec.Header().Get("Retry-After") != "20" {
+			t.Errorf("invalid Retry-After: got %v want %v", rec.Header().Get("Retry-After"), "20")
+		}
+		if store.usage["POST /tax/calculations"] != 3 {
+			t.Errorf("invalid usage: got %v want %v", store.usage["POST /tax/calculations"], 3)
+		}
+	})
+
+	t.Run("given keys should limit each key separately", func(t *testing.T) {
+		store, values := newStubStore(t,
+			postgres.APIKey{Name: "first", Scopes: []string{SCOPE_CALCULATE}, RateLimit: 1},
+			postgres.APIKey{Name: "second", Scopes: []string{SCOPE_CALCULATE}, RateLimit: 1},
+		)
+		guard := New(store)
+
+		for _, value := range values {
+			rec := request(t, guard, SCOPE_CALCULATE, value)
+			if rec.Code != http.StatusOK {
+				t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusOK)
+			}
+		}
+	})
+
+	t.Run("given idle key should drop its rate limit", func(t *testing.T) {
+		guard := New(nil)
+		now := time.Now()
+		idle := &postgres.APIKey{ID: 1, RateLimit: 1}
+		busy := &postgres.APIKey{ID: 2, RateLimit: 1}
+
+		guard.allow(idle, now)
+		guard.allow(busy, now.Add(LIMITER_IDLE_TIMEOUT/2))
+		guard.allow(busy, now.Add(LIMITER_IDLE_TIMEOUT))
+
+		if _, ok := guard.limiters[idle.ID]; ok {
+			t.Errorf("rate limit of the idle key should be dropped")
+		}
+		if _, ok := guard.limiters[busy.ID]; !ok {
+			t.Errorf("rate limit of the busy key should be kept")
+		}
+	})
+}
@@ -1,33 +1,315 @@
+-- Table Definition
+CREATE TABLE "tax_rule_sets" (
+    "tax_year" int4 NOT NULL,
+    "name" varchar(255) NOT NULL,
+    "created_by" varchar,
+    "created_at" timestamptz DEFAULT now(),
+    PRIMARY KEY ("tax_year")
+);
+
+INSERT INTO "tax_rule_sets" ("tax_year", "name", "created_by")
+VALUES (2566, 'Personal income tax 2566', 'system'),
+    (2567, 'Personal income tax 2567', 'system'),
+    (2568, 'Personal income tax 2568', 'system');
+
 -- Sequence and defined type
 CREATE SEQUENCE IF NOT EXISTS config_id_seq;
 -- Table Definition
 CREATE TABLE "tax_configs" (
     "id" int4 NOT NULL DEFAULT nextval('config_id_seq'::regclass),
+    "tax_year" int4 NOT NULL REFERENCES "tax_rule_sets" ("tax_year"),
     "name" varchar(255) NOT NULL,
-    "key" varchar(255) NOT NULL UNIQUE,
+    "key" varchar(255) NOT NULL,
     "value" decimal(10, 2),
     "created_by" varchar,
-    "created_at" timestamp DEFAULT now(),
+    "created_at" timestamptz DEFAULT now(),
     "updated_by" varchar,
-    "updated_at" timestamp,
-    PRIMARY KEY ("id")
+    "updated_at" timestamptz,
+    -- A config without effective_from is the base value of the tax year, scheduled
+    -- values override it from effective_from until effective_to (inclusive).
+    "effective_from" date,
+    "effective_to" date,
+    PRIMARY KEY ("id"),
+    CHECK ("effective_to" IS NULL OR "effective_to" >= "effective_from")
+);
+
+CREATE UNIQUE INDEX ON "tax_configs" (
+    "tax_year",
+    "key",
+    COALESCE("effective_from", '0001-01-01'::date)
 );
 
 INSERT INTO "tax_configs" (
+        "tax_year",
         "name",
         "key",
         "value",
         "created_by"
     )
-VALUES (
-        'Personal tax deduction',
-        'PERSONAL_DEDUCTION',
-        60000,
-        'system'
-    ),
-    (
-        'Maximum K Receipt deduction',
-        'MAX_K_RECEIPT_DEDUCTION',
-        50000,
-        'system'
-    );
\ No newline at end of file
+SELECT r."tax_year",
+    c."name",
+    c."key",
+    c."value",
+    'system'
+FROM "tax_rule_sets" r
+    CROSS JOIN (
+        VALUES ('Personal tax deduction', 'PERSONAL_DEDUCTION', 60000),
+            (
+                'Maximum K Receipt deduction',
+                'MAX_K_RECEIPT_DEDUCTION',
+                50000
+            ),
+            (
+                'Donation deduction limit (% of net income)',
+                'DONATION_INCOME_PERCENT',
+                10
+            ),
+            (
+                'Spouse deduction',
+                'SPOUSE_DEDUCTION',
+                60000
+            ),
+            (
+                'Child deduction',
+                'CHILD_DEDUCTION',
+                30000
+            ),
+            (
+                'Second child born from 2018 deduction',
+                'CHILD_BORN_2018_DEDUCTION',
+                60000
+            ),
+            (
+                'Parent deduction',
+                'PARENT_DEDUCTION',
+                30000
+            ),
+            (
+                'Disabled dependant deduction',
+                'DISABLED_DEPENDANT_DEDUCTION',
+                60000
+            ),
+            (
+                'Maximum health insurance deduction',
+                'MAX_HEALTH_INSURANCE_DEDUCTION',
+                25000
+            ),
+            (
+                'Maximum life insurance deduction',
+                'MAX_LIFE_INSURANCE_DEDUCTION',
+                100000
+            ),
+            (
+                'Maximum life and health insurance deduction',
+                'MAX_INSURANCE_DEDUCTION',
+                100000
+            ),
+            (
+                'Maximum parents'' health insurance deduction',
+                'MAX_PARENTS_HEALTH_INSURANCE_DEDUCTION',
+                15000
+            ),
+            (
+                'Maximum social security deduction',
+                'MAX_SOCIAL_SECURITY_DEDUCTION',
+                9000
+            ),
+            (
+                'Maximum home loan interest deduction',
+                'MAX_HOME_LOAN_INTEREST_DEDUCTION',
+                100000
+            ),
+            (
+                'Maximum SSF deduction',
+                'MAX_SSF_DEDUCTION',
+                200000
+            ),
+            (
+                'Maximum RMF deduction',
+                'MAX_RMF_DEDUCTION',
+                500000
+            ),
+            (
+                'Maximum PVD deduction',
+                'MAX_PVD_DEDUCTION',
+                500000
+            ),
+            (
+                'Maximum GPF deduction',
+                'MAX_GPF_DEDUCTION',
+                500000
+            ),
+            (
+                'Maximum retirement savings deduction',
+                'MAX_RETIREMENT_SAVINGS_DEDUCTION',
+                500000
+            ),
+            (
+                'Maximum Thai ESG deduction',
+                'MAX_THAI_ESG_DEDUCTION',
+                300000
+            ),
+            (
+                'Maximum 40(1)-40(2) expense deduction',
+                'MAX_EMPLOYMENT_EXPENSE_DEDUCTION',
+                100000
+            ),
+            (
+                'Maximum 40(3) expense deduction',
+                'MAX_ROYALTY_EXPENSE_DEDUCTION',
+                100000
+            )
+    ) AS c("name", "key", "value");
+
+-- Append-only record of every change to tax_configs
+CREATE SEQUENCE IF NOT EXISTS config_history_id_seq;
+CREATE TABLE "tax_config_history" (
+    "id" int4 NOT NULL DEFAULT nextval('config_history_id_seq'::regclass),
+    "config_id" int4 NOT NULL REFERENCES "tax_configs" ("id"),
+    "tax_year" int4 NOT NULL,
+    "key" varchar(255) NOT NULL,
+    "effective_from" date,
+    "effective_to" date,
+    "old_value" decimal(10, 2),
+    "new_value" decimal(10, 2) NOT NULL,
+    "action" varchar(16) NOT NULL,
+    "reason" text NOT NULL DEFAULT '',
+    "changed_by" varchar NOT NULL,
+    "approved_by" varchar,
+    "changed_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX ON "tax_config_history" ("tax_year", "key");
+CREATE RULE "tax_config_history_no_update" AS ON UPDATE TO "tax_config_history" DO INSTEAD NOTHING;
+CREATE RULE "tax_config_history_no_delete" AS ON DELETE TO "tax_config_history" DO INSTEAD NOTHING;
+
+-- Config changes waiting for the approval of a second admin
+CREATE SEQUENCE IF NOT EXISTS config_proposal_id_seq;
+CREATE TABLE "tax_config_proposals" (
+    "id" int4 NOT NULL DEFAULT nextval('config_proposal_id_seq'::regclass),
+    "tax_year" int4 NOT NULL,
+    "key" varchar(255) NOT NULL,
+    "value" decimal(10, 2) NOT NULL,
+    "effective_from" date,
+    "effective_to" date,
+    "action" varchar(16) NOT NULL,
+    "reason" text NOT NULL DEFAULT '',
+    "status" varchar(16) NOT NULL DEFAULT 'pending',
+    "proposed_by" varchar NOT NULL,
+    "proposed_at" timestamptz NOT NULL DEFAULT now(),
+    "expires_at" timestamptz NOT NULL,
+    "reviewed_by" varchar,
+    "reviewed_at" timestamptz,
+    "review_comment" text NOT NULL DEFAULT '',
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX ON "tax_config_proposals" ("status");
+
+CREATE SEQUENCE IF NOT EXISTS tax_bracket_id_seq;
+CREATE TABLE "tax_brackets" (
+    "id" int4 NOT NULL DEFAULT nextval('tax_bracket_id_seq'::regclass),
+    "tax_year" int4 NOT NULL REFERENCES "tax_rule_sets" ("tax_year"),
+    "min_income" decimal(14, 2) NOT NULL,
+    "max_income" decimal(14, 2),
+    "rate" decimal(5, 4) NOT NULL,
+    "active" boolean NOT NULL DEFAULT true,
+    "created_by" varchar,
+    "created_at" timestamptz DEFAULT now(),
+    "updated_by" varchar,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+INSERT INTO "tax_brackets" (
+        "tax_year",
+        "min_income",
+        "max_income",
+        "rate",
+        "created_by"
+    )
+SELECT r."tax_year",
+    b."min_income",
+    b."max_income",
+    b."rate",
+    'system'
+FROM "tax_rule_sets" r
+    CROSS JOIN (
+        VALUES (0, 150000, 0),
+            (150000, 500000, 0.10),
+            (500000, 1000000, 0.15),
+            (1000000, 2000000, 0.20),
+            (2000000, NULL, 0.35)
+    ) AS b("min_income", "max_income", "rate");
+
+-- API keys of machine clients, only the SHA-256 hash of a key is stored
+CREATE SEQUENCE IF NOT EXISTS api_key_id_seq;
+CREATE TABLE "api_keys" (
+    "id" int4 NOT NULL DEFAULT nextval('api_key_id_seq'::regclass),
+    "name" varchar(255) NOT NULL,
+    "prefix" varchar(16) NOT NULL,
+    "key_hash" char(64) NOT NULL UNIQUE,
+    "scopes" text[] NOT NULL,
+    "rate_limit" int4 NOT NULL,
+    "expires_at" timestamptz,
+    "created_by" varchar,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    "revoked_by" varchar,
+    "revoked_at" timestamptz,
+    "request_count" int8 NOT NULL DEFAULT 0,
+    "last_used_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+CREATE TABLE "api_key_usage" (
+    "api_key_id" int4 NOT NULL REFERENCES "api_keys" ("id"),
+    "endpoint" varchar(255) NOT NULL,
+    "day" date NOT NULL,
+    "count" int8 NOT NULL,
+    PRIMARY KEY ("api_key_id", "endpoint", "day")
+);
+
+CREATE SEQUENCE IF NOT EXISTS tax_assessment_id_seq;
+CREATE TABLE "tax_assessments" (
+    "id" int4 NOT NULL DEFAULT nextval('tax_assessment_id_seq'::regclass),
+    "tax_year" int4 NOT NULL REFERENCES "tax_rule_sets" ("tax_year"),
+    "rule_set" varchar(255) NOT NULL,
+    "source" varchar(16) NOT NULL,
+    "input" jsonb NOT NULL,
+    "rules" jsonb NOT NULL,
+    "output" jsonb NOT NULL,
+    "tax" decimal(14, 2) NOT NULL,
+    "tax_refund" decimal(14, 2) NOT NULL,
+    -- Assessments are read back only with the API key that stored them
+    "api_key_id" int4 REFERENCES "api_keys" ("id"),
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("id")
+);
+
+CREATE INDEX ON "tax_assessments" ("tax_year", "created_at");
+CREATE INDEX ON "tax_assessments" ("api_key_id", "id");
+
+-- Admin accounts, the first superadmin is seeded from ADMIN_USERNAME and ADMIN_PASSWORD
+CREATE SEQUENCE IF NOT EXISTS admin_user_id_seq;
+CREATE TABLE "admin_users" (
+    "id" int4 NOT NULL DEFAULT nextval('admin_user_id_seq'::regclass),
+    "username" varchar(64) NOT NULL UNIQUE,
+    "password_hash" varchar(255) NOT NULL,
+    "role" varchar(16) NOT NULL,
+    "active" boolean NOT NULL DEFAULT true,
+    "created_by" varchar,
+    "created_at" timestamptz NOT NULL DEFAULT now(),
+    "updated_by" varchar,
+    "updated_at" timestamptz,
+    PRIMARY KEY ("id")
+);
+
+-- Revoked admin tokens, kept until they would have expired
+CREATE TABLE "revoked_tokens" (
+    "jti" varchar(64) NOT NULL,
+    "expires_at" timestamptz NOT NULL,
+    "revoked_by" varchar,
+    "revoked_at" timestamptz NOT NULL DEFAULT now(),
+    PRIMARY KEY ("jti")
+);
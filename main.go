@@ -2,16 +2,19 @@ package main
 
 import (
 	"context"
+	"crypto/rand"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
 
 	"github.com/bytesbanana/assessment-tax/admin"
+	"github.com/bytesbanana/assessment-tax/apikey"
 	"github.com/bytesbanana/assessment-tax/postgres"
 	"github.com/bytesbanana/assessment-tax/tax"
 
@@ -19,15 +22,64 @@ import (
 	"github.com/labstack/echo/v4/middleware"
 )
 
-func basicAuthMiddleware() echo.MiddlewareFunc {
-	return middleware.BasicAuth(func(username, password string, c echo.Context) (bool, error) {
+// adminCredentials returns the passwords by username of the comma separated
+// username:password pairs of ADMIN_CREDENTIALS, the admins besides ADMIN_USERNAME.
+func adminCredentials() map[string]string {
+	credentials := map[string]string{}
+	for _, pair := range strings.Split(os.Getenv("ADMIN_CREDENTIALS"), ",") {
+		username, password, ok := strings.Cut(strings.TrimSpace(pair), ":")
+		if ok && username != "" {
+			credentials[username] = password
+		}
+	}
+	return credentials
+}
 
-		if username == os.Getenv("ADMIN_USERNAME") && password == os.Getenv("ADMIN_PASSWORD") {
-			return true, nil
+// tokenConfig reads the admin token settings. Without ADMIN_JWT_SECRET tokens are signed
+// with a random secret and do not survive a restart. An external identity provider is
+// trusted when ADMIN_JWKS_URL, or ADMIN_JWKS_FILE for a local key set, is set, and its
+// groups are mapped to roles by ADMIN_OIDC_GROUP_ROLES, e.g. "tax-approvers:approver".
+func tokenConfig() (admin.TokenConfig, error) {
+	config := admin.TokenConfig{
+		Secret:   []byte(os.Getenv("ADMIN_JWT_SECRET")),
+		Issuer:   os.Getenv("ADMIN_OIDC_ISSUER"),
+		Audience: os.Getenv("ADMIN_OIDC_AUDIENCE"),
+	}
+	if len(config.Secret) == 0 {
+		log.Println("ADMIN_JWT_SECRET is not set, admin tokens are signed with a random secret")
+		config.Secret = make([]byte, 32)
+		if _, err := rand.Read(config.Secret); err != nil {
+			return config, err
 		}
+	}
 
-		return false, nil
-	})
+	if url := os.Getenv("ADMIN_JWKS_URL"); url != "" {
+		config.Keys = admin.NewRemoteKeySet(url)
+	} else if file := os.Getenv("ADMIN_JWKS_FILE"); file != "" {
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return config, err
+		}
+		keys, err := admin.ParseKeySet(data)
+		if err != nil {
+			return config, err
+		}
+		config.Keys = keys
+	}
+	if config.Keys != nil && config.Issuer == "" {
+		return config, fmt.Errorf("ADMIN_OIDC_ISSUER is required to verify external tokens")
+	}
+
+	groupRoles, err := admin.ParseGroupRoles(os.Getenv("ADMIN_OIDC_GROUP_ROLES"))
+	if err != nil {
+		return config, fmt.Errorf("ADMIN_OIDC_GROUP_ROLES: %w", err)
+	}
+	if config.Keys != nil && len(groupRoles) == 0 {
+		return config, fmt.Errorf("ADMIN_OIDC_GROUP_ROLES is required to authorize external tokens")
+	}
+	config.GroupRoles = groupRoles
+
+	return config, nil
 }
 
 func main() {
@@ -46,14 +98,69 @@ func main() {
 		return c.String(http.StatusOK, "Hello, Go Bootcamp!")
 	})
 
+	apiKeys := apikey.New(p)
 	taxHandler := tax.New(p)
-	e.POST("/tax/calculations", taxHandler.CalculateTax)
-	e.POST("/tax/calculations/upload-csv", taxHandler.CalculateTaxFromTaxFile)
+	e.POST("/tax/calculations", taxHandler.CalculateTax, apiKeys.Require(apikey.SCOPE_CALCULATE))
+	e.GET("/tax/calculations", taxHandler.ListTaxAssessments, apiKeys.Require(apikey.SCOPE_READ))
+	e.GET("/tax/calculations/:id", taxHandler.GetTaxAssessment, apiKeys.Require(apikey.SCOPE_READ))
+	e.POST("/tax/calculations/upload-csv", taxHandler.CalculateTaxFromTaxFile, apiKeys.Require(apikey.SCOPE_UPLOAD))
+	e.POST("/tax/gross-up", taxHandler.GrossUp, apiKeys.Require(apikey.SCOPE_CALCULATE))
+	e.POST("/tax/optimise", taxHandler.Optimise, apiKeys.Require(apikey.SCOPE_CALCULATE))
+	e.POST("/tax/payroll/withholding", taxHandler.CalculatePayrollWithholding, apiKeys.Require(apikey.SCOPE_CALCULATE))
+	e.POST("/tax/late-filing", taxHandler.CalculateLateFiling, apiKeys.Require(apikey.SCOPE_CALCULATE))
+	e.POST("/tax/instalments", taxHandler.CalculateInstalments, apiKeys.Require(apikey.SCOPE_CALCULATE))
+	e.POST("/tax/household", taxHandler.CalculateHousehold, apiKeys.Require(apikey.SCOPE_CALCULATE))
+
+	adminHandler := admin.New(p, taxHandler)
+	err = adminHandler.BootstrapUser(os.Getenv("ADMIN_USERNAME"), os.Getenv("ADMIN_PASSWORD"))
+	if err != nil {
+		log.Fatalf("unable to bootstrap admin user: %v", err)
+	}
+	err = adminHandler.ImportUsers(adminCredentials())
+	if err != nil {
+		log.Fatalf("unable to import admin users: %v", err)
+	}
+	tokens, err := tokenConfig()
+	if err != nil {
+		log.Fatalf("invalid admin token config: %v", err)
+	}
+	adminHandler.UseTokens(tokens)
+	e.POST("/admin/auth/login", adminHandler.Login)
+	e.POST("/admin/auth/refresh", adminHandler.RefreshToken)
+
+	viewer := admin.RequireRole(admin.ROLE_VIEWER, admin.ROLE_EDITOR, admin.ROLE_APPROVER)
+	editor := admin.RequireRole(admin.ROLE_EDITOR)
+	analyst := admin.RequireRole(admin.ROLE_EDITOR, admin.ROLE_APPROVER)
+	approver := admin.RequireRole(admin.ROLE_APPROVER)
+	// Editors reach the reject route to withdraw their own proposals.
+	rejecter := admin.RequireRole(admin.ROLE_EDITOR, admin.ROLE_APPROVER)
+	superadmin := admin.RequireRole(admin.ROLE_SUPERADMIN)
 
-	adminHandler := admin.New(p)
 	adminGroup := e.Group("/admin")
-	adminGroup.Use(basicAuthMiddleware())
-	adminGroup.POST("/deductions/k-receipt", adminHandler.SetPersonalDeductionsConfig)
+	adminGroup.Use(adminHandler.TokenAuth())
+	adminGroup.Use(middleware.BasicAuthWithConfig(middleware.BasicAuthConfig{
+		Skipper:   admin.IsAuthenticated,
+		Validator: adminHandler.Authenticate,
+	}))
+	adminGroup.POST("/auth/logout", adminHandler.Logout, viewer)
+	adminGroup.POST("/deductions/personal", adminHandler.SetPersonalDeductionsConfig, editor)
+	adminGroup.POST("/deductions/k-receipt", adminHandler.SetMaxKReceiptDeduction, editor)
+	adminGroup.GET("/configs", adminHandler.ListConfigs, viewer)
+	adminGroup.GET("/configs/:key", adminHandler.GetConfig, viewer)
+	adminGroup.PUT("/configs/:key", adminHandler.SetConfig, editor)
+	adminGroup.GET("/configs/:key/history", adminHandler.GetConfigHistory, viewer)
+	adminGroup.POST("/configs/:key/rollback", adminHandler.RollbackConfig, editor)
+	adminGroup.POST("/configs/impact", adminHandler.AnalyseConfigImpact, analyst)
+	adminGroup.GET("/proposals", adminHandler.ListProposals, viewer)
+	adminGroup.POST("/proposals/:id/approve", adminHandler.ApproveProposal, approver)
+	adminGroup.POST("/proposals/:id/reject", adminHandler.RejectProposal, rejecter)
+	adminGroup.GET("/users", adminHandler.ListUsers, superadmin)
+	adminGroup.POST("/users", adminHandler.CreateUser, superadmin)
+	adminGroup.PUT("/users/:username", adminHandler.UpdateUser, superadmin)
+	adminGroup.GET("/api-keys", adminHandler.ListAPIKeys, superadmin)
+	adminGroup.POST("/api-keys", adminHandler.CreateAPIKey, superadmin)
+	adminGroup.DELETE("/api-keys/:id", adminHandler.RevokeAPIKey, superadmin)
+	adminGroup.GET("/api-keys/:id/usage", adminHandler.GetAPIKeyUsage, superadmin)
 
 	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
 	defer stop()
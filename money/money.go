@@ -0,0 +1,254 @@
+package money
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// Money is an amount of Thai baht stored as a whole number of satang,
+// so sums of many amounts never drift the way float64 does.
+type Money int64
+
+// Rate is a ratio stored in ten-thousandths, matching the decimal(5, 4) columns
+// (e.g. 0.35 is Rate(3500)).
+type Rate int64
+
+const (
+	SatangPerBaht = 100
+	RateScale     = 10_000
+
+	// Unlimited is used as the upper bound of open-ended ranges.
+	Unlimited Money = math.MaxInt64
+)
+
+var ErrInvalidAmount = errors.New("invalid amount")
+
+func FromBaht(baht int64) Money {
+	return Money(baht * SatangPerBaht)
+}
+
+// Parse reads a decimal (or JSON number) string and rounds it half away from zero to the satang.
+func Parse(s string) (Money, error) {
+	v, err := parseScaled(s, SatangPerBaht)
+	return Money(v), err
+}
+
+func MustParse(s string) Money {
+	m, err := Parse(s)
+	if err != nil {
+		panic(err)
+	}
+	return m
+}
+
+func Min(a, b Money) Money {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func Max(a, b Money) Money {
+	if a > b {
+		return a
+	}
+	return b
+}
+
+// MulRate multiplies the amount by the rate, rounding half away from zero to the satang.
+func (m Money) MulRate(r Rate) Money {
+	return Money(divRound(new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(r))), RateScale))
+}
+
+// DivRate divides the amount by the rate, rounding half away from zero to the satang.
+func (m Money) DivRate(r Rate) Money {
+	return Money(divRound(new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(RateScale)), int64(r)))
+}
+
+// Mul multiplies the amount by a whole number.
+func (m Money) Mul(n int64) Money {
+	return m * Money(n)
+}
+
+// Div divides the amount by a whole number, rounding half away from zero to the satang.
+func (m Money) Div(n int64) Money {
+	return Money(divRound(big.NewInt(int64(m)), n))
+}
+
+// Share returns the part of the amount that part is of whole, rounding half away from
+// zero to the satang. The product is computed on big.Int so it never overflows.
+func (m Money) Share(part, whole Money) Money {
+	n := new(big.Int).Mul(big.NewInt(int64(m)), big.NewInt(int64(part)))
+	return Money(divRoundBig(n, big.NewInt(int64(whole))).Int64())
+}
+
+// Abs returns the amount without its sign.
+func (m Money) Abs() Money {
+	if m < 0 {
+		return -m
+	}
+	return m
+}
+
+// Percent reads the amount as a percentage, e.g. 10.00 is a rate of 0.10.
+func (m Money) Percent() Rate {
+	return Rate(m)
+}
+
+// Baht returns the whole baht part of the amount.
+func (m Money) Baht() int64 {
+	return int64(m) / SatangPerBaht
+}
+
+func (m Money) String() string {
+	return formatScaled(int64(m), SatangPerBaht, 2)
+}
+
+func (m Money) MarshalJSON() ([]byte, error) {
+	return []byte(m.String()), nil
+}
+
+func (m *Money) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	v, err := Parse(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*m = v
+	return nil
+}
+
+func (m *Money) Scan(src any) error {
+	v, err := scanScaled(src, SatangPerBaht)
+	if err != nil {
+		return err
+	}
+	*m = Money(v)
+	return nil
+}
+
+func (m Money) Value() (driver.Value, error) {
+	return m.String(), nil
+}
+
+func ParseRate(s string) (Rate, error) {
+	v, err := parseScaled(s, RateScale)
+	return Rate(v), err
+}
+
+func MustParseRate(s string) Rate {
+	r, err := ParseRate(s)
+	if err != nil {
+		panic(err)
+	}
+	return r
+}
+
+func (r Rate) String() string {
+	return formatScaled(int64(r), RateScale, 4)
+}
+
+func (r Rate) MarshalJSON() ([]byte, error) {
+	return []byte(r.String()), nil
+}
+
+func (r *Rate) UnmarshalJSON(data []byte) error {
+	s := string(data)
+	if s == "null" {
+		return nil
+	}
+
+	v, err := ParseRate(strings.Trim(s, `"`))
+	if err != nil {
+		return err
+	}
+	*r = v
+	return nil
+}
+
+func (r *Rate) Scan(src any) error {
+	v, err := scanScaled(src, RateScale)
+	if err != nil {
+		return err
+	}
+	*r = Rate(v)
+	return nil
+}
+
+func (r Rate) Value() (driver.Value, error) {
+	return r.String(), nil
+}
+
+func parseScaled(s string, scale int64) (int64, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return 0, ErrInvalidAmount
+	}
+
+	rat, ok := new(big.Rat).SetString(s)
+	if !ok {
+		return 0, fmt.Errorf("%w: %q", ErrInvalidAmount, s)
+	}
+
+	rat.Mul(rat, new(big.Rat).SetInt64(scale))
+	num := divRoundBig(rat.Num(), rat.Denom())
+	if !num.IsInt64() {
+		return 0, fmt.Errorf("%w: %q is out of range", ErrInvalidAmount, s)
+	}
+
+	return num.Int64(), nil
+}
+
+func scanScaled(src any, scale int64) (int64, error) {
+	switch v := src.(type) {
+	case nil:
+		return 0, nil
+	case []byte:
+		return parseScaled(string(v), scale)
+	case string:
+		return parseScaled(v, scale)
+	case int64:
+		return v * scale, nil
+	case float64:
+		return parseScaled(strconv.FormatFloat(v, 'f', -1, 64), scale)
+	default:
+		return 0, fmt.Errorf("%w: unsupported type %T", ErrInvalidAmount, src)
+	}
+}
+
+func formatScaled(v int64, scale int64, decimals int) string {
+	sign := ""
+	u := uint64(v)
+	if v < 0 {
+		sign = "-"
+		u = uint64(-v)
+	}
+
+	return fmt.Sprintf("%s%d.%0*d", sign, u/uint64(scale), decimals, u%uint64(scale))
+}
+
+func divRound(n *big.Int, d int64) int64 {
+	return divRoundBig(n, big.NewInt(d)).Int64()
+}
+
+// divRoundBig divides n by d rounding half away from zero.
+func divRoundBig(n *big.Int, d *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(n, d, new(big.Int))
+	if new(big.Int).Mul(new(big.Int).Abs(r), big.NewInt(2)).Cmp(new(big.Int).Abs(d)) >= 0 {
+		if (n.Sign() < 0) != (d.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
@@ -0,0 +1,134 @@
+package money
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	testCases := []struct {
+		input string
+		want  Money
+	}{
+		{"500000", 50_000_000},
+		{"500000.000000", 50_000_000},
+		{"0.1", 10},
+		{"0.30000000000000004", 30},
+		{"10.005", 1_001},
+		{"-10.005", -1_001},
+		{"1e5", 10_000_000},
+	}
+
+	for _, tc := range testCases {
+		got, err := Parse(tc.input)
+		if err != nil {
+			t.Errorf("unable to parse %q: %v", tc.input, err)
+		}
+		if got != tc.want {
+			t.Errorf("invalid amount for %q: got %d want %d", tc.input, got, tc.want)
+		}
+	}
+
+	for _, input := range []string{"", "abc", "1,000"} {
+		if _, err := Parse(input); err == nil {
+			t.Errorf("expected error for %q", input)
+		}
+	}
+}
+
+func TestSumDoesNotDrift(t *testing.T) {
+	sum := Money(0)
+	for i := 0; i < 10_000; i++ {
+		sum += MustParse("0.1")
+	}
+
+	if sum != FromBaht(1_000) {
+		t.Errorf("invalid sum: got %v want %v", sum, FromBaht(1_000))
+	}
+}
+
+func TestMulRate(t *testing.T) {
+	testCases := []struct {
+		amount Money
+		rate   Rate
+		want   Money
+	}{
+		{FromBaht(290_000), MustParseRate("0.1"), FromBaht(29_000)},
+		{MustParse("333.33"), MustParseRate("0.15"), MustParse("50.00")},
+		{MustParse("0.05"), MustParseRate("0.1"), MustParse("0.01")},
+		{FromBaht(1_000_000), MustParseRate("0.005"), FromBaht(5_000)},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.amount.MulRate(tc.rate); got != tc.want {
+			t.Errorf("invalid result for %v * %v: got %v want %v", tc.amount, tc.rate, got, tc.want)
+		}
+	}
+}
+
+func TestDivRate(t *testing.T) {
+	testCases := []struct {
+		amount Money
+		rate   Rate
+		want   Money
+	}{
+		{FromBaht(80_000), MustParseRate("0.8"), FromBaht(100_000)},
+		{FromBaht(100), MustParseRate("0.7"), MustParse("142.86")},
+		{FromBaht(100), MustParseRate("1"), FromBaht(100)},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.amount.DivRate(tc.rate); got != tc.want {
+			t.Errorf("invalid result for %v / %v: got %v want %v", tc.amount, tc.rate, got, tc.want)
+		}
+	}
+}
+
+func TestShare(t *testing.T) {
+	testCases := []struct {
+		amount Money
+		part   Money
+		whole  Money
+		want   Money
+	}{
+		{FromBaht(30_000), FromBaht(20_000), FromBaht(60_000), FromBaht(10_000)},
+		{FromBaht(100), FromBaht(1), FromBaht(3), MustParse("33.33")},
+		{FromBaht(50_000_000), FromBaht(40_000_000), FromBaht(80_000_000), FromBaht(25_000_000)},
+	}
+
+	for _, tc := range testCases {
+		if got := tc.amount.Share(tc.part, tc.whole); got != tc.want {
+			t.Errorf("invalid share of %v for %v / %v: got %v want %v", tc.amount, tc.part, tc.whole, got, tc.want)
+		}
+	}
+}
+
+func TestJSON(t *testing.T) {
+	var v struct {
+		Amount Money `json:"amount"`
+	}
+
+	err := json.Unmarshal([]byte(`{"amount": 1234.5}`), &v)
+	if err != nil {
+		t.Fatalf("unable to unmarshal: %v", err)
+	}
+
+	b, err := json.Marshal(v)
+	if err != nil {
+		t.Fatalf("unable to marshal: %v", err)
+	}
+
+	if string(b) != `{"amount":1234.50}` {
+		t.Errorf("invalid json: got %s", b)
+	}
+}
+
+func TestScan(t *testing.T) {
+	var m Money
+	if err := m.Scan([]byte("60000.00")); err != nil {
+		t.Fatalf("unable to scan: %v", err)
+	}
+	if m != FromBaht(60_000) {
+		t.Errorf("invalid amount: got %v want %v", m, FromBaht(60_000))
+	}
+}
@@ -0,0 +1,102 @@
+package postgres
+
+import (
+	"errors"
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// ErrAdminUserExists is returned when the username is already taken.
+var ErrAdminUserExists = errors.New("admin user already exists")
+
+// UNIQUE_VIOLATION is the postgres error code of a unique constraint violation.
+const UNIQUE_VIOLATION = "23505"
+
+type AdminUser struct {
+	ID           int        `postgres:"id"`
+	Username     string     `postgres:"username"`
+	PasswordHash string     `postgres:"password_hash"`
+	Role         string     `postgres:"role"`
+	Active       bool       `postgres:"active"`
+	CreatedBy    *string    `postgres:"created_by"`
+	CreatedAt    *time.Time `postgres:"created_at"`
+	UpdatedBy    *string    `postgres:"updated_by"`
+	UpdatedAt    *time.Time `postgres:"updated_at"`
+}
+
+const adminUserColumns = "id, username, password_hash, role, active, created_by, created_at, updated_by, updated_at"
+
+// GetAdminUser returns sql.ErrNoRows when the user does not exist.
+func (p *Postgres) GetAdminUser(username string) (*AdminUser, error) {
+	row := p.Db.QueryRow("SELECT "+adminUserColumns+" FROM admin_users WHERE username = $1", username)
+
+	return scanAdminUser(row)
+}
+
+func (p *Postgres) ListAdminUsers() ([]AdminUser, error) {
+	rows, err := p.Db.Query("SELECT " + adminUserColumns + " FROM admin_users ORDER BY username")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []AdminUser{}
+	for rows.Next() {
+		user, err := scanAdminUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, *user)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return users, nil
+}
+
+func (p *Postgres) CountAdminUsers() (int, error) {
+	var count int
+	err := p.Db.QueryRow("SELECT count(*) FROM admin_users").Scan(&count)
+	return count, err
+}
+
+// CreateAdminUser returns ErrAdminUserExists when the username is taken.
+func (p *Postgres) CreateAdminUser(user AdminUser) (*AdminUser, error) {
+	row := p.Db.QueryRow(`INSERT INTO admin_users (username, password_hash, role, active, created_by)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING `+adminUserColumns,
+		user.Username, user.PasswordHash, user.Role, user.Active, user.CreatedBy)
+
+	created, err := scanAdminUser(row)
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) && pqErr.Code == UNIQUE_VIOLATION {
+		return nil, ErrAdminUserExists
+	}
+	return created, err
+}
+
+// UpdateAdminUser stores the password hash, role and active flag of the user.
+// sql.ErrNoRows is returned when the user does not exist.
+func (p *Postgres) UpdateAdminUser(user AdminUser) (*AdminUser, error) {
+	row := p.Db.QueryRow(`UPDATE admin_users
+		SET password_hash = $2, role = $3, active = $4, updated_by = $5, updated_at = now()
+		WHERE username = $1
+		RETURNING `+adminUserColumns,
+		user.Username, user.PasswordHash, user.Role, user.Active, user.UpdatedBy)
+
+	return scanAdminUser(row)
+}
+
+func scanAdminUser(row rowScanner) (*AdminUser, error) {
+	var user AdminUser
+	err := row.Scan(&user.ID, &user.Username, &user.PasswordHash, &user.Role, &user.Active,
+		&user.CreatedBy, &user.CreatedAt, &user.UpdatedBy, &user.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &user, nil
+}
@@ -0,0 +1,130 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/lib/pq"
+)
+
+// APIKey identifies a machine client. RateLimit is in requests per minute.
+type APIKey struct {
+	ID           int        `postgres:"id"`
+	Name         string     `postgres:"name"`
+	Prefix       string     `postgres:"prefix"`
+	KeyHash      string     `postgres:"key_hash"`
+	Scopes       []string   `postgres:"scopes"`
+	RateLimit    int        `postgres:"rate_limit"`
+	ExpiresAt    *time.Time `postgres:"expires_at"`
+	CreatedBy    *string    `postgres:"created_by"`
+	CreatedAt    *time.Time `postgres:"created_at"`
+	RevokedBy    *string    `postgres:"revoked_by"`
+	RevokedAt    *time.Time `postgres:"revoked_at"`
+	RequestCount int64      `postgres:"request_count"`
+	LastUsedAt   *time.Time `postgres:"last_used_at"`
+}
+
+// APIKeyUsage counts the requests of a key to an endpoint on a day.
+type APIKeyUsage struct {
+	Endpoint string    `postgres:"endpoint"`
+	Day      time.Time `postgres:"day"`
+	Count    int64     `postgres:"count"`
+}
+
+const apiKeyColumns = "id, name, prefix, key_hash, scopes, rate_limit, expires_at, created_by, created_at, revoked_by, revoked_at, request_count, last_used_at"
+
+func (p *Postgres) CreateAPIKey(key APIKey) (*APIKey, error) {
+	row := p.Db.QueryRow(`INSERT INTO api_keys (name, prefix, key_hash, scopes, rate_limit, expires_at, created_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+		RETURNING `+apiKeyColumns,
+		key.Name, key.Prefix, key.KeyHash, pq.Array(key.Scopes), key.RateLimit, key.ExpiresAt, key.CreatedBy)
+
+	return scanAPIKey(row)
+}
+
+// GetAPIKeyByHash returns sql.ErrNoRows when no key has the hash.
+func (p *Postgres) GetAPIKeyByHash(hash string) (*APIKey, error) {
+	row := p.Db.QueryRow("SELECT "+apiKeyColumns+" FROM api_keys WHERE key_hash = $1", hash)
+
+	return scanAPIKey(row)
+}
+
+func (p *Postgres) ListAPIKeys() ([]APIKey, error) {
+	rows, err := p.Db.Query("SELECT " + apiKeyColumns + " FROM api_keys ORDER BY id")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	keys := []APIKey{}
+	for rows.Next() {
+		key, err := scanAPIKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *key)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return keys, nil
+}
+
+// RevokeAPIKey keeps the first revocation of a key revoked twice. sql.ErrNoRows is
+// returned when the key does not exist.
+func (p *Postgres) RevokeAPIKey(id int, revokedBy string) (*APIKey, error) {
+	row := p.Db.QueryRow(`UPDATE api_keys
+		SET revoked_at = COALESCE(revoked_at, now()), revoked_by = COALESCE(revoked_by, $2)
+		WHERE id = $1
+		RETURNING `+apiKeyColumns, id, revokedBy)
+
+	return scanAPIKey(row)
+}
+
+// RecordAPIKeyUsage counts a request of the key to the endpoint.
+func (p *Postgres) RecordAPIKeyUsage(id int, endpoint string, at time.Time) error {
+	_, err := p.Db.Exec(`WITH usage AS (
+			INSERT INTO api_key_usage (api_key_id, endpoint, day, count) VALUES ($1, $2, $3::timestamptz::date, 1)
+			ON CONFLICT (api_key_id, endpoint, day) DO UPDATE SET count = api_key_usage.count + 1
+		)
+		UPDATE api_keys SET request_count = request_count + 1, last_used_at = $3 WHERE id = $1`,
+		id, endpoint, at)
+	return err
+}
+
+// GetAPIKeyUsage returns the request counts of the key by endpoint and day, the latest day first.
+func (p *Postgres) GetAPIKeyUsage(id int) ([]APIKeyUsage, error) {
+	rows, err := p.Db.Query("SELECT endpoint, day, count FROM api_key_usage WHERE api_key_id = $1 ORDER BY day DESC, endpoint", id)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	usage := []APIKeyUsage{}
+	for rows.Next() {
+		var u APIKeyUsage
+		err := rows.Scan(&u.Endpoint, &u.Day, &u.Count)
+		if err != nil {
+			return nil, err
+		}
+		usage = append(usage, u)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return usage, nil
+}
+
+func scanAPIKey(row rowScanner) (*APIKey, error) {
+	var key APIKey
+	err := row.Scan(&key.ID, &key.Name, &key.Prefix, &key.KeyHash, pq.Array(&key.Scopes), &key.RateLimit, &key.ExpiresAt,
+		&key.CreatedBy, &key.CreatedAt, &key.RevokedBy, &key.RevokedAt, &key.RequestCount, &key.LastUsedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &key, nil
+}
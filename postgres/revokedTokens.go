@@ -0,0 +1,27 @@
+package postgres
+
+import "time"
+
+// RevokeToken records the token id as revoked and reports whether it was not revoked
+// before. Revoking a token twice keeps the first record.
+func (p *Postgres) RevokeToken(id string, expiresAt time.Time, revokedBy string) (bool, error) {
+	result, err := p.Db.Exec(`INSERT INTO revoked_tokens (jti, expires_at, revoked_by) VALUES ($1, $2, $3)
+		ON CONFLICT (jti) DO NOTHING`, id, expiresAt, revokedBy)
+	if err != nil {
+		return false, err
+	}
+	inserted, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	// Expired tokens are rejected anyway, so their records are no longer needed.
+	_, err = p.Db.Exec("DELETE FROM revoked_tokens WHERE expires_at < now()")
+	return inserted == 1, err
+}
+
+func (p *Postgres) IsTokenRevoked(id string) (bool, error) {
+	var revoked bool
+	err := p.Db.QueryRow("SELECT EXISTS (SELECT 1 FROM revoked_tokens WHERE jti = $1)", id).Scan(&revoked)
+	return revoked, err
+}
@@ -0,0 +1,150 @@
+package postgres
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+// TaxAssessment is a stored tax calculation with its input, the resolved rules of the
+// rule set it was calculated with and its output. APIKeyID is the API key that stored it.
+type TaxAssessment struct {
+	ID        int             `postgres:"id"`
+	TaxYear   int             `postgres:"tax_year"`
+	RuleSet   string          `postgres:"rule_set"`
+	Source    string          `postgres:"source"`
+	Input     json.RawMessage `postgres:"input"`
+	Rules     json.RawMessage `postgres:"rules"`
+	Output    json.RawMessage `postgres:"output"`
+	Tax       money.Money     `postgres:"tax"`
+	TaxRefund money.Money     `postgres:"tax_refund"`
+	APIKeyID  *int            `postgres:"api_key_id"`
+	CreatedAt *time.Time      `postgres:"created_at"`
+}
+
+// TaxAssessmentFilter narrows ListTaxAssessments, zero values are not filtered on.
+// BeforeID pages through the assessments by ID, which unlike Offset neither skips nor
+// repeats assessments stored in the meantime.
+type TaxAssessmentFilter struct {
+	APIKeyID int
+	TaxYear  int
+	Source   string
+	From     *time.Time
+	To       *time.Time
+	BeforeID int
+	Limit    int
+	Offset   int
+}
+
+const taxAssessmentColumns = "id, tax_year, rule_set, source, input, rules, output, tax, tax_refund, api_key_id, created_at"
+
+const insertTaxAssessmentQuery = `INSERT INTO tax_assessments (tax_year, rule_set, source, input, rules, output, tax, tax_refund, api_key_id)
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING ` + taxAssessmentColumns
+
+func insertTaxAssessmentArgs(assessment TaxAssessment) []any {
+	return []any{assessment.TaxYear, assessment.RuleSet, assessment.Source, []byte(assessment.Input), []byte(assessment.Rules),
+		[]byte(assessment.Output), assessment.Tax, assessment.TaxRefund, assessment.APIKeyID}
+}
+
+func (p *Postgres) CreateTaxAssessment(assessment TaxAssessment) (*TaxAssessment, error) {
+	row := p.Db.QueryRow(insertTaxAssessmentQuery, insertTaxAssessmentArgs(assessment)...)
+
+	return scanTaxAssessment(row)
+}
+
+// CreateTaxAssessments stores a batch of assessments in one transaction, either all of
+// them are stored or none is.
+func (p *Postgres) CreateTaxAssessments(assessments []TaxAssessment) ([]TaxAssessment, error) {
+	return inTx(p.Db, func(tx *sql.Tx) ([]TaxAssessment, error) {
+		stored := []TaxAssessment{}
+		for _, assessment := range assessments {
+			created, err := scanTaxAssessment(tx.QueryRow(insertTaxAssessmentQuery, insertTaxAssessmentArgs(assessment)...))
+			if err != nil {
+				return nil, err
+			}
+			stored = append(stored, *created)
+		}
+		return stored, nil
+	})
+}
+
+// GetTaxAssessment returns sql.ErrNoRows when the assessment does not exist.
+func (p *Postgres) GetTaxAssessment(id int) (*TaxAssessment, error) {
+	row := p.Db.QueryRow("SELECT "+taxAssessmentColumns+" FROM tax_assessments WHERE id = $1", id)
+
+	return scanTaxAssessment(row)
+}
+
+// ListTaxAssessments returns the matching assessments, the latest first.
+func (p *Postgres) ListTaxAssessments(filter TaxAssessmentFilter) ([]TaxAssessment, error) {
+	conditions := []string{}
+	args := []any{}
+	where := func(condition string, arg any) {
+		args = append(args, arg)
+		conditions = append(conditions, fmt.Sprintf(condition, len(args)))
+	}
+
+	if filter.APIKeyID != 0 {
+		where("api_key_id = $%d", filter.APIKeyID)
+	}
+	if filter.TaxYear != 0 {
+		where("tax_year = $%d", filter.TaxYear)
+	}
+	if filter.Source != "" {
+		where("source = $%d", filter.Source)
+	}
+	if filter.From != nil {
+		where("created_at >= $%d", *filter.From)
+	}
+	if filter.To != nil {
+		where("created_at < $%d", *filter.To)
+	}
+	if filter.BeforeID != 0 {
+		where("id < $%d", filter.BeforeID)
+	}
+
+	query := "SELECT " + taxAssessmentColumns + " FROM tax_assessments"
+	if len(conditions) > 0 {
+		query += " WHERE " + strings.Join(conditions, " AND ")
+	}
+	args = append(args, filter.Limit, filter.Offset)
+	query += fmt.Sprintf(" ORDER BY id DESC LIMIT $%d OFFSET $%d", len(args)-1, len(args))
+
+	rows, err := p.Db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	assessments := []TaxAssessment{}
+	for rows.Next() {
+		assessment, err := scanTaxAssessment(rows)
+		if err != nil {
+			return nil, err
+		}
+		assessments = append(assessments, *assessment)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return assessments, nil
+}
+
+func scanTaxAssessment(row rowScanner) (*TaxAssessment, error) {
+	var assessment TaxAssessment
+	var input, rules, output []byte
+	err := row.Scan(&assessment.ID, &assessment.TaxYear, &assessment.RuleSet, &assessment.Source, &input, &rules, &output,
+		&assessment.Tax, &assessment.TaxRefund, &assessment.APIKeyID, &assessment.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	assessment.Input, assessment.Rules, assessment.Output = input, rules, output
+	return &assessment, nil
+}
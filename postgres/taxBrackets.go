@@ -0,0 +1,48 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+type TaxBracket struct {
+	ID        int          `postgres:"id"`
+	TaxYear   int          `postgres:"tax_year"`
+	MinIncome money.Money  `postgres:"min_income"`
+	MaxIncome *money.Money `postgres:"max_income"`
+	Rate      money.Rate   `postgres:"rate"`
+	Active    bool         `postgres:"active"`
+	CreatedAt *time.Time   `postgres:"created_at"`
+	CreatedBy *string      `postgres:"created_by"`
+	UpdatedAt *time.Time   `postgres:"updated_at"`
+	UpdatedBy *string      `postgres:"updated_by"`
+}
+
+// GetTaxBrackets returns the active bracket set of the tax year ordered from the lowest income band.
+// A nil MaxIncome marks the open-ended top bracket.
+func (p *Postgres) GetTaxBrackets(taxYear int) ([]TaxBracket, error) {
+	rows, err := p.Db.Query(`SELECT id, tax_year, min_income, max_income, rate, active, created_by, created_at, updated_by, updated_at
+		FROM tax_brackets WHERE tax_year = $1 AND active = true ORDER BY min_income`, taxYear)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	brackets := []TaxBracket{}
+	for rows.Next() {
+		var bracket TaxBracket
+		err := rows.Scan(&bracket.ID, &bracket.TaxYear, &bracket.MinIncome, &bracket.MaxIncome, &bracket.Rate, &bracket.Active,
+			&bracket.CreatedBy, &bracket.CreatedAt, &bracket.UpdatedBy, &bracket.UpdatedAt)
+		if err != nil {
+			return nil, err
+		}
+		brackets = append(brackets, bracket)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return brackets, nil
+}
@@ -0,0 +1,84 @@
+package postgres
+
+import (
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+const (
+	CONFIG_ACTION_UPDATE   = "update"
+	CONFIG_ACTION_SCHEDULE = "schedule"
+	CONFIG_ACTION_ROLLBACK = "rollback"
+)
+
+// ConfigAudit describes who changes a config, how and why. ApprovedBy is the admin who
+// approved the change proposed by Actor.
+type ConfigAudit struct {
+	Actor      string
+	ApprovedBy string
+	Action     string
+	Reason     string
+}
+
+// TaxConfigHistory is a recorded config change. OldValue is nil when the change
+// scheduled a new value.
+type TaxConfigHistory struct {
+	ID            int          `postgres:"id"`
+	ConfigID      int          `postgres:"config_id"`
+	TaxYear       int          `postgres:"tax_year"`
+	Key           string       `postgres:"key"`
+	EffectiveFrom *time.Time   `postgres:"effective_from"`
+	EffectiveTo   *time.Time   `postgres:"effective_to"`
+	OldValue      *money.Money `postgres:"old_value"`
+	NewValue      money.Money  `postgres:"new_value"`
+	Action        string       `postgres:"action"`
+	Reason        string       `postgres:"reason"`
+	ChangedBy     string       `postgres:"changed_by"`
+	ApprovedBy    *string      `postgres:"approved_by"`
+	ChangedAt     *time.Time   `postgres:"changed_at"`
+}
+
+const taxConfigHistoryColumns = "id, config_id, tax_year, key, effective_from, effective_to, old_value, new_value, action, reason, changed_by, approved_by, changed_at"
+
+// GetTaxConfigHistory returns the changes of the key in the tax year, the latest first.
+func (p *Postgres) GetTaxConfigHistory(taxYear int, key string) ([]TaxConfigHistory, error) {
+	rows, err := p.Db.Query("SELECT "+taxConfigHistoryColumns+" FROM tax_config_history WHERE tax_year = $1 AND key = $2 ORDER BY id DESC", taxYear, key)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	history := []TaxConfigHistory{}
+	for rows.Next() {
+		change, err := scanTaxConfigHistory(rows)
+		if err != nil {
+			return nil, err
+		}
+		history = append(history, *change)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return history, nil
+}
+
+// GetTaxConfigChange returns sql.ErrNoRows when the change does not exist.
+func (p *Postgres) GetTaxConfigChange(id int) (*TaxConfigHistory, error) {
+	row := p.Db.QueryRow("SELECT "+taxConfigHistoryColumns+" FROM tax_config_history WHERE id = $1", id)
+
+	return scanTaxConfigHistory(row)
+}
+
+func scanTaxConfigHistory(row rowScanner) (*TaxConfigHistory, error) {
+	var change TaxConfigHistory
+	err := row.Scan(&change.ID, &change.ConfigID, &change.TaxYear, &change.Key, &change.EffectiveFrom, &change.EffectiveTo,
+		&change.OldValue, &change.NewValue, &change.Action, &change.Reason, &change.ChangedBy, &change.ApprovedBy, &change.ChangedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &change, nil
+}
@@ -0,0 +1,137 @@
+package postgres
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+const (
+	PROPOSAL_STATUS_PENDING  = "pending"
+	PROPOSAL_STATUS_APPROVED = "approved"
+	PROPOSAL_STATUS_REJECTED = "rejected"
+	PROPOSAL_STATUS_EXPIRED  = "expired"
+)
+
+// TaxConfigProposal is a config change proposed by one admin that applies once a different
+// admin approves it. EffectiveFrom is nil when the proposal changes the base value.
+type TaxConfigProposal struct {
+	ID            int         `postgres:"id"`
+	TaxYear       int         `postgres:"tax_year"`
+	Key           string      `postgres:"key"`
+	Value         money.Money `postgres:"value"`
+	EffectiveFrom *time.Time  `postgres:"effective_from"`
+	EffectiveTo   *time.Time  `postgres:"effective_to"`
+	Action        string      `postgres:"action"`
+	Reason        string      `postgres:"reason"`
+	Status        string      `postgres:"status"`
+	ProposedBy    string      `postgres:"proposed_by"`
+	ProposedAt    *time.Time  `postgres:"proposed_at"`
+	ExpiresAt     time.Time   `postgres:"expires_at"`
+	ReviewedBy    *string     `postgres:"reviewed_by"`
+	ReviewedAt    *time.Time  `postgres:"reviewed_at"`
+	ReviewComment string      `postgres:"review_comment"`
+}
+
+const taxConfigProposalColumns = "id, tax_year, key, value, effective_from, effective_to, action, reason, status, proposed_by, proposed_at, expires_at, reviewed_by, reviewed_at, review_comment"
+
+func (p *Postgres) CreateTaxConfigProposal(proposal TaxConfigProposal) (*TaxConfigProposal, error) {
+	row := p.Db.QueryRow(`INSERT INTO tax_config_proposals
+		(tax_year, key, value, effective_from, effective_to, action, reason, proposed_by, expires_at)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING `+taxConfigProposalColumns,
+		proposal.TaxYear, proposal.Key, proposal.Value, nullableDateParam(proposal.EffectiveFrom), nullableDateParam(proposal.EffectiveTo),
+		proposal.Action, proposal.Reason, proposal.ProposedBy, proposal.ExpiresAt)
+
+	return scanTaxConfigProposal(row)
+}
+
+// GetTaxConfigProposal returns sql.ErrNoRows when the proposal does not exist.
+func (p *Postgres) GetTaxConfigProposal(id int) (*TaxConfigProposal, error) {
+	row := p.Db.QueryRow("SELECT "+taxConfigProposalColumns+" FROM tax_config_proposals WHERE id = $1", id)
+
+	return scanTaxConfigProposal(row)
+}
+
+// ListTaxConfigProposals returns the proposals with the status, or every proposal when
+// status is empty, the latest first.
+func (p *Postgres) ListTaxConfigProposals(status string) ([]TaxConfigProposal, error) {
+	rows, err := p.Db.Query("SELECT "+taxConfigProposalColumns+" FROM tax_config_proposals WHERE $1 = '' OR status = $1 ORDER BY id DESC", status)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	proposals := []TaxConfigProposal{}
+	for rows.Next() {
+		proposal, err := scanTaxConfigProposal(rows)
+		if err != nil {
+			return nil, err
+		}
+		proposals = append(proposals, *proposal)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	return proposals, nil
+}
+
+// ExpireTaxConfigProposals marks the pending proposals expired at the time as expired.
+func (p *Postgres) ExpireTaxConfigProposals(at time.Time) error {
+	_, err := p.Db.Exec("UPDATE tax_config_proposals SET status = $1 WHERE status = $2 AND expires_at <= $3",
+		PROPOSAL_STATUS_EXPIRED, PROPOSAL_STATUS_PENDING, at)
+	return err
+}
+
+// ApproveTaxConfigProposal applies the proposed change in the same transaction that marks
+// the proposal approved. sql.ErrNoRows is returned when the proposal is no longer pending
+// or has expired at the time.
+func (p *Postgres) ApproveTaxConfigProposal(id int, reviewer string, at time.Time) (*TaxConfig, error) {
+	return inTx(p.Db, func(tx *sql.Tx) (*TaxConfig, error) {
+		proposal, err := scanTaxConfigProposal(tx.QueryRow(`UPDATE tax_config_proposals
+			SET status = $2, reviewed_by = $3, reviewed_at = now()
+			WHERE id = $1 AND status = $4 AND expires_at > $5
+			RETURNING `+taxConfigProposalColumns,
+			id, PROPOSAL_STATUS_APPROVED, reviewer, PROPOSAL_STATUS_PENDING, at))
+		if err != nil {
+			return nil, err
+		}
+
+		audit := ConfigAudit{
+			Actor:      proposal.ProposedBy,
+			ApprovedBy: reviewer,
+			Action:     proposal.Action,
+			Reason:     proposal.Reason,
+		}
+		if proposal.EffectiveFrom != nil {
+			return scheduleTaxConfig(tx, proposal.TaxYear, proposal.Key, proposal.Value, *proposal.EffectiveFrom, proposal.EffectiveTo, audit)
+		}
+		return setTaxConfig(tx, proposal.TaxYear, proposal.Key, proposal.Value, audit)
+	})
+}
+
+// RejectTaxConfigProposal returns sql.ErrNoRows when the proposal is no longer pending.
+func (p *Postgres) RejectTaxConfigProposal(id int, reviewer string, comment string) (*TaxConfigProposal, error) {
+	row := p.Db.QueryRow(`UPDATE tax_config_proposals
+		SET status = $2, reviewed_by = $3, reviewed_at = now(), review_comment = $4
+		WHERE id = $1 AND status = $5
+		RETURNING `+taxConfigProposalColumns,
+		id, PROPOSAL_STATUS_REJECTED, reviewer, comment, PROPOSAL_STATUS_PENDING)
+
+	return scanTaxConfigProposal(row)
+}
+
+func scanTaxConfigProposal(row rowScanner) (*TaxConfigProposal, error) {
+	var proposal TaxConfigProposal
+	err := row.Scan(&proposal.ID, &proposal.TaxYear, &proposal.Key, &proposal.Value, &proposal.EffectiveFrom, &proposal.EffectiveTo,
+		&proposal.Action, &proposal.Reason, &proposal.Status, &proposal.ProposedBy, &proposal.ProposedAt, &proposal.ExpiresAt,
+		&proposal.ReviewedBy, &proposal.ReviewedAt, &proposal.ReviewComment)
+	if err != nil {
+		return nil, err
+	}
+
+	return &proposal, nil
+}
@@ -1,68 +1,172 @@
 package postgres
 
 import (
+	"database/sql"
+	"errors"
 	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
 )
 
 type TaxConfig struct {
-	ID        int        `postgres:"id"`
-	Key       string     `postgres:"key"`
-	Name      string     `postgres:"name"`
-	Value     float64    `postgres:"value"`
-	CreatedAt *time.Time `postgres:"created_at"`
-	CreatedBy *string    `postgres:"created_by"`
-	UpdatedAt *time.Time `postgres:"updated_at"`
-	UpdatedBy *string    `postgres:"updated_by"`
+	ID        int         `postgres:"id"`
+	TaxYear   int         `postgres:"tax_year"`
+	Key       string      `postgres:"key"`
+	Name      string      `postgres:"name"`
+	Value     money.Money `postgres:"value"`
+	CreatedAt *time.Time  `postgres:"created_at"`
+	CreatedBy *string     `postgres:"created_by"`
+	UpdatedAt *time.Time  `postgres:"updated_at"`
+	UpdatedBy *string     `postgres:"updated_by"`
+	// EffectiveFrom is nil for the base value of the tax year. Scheduled values apply
+	// from EffectiveFrom until EffectiveTo, inclusive, or open-ended when it is nil.
+	EffectiveFrom *time.Time `postgres:"effective_from"`
+	EffectiveTo   *time.Time `postgres:"effective_to"`
+}
+
+const taxConfigColumns = "id, tax_year, key, name, value, created_by, created_at, updated_by, updated_at, effective_from, effective_to"
+
+// GetTaxConfig returns the value in force on the date: the latest scheduled value covering
+// the date, or the base value when none does.
+func (p *Postgres) GetTaxConfig(taxYear int, key string, at time.Time) (*TaxConfig, error) {
+	row := p.Db.QueryRow("SELECT "+taxConfigColumns+` FROM tax_configs
+		WHERE tax_year = $1 AND key = $2
+			AND (effective_from IS NULL OR effective_from <= $3::date)
+			AND (effective_to IS NULL OR effective_to >= $3::date)
+		ORDER BY effective_from DESC NULLS LAST LIMIT 1`, taxYear, key, dateParam(at))
+
+	return scanTaxConfig(row)
 }
 
-func (p *Postgres) GetTaxConfig(key string) (*TaxConfig, error) {
-	var taxConfig *TaxConfig
+// SetTaxConfig updates the base value of the tax year and records the change in the config history.
+func (p *Postgres) SetTaxConfig(taxYear int, key string, value money.Money, audit ConfigAudit) (*TaxConfig, error) {
+	return inTx(p.Db, func(tx *sql.Tx) (*TaxConfig, error) {
+		return setTaxConfig(tx, taxYear, key, value, audit)
+	})
+}
 
-	row := p.Db.QueryRow("SELECT * FROM tax_configs WHERE key = $1", key)
+// ScheduleTaxConfig stores a value in force from the date until to, or open-ended when to
+// is nil, replacing a value scheduled from the same date, and records the change in the
+// config history. The key must have a base value, sql.ErrNoRows is returned otherwise.
+func (p *Postgres) ScheduleTaxConfig(taxYear int, key string, value money.Money, from time.Time, to *time.Time, audit ConfigAudit) (*TaxConfig, error) {
+	return inTx(p.Db, func(tx *sql.Tx) (*TaxConfig, error) {
+		return scheduleTaxConfig(tx, taxYear, key, value, from, to, audit)
+	})
+}
 
-	err := row.Scan(taxConfig)
+func setTaxConfig(tx *sql.Tx, taxYear int, key string, value money.Money, audit ConfigAudit) (*TaxConfig, error) {
+	return changeTaxConfig(tx, audit,
+		"SELECT value FROM tax_configs WHERE tax_year = $1 AND key = $2 AND effective_from IS NULL FOR UPDATE",
+		[]any{taxYear, key},
+		"UPDATE tax_configs SET value = $3, updated_at = now(), updated_by = $4 WHERE tax_year = $1 AND key = $2 AND effective_from IS NULL RETURNING "+taxConfigColumns,
+		[]any{taxYear, key, value, audit.Actor})
+}
+
+func scheduleTaxConfig(tx *sql.Tx, taxYear int, key string, value money.Money, from time.Time, to *time.Time, audit ConfigAudit) (*TaxConfig, error) {
+	return changeTaxConfig(tx, audit,
+		"SELECT value FROM tax_configs WHERE tax_year = $1 AND key = $2 AND effective_from = $3::date FOR UPDATE",
+		[]any{taxYear, key, dateParam(from)},
+		`INSERT INTO tax_configs (tax_year, name, key, value, effective_from, effective_to, created_by)
+		SELECT tax_year, name, key, $3, $4::date, $5::date, $6 FROM tax_configs
+		WHERE tax_year = $1 AND key = $2 AND effective_from IS NULL
+		ON CONFLICT (tax_year, key, COALESCE(effective_from, '0001-01-01'::date))
+		DO UPDATE SET value = EXCLUDED.value, effective_to = EXCLUDED.effective_to, updated_at = now(), updated_by = $6
+		RETURNING `+taxConfigColumns,
+		[]any{taxYear, key, value, dateParam(from), nullableDateParam(to), audit.Actor})
+}
+
+// changeTaxConfig applies the change together with its history record. The lock query
+// selects the value before the change, which is NULL for a newly scheduled value.
+func changeTaxConfig(tx *sql.Tx, audit ConfigAudit, lockQuery string, lockArgs []any, changeQuery string, changeArgs []any) (*TaxConfig, error) {
+	var oldValue *money.Money
+	err := tx.QueryRow(lockQuery, lockArgs...).Scan(&oldValue)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, err
+	}
+
+	config, err := scanTaxConfig(tx.QueryRow(changeQuery, changeArgs...))
 	if err != nil {
 		return nil, err
 	}
 
-	return taxConfig, nil
+	_, err = tx.Exec(`INSERT INTO tax_config_history
+		(config_id, tax_year, key, effective_from, effective_to, old_value, new_value, action, reason, changed_by, approved_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, NULLIF($11, ''))`,
+		config.ID, config.TaxYear, config.Key, nullableDateParam(config.EffectiveFrom), nullableDateParam(config.EffectiveTo), oldValue, config.Value,
+		audit.Action, audit.Reason, audit.Actor, audit.ApprovedBy)
+	if err != nil {
+		return nil, err
+	}
+
+	return config, nil
 }
 
-func (p *Postgres) SetTaxConfig(key string, value float64) (*TaxConfig, error) {
-	row := p.Db.QueryRow("UPDATE tax_configs SET value = $1 WHERE key = $2 returning *", value, key)
-	var config TaxConfig
-	var sCreateAt *string
-	var sUpdatedAt *string
-	err := row.Scan(&config.ID, &config.Key, &config.Name, &config.Value, &config.CreatedBy, &sCreateAt, &config.UpdatedBy, &sUpdatedAt)
+// dateParam passes the calendar date of t as YYYY-MM-DD. A time.Time would be sent as a
+// timestamp and cast to a date in the session time zone, which can be the previous day.
+func dateParam(t time.Time) string {
+	return t.Format("2006-01-02")
+}
+
+func nullableDateParam(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return dateParam(*t)
+}
+
+// inTx runs fn in a transaction, committed when fn succeeds and rolled back otherwise.
+func inTx[T any](db *sql.DB, fn func(tx *sql.Tx) (T, error)) (T, error) {
+	var zero T
+	tx, err := db.Begin()
 	if err != nil {
-		return nil, err
+		return zero, err
 	}
+	defer tx.Rollback()
 
-	createAt, err := p.dateStrToTime(sCreateAt)
+	result, err := fn(tx)
 	if err != nil {
-		return nil, err
+		return zero, err
 	}
 
-	updatedAt, err := p.dateStrToTime(sUpdatedAt)
+	return result, tx.Commit()
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanTaxConfig(row rowScanner) (*TaxConfig, error) {
+	var config TaxConfig
+	err := row.Scan(&config.ID, &config.TaxYear, &config.Key, &config.Name, &config.Value,
+		&config.CreatedBy, &config.CreatedAt, &config.UpdatedBy, &config.UpdatedAt, &config.EffectiveFrom, &config.EffectiveTo)
 	if err != nil {
 		return nil, err
 	}
 
-	config.CreatedAt = createAt
-	config.UpdatedAt = updatedAt
-
 	return &config, nil
 }
 
-func (p *Postgres) dateStrToTime(dateStr *string) (*time.Time, error) {
+// ListTaxConfigs returns every config of the tax year ordered by key, the base value
+// first followed by the scheduled values.
+func (p *Postgres) ListTaxConfigs(taxYear int) ([]TaxConfig, error) {
+	rows, err := p.Db.Query("SELECT "+taxConfigColumns+" FROM tax_configs WHERE tax_year = $1 ORDER BY key, effective_from NULLS FIRST", taxYear)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
 
-	if dateStr != nil {
-		date, err := time.Parse(time.RFC3339, *dateStr)
+	configs := []TaxConfig{}
+	for rows.Next() {
+		config, err := scanTaxConfig(rows)
 		if err != nil {
 			return nil, err
 		}
+		configs = append(configs, *config)
+	}
 
-		return &date, nil
+	if err := rows.Err(); err != nil {
+		return nil, err
 	}
-	return nil, nil
+
+	return configs, nil
 }
@@ -0,0 +1,23 @@
+package postgres
+
+import "time"
+
+type TaxRuleSet struct {
+	TaxYear   int        `postgres:"tax_year"`
+	Name      string     `postgres:"name"`
+	CreatedBy *string    `postgres:"created_by"`
+	CreatedAt *time.Time `postgres:"created_at"`
+}
+
+// GetTaxRuleSet returns sql.ErrNoRows when no rule set exists for the tax year.
+func (p *Postgres) GetTaxRuleSet(taxYear int) (*TaxRuleSet, error) {
+	row := p.Db.QueryRow("SELECT tax_year, name, created_by, created_at FROM tax_rule_sets WHERE tax_year = $1", taxYear)
+
+	var ruleSet TaxRuleSet
+	err := row.Scan(&ruleSet.TaxYear, &ruleSet.Name, &ruleSet.CreatedBy, &ruleSet.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+
+	return &ruleSet, nil
+}
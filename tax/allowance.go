@@ -0,0 +1,233 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+type (
+	// AllowanceRule describes how claims of one allowance type are turned into a deduction.
+	AllowanceRule struct {
+		Type string
+		// Group names an AllowanceGroup whose combined cap also limits this allowance.
+		Group string
+		// Caps maps the tax_configs keys read by the rule to their statutory defaults.
+		Caps map[string]money.Money
+		// Fixed marks allowances granted per claimed person rather than by amount.
+		Fixed bool
+		// Validate checks a single claim before calculation, it may be nil.
+		Validate func(claim Allowance) error
+		Allowed  AllowanceFunc
+	}
+
+	// AllowanceFunc returns the deductible amount of all claims of a type before the group
+	// cap, along with the limit that reduced it below the claimed amount, if any.
+	AllowanceFunc func(claims []Allowance, ctx AllowanceContext) (allowed money.Money, limit string)
+
+	// AllowanceGroup caps the combined deduction of several allowance types,
+	// e.g. life and health insurance together.
+	AllowanceGroup struct {
+		Name       string
+		CapKey     string
+		DefaultCap money.Money
+	}
+
+	AllowanceContext struct {
+		TaxYear int
+		// Income is the assessable income before expenses.
+		Income money.Money
+		// NetIncome is the income after expenses, the personal deduction and
+		// every allowance deducted before the current one.
+		NetIncome  money.Money
+		calculator TaxCalculator
+	}
+
+	// AllowanceDeduction is the outcome of one allowance type in a calculation. Claimed is
+	// the granted amount for fixed allowances.
+	AllowanceDeduction struct {
+		Type    string
+		Claimed money.Money
+		Allowed money.Money
+		// Limit names the cap that bit, e.g. a tax_configs key, or is empty.
+		Limit string
+	}
+)
+
+var (
+	allowanceRules  = []AllowanceRule{}
+	allowanceGroups = map[string]AllowanceGroup{}
+)
+
+const (
+	LIMIT_INCOME_SHARE = "INCOME_SHARE"
+	LIMIT_MAX_PERSONS  = "MAX_PERSONS"
+)
+
+var ErrInvalidAllowanceType = errors.New("invalid allowance type")
+
+// RegisterAllowance adds an allowance type to the registry. Allowances are
+// deducted in registration order.
+func RegisterAllowance(rule AllowanceRule) {
+	if _, ok := LookupAllowance(rule.Type); ok {
+		panic(fmt.Sprintf("allowance %q is already registered", rule.Type))
+	}
+	if rule.Group != "" {
+		if _, ok := allowanceGroups[rule.Group]; !ok {
+			panic(fmt.Sprintf("allowance group %q is not registered", rule.Group))
+		}
+	}
+	allowanceRules = append(allowanceRules, rule)
+}
+
+func RegisterAllowanceGroup(group AllowanceGroup) {
+	if _, ok := allowanceGroups[group.Name]; ok {
+		panic(fmt.Sprintf("allowance group %q is already registered", group.Name))
+	}
+	allowanceGroups[group.Name] = group
+}
+
+func LookupAllowance(allowanceType string) (AllowanceRule, bool) {
+	for _, rule := range allowanceRules {
+		if rule.Type == allowanceType {
+			return rule, true
+		}
+	}
+	return AllowanceRule{}, false
+}
+
+// AllowanceCapDefaults returns every config key used by the registered allowances
+// and groups with its statutory default.
+func AllowanceCapDefaults() map[string]money.Money {
+	defaults := map[string]money.Money{}
+	for _, rule := range allowanceRules {
+		for key, value := range rule.Caps {
+			defaults[key] = value
+		}
+	}
+	for _, group := range allowanceGroups {
+		defaults[group.CapKey] = group.DefaultCap
+	}
+	return defaults
+}
+
+// Cap returns the resolved value of a config key declared by an allowance.
+func (c AllowanceContext) Cap(key string) money.Money {
+	return c.calculator.cap(key)
+}
+
+func validateAllowance(allowances []Allowance) error {
+	for _, al := range allowances {
+		rule, ok := LookupAllowance(al.AllowanceType)
+		if !ok {
+			return ErrInvalidAllowanceType
+		}
+		if al.Amount < 0 {
+			return fmt.Errorf("%s amount must not be negative", al.AllowanceType)
+		}
+		if rule.Validate != nil {
+			if err := rule.Validate(al); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func sumAllowances(claims []Allowance) money.Money {
+	sum := money.Money(0)
+	for _, claim := range claims {
+		sum += claim.Amount
+	}
+	return sum
+}
+
+// limitTo caps the claimed amount, naming the limit when it bites.
+func limitTo(claimed money.Money, max money.Money, limit string) (money.Money, string) {
+	if claimed > max {
+		return max, limit
+	}
+	return claimed, ""
+}
+
+// capped is the AllowanceFunc of allowances that are deductible up to a single cap.
+func capped(capKey string) AllowanceFunc {
+	return func(claims []Allowance, ctx AllowanceContext) (money.Money, string) {
+		return limitTo(sumAllowances(claims), ctx.Cap(capKey), capKey)
+	}
+}
+
+// cappedByIncome is like capped but also limits the deduction to a share of income,
+// reported as the INCOME_SHARE limit.
+func cappedByIncome(capKey string, rate money.Rate) AllowanceFunc {
+	return func(claims []Allowance, ctx AllowanceContext) (money.Money, string) {
+		share := money.Max(ctx.Income, 0).MulRate(rate)
+		if share < ctx.Cap(capKey) {
+			return limitTo(sumAllowances(claims), share, LIMIT_INCOME_SHARE)
+		}
+		return limitTo(sumAllowances(claims), ctx.Cap(capKey), capKey)
+	}
+}
+
+// perPerson is the AllowanceFunc of allowances granted as a fixed amount per claim.
+func perPerson(capKey string, maxPersons int) AllowanceFunc {
+	return func(claims []Allowance, ctx AllowanceContext) (money.Money, string) {
+		persons := len(claims)
+		limit := ""
+		if maxPersons > 0 && persons > maxPersons {
+			persons = maxPersons
+			limit = LIMIT_MAX_PERSONS
+		}
+		return ctx.Cap(capKey).Mul(int64(persons)), limit
+	}
+}
+
+// allowanceDeductions applies every registered allowance to the claims in registration order.
+func (t TaxCalculator) allowanceDeductions(info TaxInformation) []AllowanceDeduction {
+	return t.deductAllowances(info, t.netIncome(info))
+}
+
+// deductAllowances is allowanceDeductions for a return whose income after expenses is known.
+func (t TaxCalculator) deductAllowances(info TaxInformation, netIncome money.Money) []AllowanceDeduction {
+	ctx := AllowanceContext{
+		TaxYear:    t.rules.TaxYear,
+		Income:     info.grossIncome(),
+		NetIncome:  netIncome - t.rules.PersonalDeduction,
+		calculator: t,
+	}
+
+	groupUsed := map[string]money.Money{}
+	deductions := []AllowanceDeduction{}
+	for _, rule := range allowanceRules {
+		claims := info.allowancesByType(rule.Type)
+		if len(claims) == 0 {
+			continue
+		}
+
+		allowed, limit := rule.Allowed(claims, ctx)
+		allowed = money.Max(allowed, 0)
+		if group, ok := allowanceGroups[rule.Group]; ok {
+			headroom := money.Max(ctx.Cap(group.CapKey)-groupUsed[group.Name], 0)
+			if allowed > headroom {
+				allowed, limit = headroom, group.CapKey
+			}
+			groupUsed[group.Name] += allowed
+		}
+		ctx.NetIncome -= allowed
+
+		claimed := sumAllowances(claims)
+		if rule.Fixed {
+			// Fixed allowances are granted per person, the amounts sent with them are not claims.
+			claimed = allowed
+		}
+		deductions = append(deductions, AllowanceDeduction{
+			Type:    rule.Type,
+			Claimed: claimed,
+			Allowed: allowed,
+			Limit:   limit,
+		})
+	}
+
+	return deductions
+}
@@ -0,0 +1,157 @@
+package tax
+
+import (
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+func newDefaultTaxCalculator() TaxCalculator {
+	return NewTaxCalculator(TaxRules{
+		TaxYear:           DEFAULT_TAX_YEAR,
+		PersonalDeduction: DEFAULT_PERSONAL_DEDUCTION,
+		Caps:              CapDefaults(),
+		Brackets:          defaultTaxBrackets,
+	})
+}
+
+func allowedByType(deductions []AllowanceDeduction) map[string]money.Money {
+	allowed := map[string]money.Money{}
+	for _, deduction := range deductions {
+		allowed[deduction.Type] = deduction.Allowed
+	}
+	return allowed
+}
+
+func TestAllowanceDeductions(t *testing.T) {
+	testCases := []struct {
+		name       string
+		income     money.Money
+		allowances []Allowance
+		expected   map[string]money.Money
+	}{
+		{
+			name:   "given second child born from 2018 should deduct the higher child allowance",
+			income: money.FromBaht(1_000_000),
+			allowances: []Allowance{
+				{AllowanceType: "child", BirthYear: 2558},
+				{AllowanceType: "child", BirthYear: 2019},
+			},
+			expected: map[string]money.Money{"child": money.FromBaht(90_000)},
+		},
+		{
+			name:   "given children out of birth order should deduct the same child allowance",
+			income: money.FromBaht(1_000_000),
+			allowances: []Allowance{
+				{AllowanceType: "child", BirthYear: 2020},
+				{AllowanceType: "child", BirthYear: 2558},
+				{AllowanceType: "child", BirthYear: 2019},
+			},
+			expected: map[string]money.Money{"child": money.FromBaht(150_000)},
+		},
+		{
+			name:   "given five parents should deduct four parents",
+			income: money.FromBaht(1_000_000),
+			allowances: []Allowance{
+				{AllowanceType: "parent", Age: 61},
+				{AllowanceType: "parent", Age: 62},
+				{AllowanceType: "parent", Age: 63},
+				{AllowanceType: "parent", Age: 64},
+				{AllowanceType: "parent", Age: 65},
+			},
+			expected: map[string]money.Money{"parent": money.FromBaht(120_000)},
+		},
+		{
+			name:   "given life and health insurance should cap combined insurance",
+			income: money.FromBaht(1_000_000),
+			allowances: []Allowance{
+				{AllowanceType: "health-insurance", Amount: money.FromBaht(30_000)},
+				{AllowanceType: "life-insurance", Amount: money.FromBaht(90_000)},
+			},
+			expected: map[string]money.Money{
+				"health-insurance": money.FromBaht(25_000),
+				"life-insurance":   money.FromBaht(75_000),
+			},
+		},
+		{
+			name:   "given retirement savings should cap by income and combined retirement cap",
+			income: money.FromBaht(2_000_000),
+			allowances: []Allowance{
+				{AllowanceType: "ssf", Amount: money.FromBaht(250_000)},
+				{AllowanceType: "rmf", Amount: money.FromBaht(400_000)},
+			},
+			expected: map[string]money.Money{
+				"ssf": money.FromBaht(200_000),
+				"rmf": money.FromBaht(300_000),
+			},
+		},
+		{
+			name:   "given pvd above 15% of income should cap by income",
+			income: money.FromBaht(400_000),
+			allowances: []Allowance{
+				{AllowanceType: "pvd", Amount: money.FromBaht(100_000)},
+			},
+			expected: map[string]money.Money{"pvd": money.FromBaht(60_000)},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			deductions := newDefaultTaxCalculator().allowanceDeductions(TaxInformation{
+				TotalIncome: tc.income,
+				Allowances:  tc.allowances,
+			})
+
+			got := allowedByType(deductions)
+			for allowanceType, want := range tc.expected {
+				if got[allowanceType] != want {
+					t.Errorf("invalid %s deduction: got %v want %v", allowanceType, got[allowanceType], want)
+				}
+			}
+		})
+	}
+}
+
+func TestFixedAllowanceClaimed(t *testing.T) {
+	t.Run("given fixed allowance with an amount should report the granted amount as claimed", func(t *testing.T) {
+		deductions := newDefaultTaxCalculator().allowanceDeductions(TaxInformation{
+			TotalIncome: money.FromBaht(1_000_000),
+			Allowances: []Allowance{
+				{AllowanceType: "spouse", Amount: money.FromBaht(500_000)},
+				{AllowanceType: "child", Amount: money.FromBaht(1), BirthYear: 2558},
+			},
+		})
+
+		for _, deduction := range deductions {
+			if deduction.Claimed != deduction.Allowed {
+				t.Errorf("invalid %s claimed: got %v want %v", deduction.Type, deduction.Claimed, deduction.Allowed)
+			}
+		}
+		if len(deductions) != 2 {
+			t.Errorf("invalid deductions: got %v want %v", len(deductions), 2)
+		}
+	})
+}
+
+func TestValidateAllowance(t *testing.T) {
+	t.Run("given parent under 60 should return error", func(t *testing.T) {
+		err := validateAllowance([]Allowance{{AllowanceType: "parent", Age: 59}})
+		if err == nil {
+			t.Errorf("expected error for parent under 60")
+		}
+	})
+
+	t.Run("given negative amount should return error", func(t *testing.T) {
+		err := validateAllowance([]Allowance{{AllowanceType: "donation", Amount: money.FromBaht(-1)}})
+		if err == nil {
+			t.Errorf("expected error for negative amount")
+		}
+	})
+
+	t.Run("given unknown allowance type should return error", func(t *testing.T) {
+		err := validateAllowance([]Allowance{{AllowanceType: "investment"}})
+		if err != ErrInvalidAllowanceType {
+			t.Errorf("invalid error: got %v want %v", err, ErrInvalidAllowanceType)
+		}
+	})
+}
@@ -0,0 +1,154 @@
+package tax
+
+import (
+	"cmp"
+	"errors"
+	"slices"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+const (
+	// CHILD_BORN_FROM_YEAR is 2018 in the Buddhist era; second and later children
+	// born from this year on get the higher child allowance.
+	CHILD_BORN_FROM_YEAR = 2561
+	PARENT_MIN_AGE       = 60
+	MAX_PARENTS          = 4
+)
+
+func init() {
+	RegisterAllowanceGroup(AllowanceGroup{
+		Name:       "insurance",
+		CapKey:     "MAX_INSURANCE_DEDUCTION",
+		DefaultCap: money.FromBaht(100_000),
+	})
+	RegisterAllowanceGroup(AllowanceGroup{
+		Name:       "retirement",
+		CapKey:     "MAX_RETIREMENT_SAVINGS_DEDUCTION",
+		DefaultCap: money.FromBaht(500_000),
+	})
+
+	RegisterAllowance(AllowanceRule{
+		Type:  "spouse",
+		Fixed: true,
+		Caps:  map[string]money.Money{"SPOUSE_DEDUCTION": money.FromBaht(60_000)},
+		Allowed: func(claims []Allowance, ctx AllowanceContext) (money.Money, string) {
+			return ctx.Cap("SPOUSE_DEDUCTION"), ""
+		},
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:  "child",
+		Fixed: true,
+		Caps: map[string]money.Money{
+			"CHILD_DEDUCTION":           money.FromBaht(30_000),
+			"CHILD_BORN_2018_DEDUCTION": money.FromBaht(60_000),
+		},
+		Allowed: childAllowance,
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:  "parent",
+		Fixed: true,
+		Caps:  map[string]money.Money{"PARENT_DEDUCTION": money.FromBaht(30_000)},
+		Validate: func(claim Allowance) error {
+			if claim.Age < PARENT_MIN_AGE {
+				return errors.New("parent must be at least 60 years old")
+			}
+			return nil
+		},
+		Allowed: perPerson("PARENT_DEDUCTION", MAX_PARENTS),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "disabled-dependant",
+		Fixed:   true,
+		Caps:    map[string]money.Money{"DISABLED_DEPENDANT_DEDUCTION": money.FromBaht(60_000)},
+		Allowed: perPerson("DISABLED_DEPENDANT_DEDUCTION", 0),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "health-insurance",
+		Group:   "insurance",
+		Caps:    map[string]money.Money{"MAX_HEALTH_INSURANCE_DEDUCTION": money.FromBaht(25_000)},
+		Allowed: capped("MAX_HEALTH_INSURANCE_DEDUCTION"),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "life-insurance",
+		Group:   "insurance",
+		Caps:    map[string]money.Money{"MAX_LIFE_INSURANCE_DEDUCTION": money.FromBaht(100_000)},
+		Allowed: capped("MAX_LIFE_INSURANCE_DEDUCTION"),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "parents-health-insurance",
+		Caps:    map[string]money.Money{"MAX_PARENTS_HEALTH_INSURANCE_DEDUCTION": money.FromBaht(15_000)},
+		Allowed: capped("MAX_PARENTS_HEALTH_INSURANCE_DEDUCTION"),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "social-security",
+		Caps:    map[string]money.Money{"MAX_SOCIAL_SECURITY_DEDUCTION": money.FromBaht(9_000)},
+		Allowed: capped("MAX_SOCIAL_SECURITY_DEDUCTION"),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "home-loan-interest",
+		Caps:    map[string]money.Money{"MAX_HOME_LOAN_INTEREST_DEDUCTION": money.FromBaht(100_000)},
+		Allowed: capped("MAX_HOME_LOAN_INTEREST_DEDUCTION"),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "ssf",
+		Group:   "retirement",
+		Caps:    map[string]money.Money{"MAX_SSF_DEDUCTION": money.FromBaht(200_000)},
+		Allowed: cappedByIncome("MAX_SSF_DEDUCTION", money.MustParseRate("0.30")),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "rmf",
+		Group:   "retirement",
+		Caps:    map[string]money.Money{"MAX_RMF_DEDUCTION": money.FromBaht(500_000)},
+		Allowed: cappedByIncome("MAX_RMF_DEDUCTION", money.MustParseRate("0.30")),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "pvd",
+		Group:   "retirement",
+		Caps:    map[string]money.Money{"MAX_PVD_DEDUCTION": money.FromBaht(500_000)},
+		Allowed: cappedByIncome("MAX_PVD_DEDUCTION", money.MustParseRate("0.15")),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "gpf",
+		Group:   "retirement",
+		Caps:    map[string]money.Money{"MAX_GPF_DEDUCTION": money.FromBaht(500_000)},
+		Allowed: capped("MAX_GPF_DEDUCTION"),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "thai-esg",
+		Caps:    map[string]money.Money{"MAX_THAI_ESG_DEDUCTION": money.FromBaht(300_000)},
+		Allowed: cappedByIncome("MAX_THAI_ESG_DEDUCTION", money.MustParseRate("0.30")),
+	})
+	RegisterAllowance(AllowanceRule{
+		Type:    "k-receipt",
+		Caps:    map[string]money.Money{"MAX_K_RECEIPT_DEDUCTION": DEFAULT_MAX_K_RECEIPT},
+		Allowed: capped("MAX_K_RECEIPT_DEDUCTION"),
+	})
+	// Donations are deducted last as the statutory donation limit depends on the other allowances.
+	RegisterAllowance(AllowanceRule{
+		Type:     "donation",
+		Caps:     map[string]money.Money{"DONATION_INCOME_PERCENT": DEFAULT_DONATION_INCOME_PERCENT},
+		Validate: validateDonation,
+		Allowed:  donationAllowance,
+	})
+}
+
+// childAllowance grants the higher amount to the second and later children born
+// from CHILD_BORN_FROM_YEAR. Claims are put in birth order first, so the order they are
+// sent in does not matter.
+func childAllowance(claims []Allowance, ctx AllowanceContext) (money.Money, string) {
+	children := slices.Clone(claims)
+	slices.SortStableFunc(children, func(a, b Allowance) int {
+		return cmp.Compare(a.birthYear(), b.birthYear())
+	})
+
+	total := money.Money(0)
+	for i, claim := range children {
+		if i > 0 && claim.birthYear() >= CHILD_BORN_FROM_YEAR {
+			total += ctx.Cap("CHILD_BORN_2018_DEDUCTION")
+		} else {
+			total += ctx.Cap("CHILD_DEDUCTION")
+		}
+	}
+	return total, ""
+}
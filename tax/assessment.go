@@ -0,0 +1,232 @@
+package tax
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	ASSESSMENT_SOURCE_API = "api"
+	ASSESSMENT_SOURCE_CSV = "csv"
+
+	DEFAULT_ASSESSMENT_LIMIT = 50
+	MAX_ASSESSMENT_LIMIT     = 100
+
+	// API_KEY_ID_CONTEXT_KEY holds the int ID of the API key that authenticated the
+	// request, set by the middleware guarding the tax routes.
+	API_KEY_ID_CONTEXT_KEY = "apiKeyID"
+)
+
+var ErrMissingAPIKey = errors.New("missing API key")
+
+type TaxAssessmentResponse struct {
+	ID        int             `json:"id"`
+	TaxYear   int             `json:"taxYear"`
+	RuleSet   string          `json:"ruleSet"`
+	Source    string          `json:"source"`
+	Input     json.RawMessage `json:"input"`
+	Rules     json.RawMessage `json:"rules"`
+	Output    json.RawMessage `json:"output"`
+	CreatedAt *time.Time      `json:"createdAt"`
+}
+
+func newTaxAssessmentResponse(assessment postgres.TaxAssessment) TaxAssessmentResponse {
+	return TaxAssessmentResponse{
+		ID:        assessment.ID,
+		TaxYear:   assessment.TaxYear,
+		RuleSet:   assessment.RuleSet,
+		Source:    assessment.Source,
+		Input:     assessment.Input,
+		Rules:     assessment.Rules,
+		Output:    assessment.Output,
+		CreatedAt: assessment.CreatedAt,
+	}
+}
+
+// requestAPIKeyID returns the ID of the API key that authenticated the request, nil
+// without one.
+func requestAPIKeyID(c echo.Context) *int {
+	id, ok := c.Get(API_KEY_ID_CONTEXT_KEY).(int)
+	if !ok {
+		return nil
+	}
+	return &id
+}
+
+// newTaxAssessment records the input, rules and output of a calculation by the API key.
+func newTaxAssessment(source string, apiKeyID *int, rules TaxRules, input TaxInformation, res TaxCalculationResponse) (postgres.TaxAssessment, error) {
+	inputJSON, err := json.Marshal(input)
+	if err != nil {
+		return postgres.TaxAssessment{}, err
+	}
+	rulesJSON, err := json.Marshal(rules)
+	if err != nil {
+		return postgres.TaxAssessment{}, err
+	}
+	outputJSON, err := json.Marshal(res)
+	if err != nil {
+		return postgres.TaxAssessment{}, err
+	}
+
+	return postgres.TaxAssessment{
+		TaxYear:   rules.TaxYear,
+		RuleSet:   rules.RuleSet,
+		Source:    source,
+		Input:     inputJSON,
+		Rules:     rulesJSON,
+		Output:    outputJSON,
+		Tax:       res.Tax,
+		TaxRefund: res.TaxRefund,
+		APIKeyID:  apiKeyID,
+	}, nil
+}
+
+// storeAssessment stores the calculation as an assessment and sets the assessment ID
+// on the response.
+func (h *Handler) storeAssessment(source string, apiKeyID *int, rules TaxRules, input TaxInformation, res *TaxCalculationResponse) error {
+	pending, err := newTaxAssessment(source, apiKeyID, rules, input, *res)
+	if err != nil {
+		return err
+	}
+
+	assessment, err := h.storer.CreateTaxAssessment(pending)
+	if err != nil {
+		return err
+	}
+
+	res.AssessmentID = assessment.ID
+	return nil
+}
+
+// GetTaxAssessment returns an assessment stored with the API key of the request. The
+// assessments of other keys are not found.
+func (h *Handler) GetTaxAssessment(c echo.Context) error {
+	apiKeyID := requestAPIKeyID(c)
+	if apiKeyID == nil {
+		return c.JSON(http.StatusUnauthorized, &Err{
+			Message: ErrMissingAPIKey.Error(),
+		})
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid assessment id",
+		})
+	}
+
+	assessment, err := h.storer.GetTaxAssessment(id)
+	if err == nil && (assessment.APIKeyID == nil || *assessment.APIKeyID != *apiKeyID) {
+		err = sql.ErrNoRows
+	}
+	if errors.Is(err, sql.ErrNoRows) {
+		return c.JSON(http.StatusNotFound, &Err{
+			Message: "assessment not found",
+		})
+	}
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to get assessment",
+		})
+	}
+
+	return c.JSON(http.StatusOK, newTaxAssessmentResponse(*assessment))
+}
+
+// taxAssessmentFilter reads the taxYear, source, from, to, limit and offset query
+// parameters. The from and to dates are both inclusive.
+func taxAssessmentFilter(c echo.Context) (postgres.TaxAssessmentFilter, error) {
+	filter := postgres.TaxAssessmentFilter{
+		Source: c.QueryParam("source"),
+		Limit:  DEFAULT_ASSESSMENT_LIMIT,
+	}
+
+	intParam := func(name string, value *int) error {
+		if c.QueryParam(name) == "" {
+			return nil
+		}
+		v, err := strconv.Atoi(c.QueryParam(name))
+		if err != nil || v < 0 {
+			return errors.New("invalid " + name + " parameter")
+		}
+		*value = v
+		return nil
+	}
+	dateParam := func(name string, days int, value **time.Time) error {
+		if c.QueryParam(name) == "" {
+			return nil
+		}
+		date, err := time.Parse(DATE_FORMAT, c.QueryParam(name))
+		if err != nil {
+			return errors.New(name + " must be formatted as YYYY-MM-DD")
+		}
+		date = date.AddDate(0, 0, days)
+		*value = &date
+		return nil
+	}
+
+	for _, err := range []error{
+		intParam("taxYear", &filter.TaxYear),
+		intParam("limit", &filter.Limit),
+		intParam("offset", &filter.Offset),
+		dateParam("from", 0, &filter.From),
+		dateParam("to", 1, &filter.To),
+	} {
+		if err != nil {
+			return filter, err
+		}
+	}
+
+	switch filter.Source {
+	case "", ASSESSMENT_SOURCE_API, ASSESSMENT_SOURCE_CSV:
+	default:
+		return filter, errors.New("invalid source parameter")
+	}
+	if filter.Limit == 0 || filter.Limit > MAX_ASSESSMENT_LIMIT {
+		return filter, errors.New("limit must be between 1 and " + strconv.Itoa(MAX_ASSESSMENT_LIMIT))
+	}
+	return filter, nil
+}
+
+// ListTaxAssessments lists the assessments stored with the API key of the request.
+func (h *Handler) ListTaxAssessments(c echo.Context) error {
+	apiKeyID := requestAPIKeyID(c)
+	if apiKeyID == nil {
+		return c.JSON(http.StatusUnauthorized, &Err{
+			Message: ErrMissingAPIKey.Error(),
+		})
+	}
+
+	filter, err := taxAssessmentFilter(c)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+	filter.APIKeyID = *apiKeyID
+
+	assessments, err := h.storer.ListTaxAssessments(filter)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to list assessments",
+		})
+	}
+
+	res := []TaxAssessmentResponse{}
+	for _, assessment := range assessments {
+		res = append(res, newTaxAssessmentResponse(assessment))
+	}
+
+	return c.JSON(http.StatusOK, struct {
+		Assessments []TaxAssessmentResponse `json:"assessments"`
+	}{
+		Assessments: res,
+	})
+}
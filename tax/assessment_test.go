@@ -0,0 +1,190 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	PARTNER_KEY_ID = 1
+	OTHER_KEY_ID   = 2
+)
+
+// withAPIKey authenticates the request with the API key of the ID, as apikey.Guard does.
+func withAPIKey(c echo.Context, id int) echo.Context {
+	c.Set(API_KEY_ID_CONTEXT_KEY, id)
+	return c
+}
+
+func intPtr(v int) *int {
+	return &v
+}
+
+func TestTaxAssessment(t *testing.T) {
+	t.Run("given calculation should store it as an assessment", func(t *testing.T) {
+		stub := &StubTaxHandler{configs: map[string]*postgres.TaxConfig{}}
+		h := New(stub)
+
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(`{"totalIncome": 500000.0}`))
+		})
+		err := h.CalculateTax(withAPIKey(c, PARTNER_KEY_ID))
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		res := &TaxCalculationResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+		if res.AssessmentID != 1 {
+			t.Fatalf("invalid assessment id: got %v want %v", res.AssessmentID, 1)
+		}
+
+		rec = httptest.NewRecorder()
+		c = echo.New().NewContext(httptest.NewRequest(http.MethodGet, "/tax/calculations/1", nil), rec)
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+		err = h.GetTaxAssessment(withAPIKey(c, PARTNER_KEY_ID))
+		if err != nil {
+			t.Errorf("unable to get assessment: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		assessment := &TaxAssessmentResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), assessment)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		stored := stub.assessments[0]
+		if assessment.Source != ASSESSMENT_SOURCE_API || assessment.TaxYear != DEFAULT_TAX_YEAR || stored.Tax != money.FromBaht(29_000) {
+			t.Errorf("invalid assessment: got %+v", assessment)
+		}
+
+		var rules TaxRules
+		err = json.Unmarshal(assessment.Rules, &rules)
+		if err != nil || rules.PersonalDeduction != DEFAULT_PERSONAL_DEDUCTION {
+			t.Errorf("invalid stored rules: got %s", assessment.Rules)
+		}
+		if stored.APIKeyID == nil || *stored.APIKeyID != PARTNER_KEY_ID {
+			t.Errorf("invalid API key of the assessment: got %v want %v", stored.APIKeyID, PARTNER_KEY_ID)
+		}
+	})
+
+	t.Run("given assessment of another API key should return 404", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/tax/calculations/1", nil)
+		})
+		c.SetParamNames("id")
+		c.SetParamValues("1")
+
+		h := New(&StubTaxHandler{assessments: []postgres.TaxAssessment{
+			{ID: 1, TaxYear: 2567, Source: ASSESSMENT_SOURCE_API, APIKeyID: intPtr(OTHER_KEY_ID)},
+		}})
+		err := h.GetTaxAssessment(withAPIKey(c, PARTNER_KEY_ID))
+		if err != nil {
+			t.Errorf("unable to get assessment: %v", err)
+		}
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("given no API key should return 401", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/tax/calculations", nil)
+		})
+
+		h := New(&StubTaxHandler{})
+		err := h.ListTaxAssessments(c)
+		if err != nil {
+			t.Errorf("unable to list assessments: %v", err)
+		}
+
+		if rec.Code != http.StatusUnauthorized {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusUnauthorized)
+		}
+	})
+
+	t.Run("given unknown assessment id should return 404", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/tax/calculations/42", nil)
+		})
+		c.SetParamNames("id")
+		c.SetParamValues("42")
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.GetTaxAssessment(withAPIKey(c, PARTNER_KEY_ID))
+		if err != nil {
+			t.Errorf("unable to get assessment: %v", err)
+		}
+
+		if rec.Code != http.StatusNotFound {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusNotFound)
+		}
+	})
+
+	t.Run("given source filter should list the matching assessments of the API key", func(t *testing.T) {
+		stub := &StubTaxHandler{assessments: []postgres.TaxAssessment{
+			{ID: 1, TaxYear: 2567, Source: ASSESSMENT_SOURCE_API, APIKeyID: intPtr(PARTNER_KEY_ID)},
+			{ID: 2, TaxYear: 2567, Source: ASSESSMENT_SOURCE_CSV, APIKeyID: intPtr(PARTNER_KEY_ID)},
+			{ID: 3, TaxYear: 2567, Source: ASSESSMENT_SOURCE_API, APIKeyID: intPtr(PARTNER_KEY_ID)},
+			{ID: 4, TaxYear: 2567, Source: ASSESSMENT_SOURCE_API, APIKeyID: intPtr(OTHER_KEY_ID)},
+		}}
+
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/tax/calculations?source=api&from=2024-01-01", nil)
+		})
+
+		h := New(stub)
+		err := h.ListTaxAssessments(withAPIKey(c, PARTNER_KEY_ID))
+		if err != nil {
+			t.Errorf("unable to list assessments: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := struct {
+			Assessments []TaxAssessmentResponse `json:"assessments"`
+		}{}
+		err = json.Unmarshal(rec.Body.Bytes(), &res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		if len(res.Assessments) != 2 || res.Assessments[0].ID != 3 || res.Assessments[1].ID != 1 {
+			t.Errorf("invalid assessments: got %+v", res.Assessments)
+		}
+	})
+
+	t.Run("given limit above the maximum should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodGet, "/tax/calculations?limit=1000", nil)
+		})
+
+		h := New(&StubTaxHandler{})
+		err := h.ListTaxAssessments(withAPIKey(c, PARTNER_KEY_ID))
+		if err != nil {
+			t.Errorf("unable to list assessments: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,147 @@
+package tax
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func moneyPtr(v money.Money) *money.Money {
+	return &v
+}
+
+func TestNewTaxBrackets(t *testing.T) {
+	t.Run("given stored brackets should generate tax level labels from bounds", func(t *testing.T) {
+		brackets, err := NewTaxBrackets([]postgres.TaxBracket{
+			{MinIncome: 0, MaxIncome: moneyPtr(money.FromBaht(150_000)), Rate: money.MustParseRate("0")},
+			{MinIncome: money.FromBaht(150_000), MaxIncome: moneyPtr(money.FromBaht(500_000)), Rate: money.MustParseRate("0.1")},
+			{MinIncome: money.FromBaht(500_000), MaxIncome: moneyPtr(money.FromBaht(1_000_000)), Rate: money.MustParseRate("0.15")},
+			{MinIncome: money.FromBaht(1_000_000), MaxIncome: moneyPtr(money.FromBaht(2_000_000)), Rate: money.MustParseRate("0.2")},
+			{MinIncome: money.FromBaht(2_000_000), Rate: money.MustParseRate("0.35")},
+		})
+		if err != nil {
+			t.Fatalf("unable to create tax brackets: %v", err)
+		}
+
+		got := []string{}
+		for _, bracket := range brackets {
+			got = append(got, bracket.Label())
+		}
+
+		want := []string{"0-150,000", "150,001-500,000", "500,001-1,000,000", "1,000,001-2,000,000", "2,000,001 ขึ้นไป"}
+		if !reflect.DeepEqual(got, want) {
+			t.Errorf("invalid tax level labels: got %v want %v", got, want)
+		}
+	})
+
+	t.Run("given brackets with a gap should return error", func(t *testing.T) {
+		_, err := NewTaxBrackets([]postgres.TaxBracket{
+			{MinIncome: 0, MaxIncome: moneyPtr(money.FromBaht(150_000)), Rate: money.MustParseRate("0")},
+			{MinIncome: money.FromBaht(200_000), Rate: money.MustParseRate("0.1")},
+		})
+		if err == nil {
+			t.Errorf("expected error for non-contiguous brackets")
+		}
+	})
+
+	t.Run("given brackets without open-ended top bracket should return error", func(t *testing.T) {
+		_, err := NewTaxBrackets([]postgres.TaxBracket{
+			{MinIncome: 0, MaxIncome: moneyPtr(money.FromBaht(150_000)), Rate: money.MustParseRate("0")},
+		})
+		if err == nil {
+			t.Errorf("expected error for bounded top bracket")
+		}
+	})
+}
+
+func TestCalculateTaxWithStoredBrackets(t *testing.T) {
+	c, rec := setup(t, func() *http.Request {
+		return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"totalIncome": 500000.0}`))
+	})
+
+	h := New(&StubTaxHandler{
+		configs: map[string]*postgres.TaxConfig{
+			"PERSONAL_DEDUCTION": {
+				Value: money.FromBaht(60_000),
+			},
+			"MAX_K_RECEIPT_DEDUCTION": {
+				Value: money.FromBaht(50_000),
+			},
+		},
+		brackets: []postgres.TaxBracket{
+			{MinIncome: 0, MaxIncome: moneyPtr(money.FromBaht(200_000)), Rate: money.MustParseRate("0")},
+			{MinIncome: money.FromBaht(200_000), Rate: money.MustParseRate("0.05")},
+		},
+	})
+
+	err := h.CalculateTax(c)
+	if err != nil {
+		t.Errorf("unable to calculate tax: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("invalid status code: got %v want %v",
+			rec.Code, http.StatusOK)
+	}
+
+	res := &TaxCalculationResponse{}
+	err = json.Unmarshal(rec.Body.Bytes(), res)
+	if err != nil {
+		t.Errorf("unable to unmarshal response: %v", err)
+	}
+
+	if res.Tax != money.FromBaht(12_000) {
+		t.Errorf("invalid tax: got %v want %v", res.Tax, money.FromBaht(12_000))
+	}
+
+	expectedTaxLevel := []TaxLevel{
+		{Level: "0-200,000", Tax: 0},
+		{Level: "200,001 ขึ้นไป", Tax: money.FromBaht(12_000)},
+	}
+	if !reflect.DeepEqual(res.TaxLevel, expectedTaxLevel) {
+		t.Errorf("invalid tax level: got %v want %v", res.TaxLevel, expectedTaxLevel)
+	}
+}
+
+func TestCalculateTaxWithUnloadableBrackets(t *testing.T) {
+	testCases := []struct {
+		name string
+		stub *StubTaxHandler
+	}{
+		{
+			name: "given brackets that fail to load should return 500",
+			stub: &StubTaxHandler{bracketsErr: errors.New("connection refused")},
+		},
+		{
+			name: "given invalid stored brackets should return 500",
+			stub: &StubTaxHandler{brackets: []postgres.TaxBracket{}},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			c, rec := setup(t, func() *http.Request {
+				return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"totalIncome": 500000.0}`))
+			})
+
+			err := New(tc.stub).CalculateTax(c)
+			if err != nil {
+				t.Errorf("unable to calculate tax: %v", err)
+			}
+
+			if rec.Code != http.StatusInternalServerError {
+				t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusInternalServerError)
+			}
+			if !strings.Contains(rec.Body.String(), "unable to load tax rules") {
+				t.Errorf("invalid response body: %s", rec.Body.String())
+			}
+		})
+	}
+}
@@ -0,0 +1,129 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+const (
+	// Thai dividends are either left out of the return, with the 10% withholding as the
+	// final tax, or included as 40(4) income grossed up by the corporate tax credit.
+	DIVIDEND_METHOD_FINAL_WITHHOLDING = "final-withholding"
+	DIVIDEND_METHOD_TAX_CREDIT        = "tax-credit"
+
+	DIVIDEND_INCOME_TYPE = "40(4)"
+)
+
+var DIVIDEND_WITHHOLDING_RATE = money.MustParseRate("0.10")
+
+type (
+	// Dividend is a Thai dividend and the corporate tax rate of the profit it was paid from.
+	Dividend struct {
+		Amount           money.Money `json:"amount"`
+		CorporateTaxRate money.Rate  `json:"corporateTaxRate"`
+	}
+
+	// DividendDetails compares the total tax of both dividend methods, FinalWithholdingTax
+	// and TaxCreditTax, and tells which Method was applied.
+	DividendDetails struct {
+		Method              string      `json:"method"`
+		Amount              money.Money `json:"amount"`
+		Withholding         money.Money `json:"withholding"`
+		TaxCredit           money.Money `json:"taxCredit"`
+		GrossIncome         money.Money `json:"grossIncome"`
+		FinalWithholdingTax money.Money `json:"finalWithholdingTax"`
+		TaxCreditTax        money.Money `json:"taxCreditTax"`
+	}
+)
+
+// taxCredit is the corporate tax paid on the profit behind the dividend, amount * rate / (1 - rate).
+func (d Dividend) taxCredit() money.Money {
+	return d.Amount.DivRate(money.RateScale-d.CorporateTaxRate) - d.Amount
+}
+
+func validateDividends(info TaxInformation) error {
+	switch info.DividendMethod {
+	case "", DIVIDEND_METHOD_FINAL_WITHHOLDING, DIVIDEND_METHOD_TAX_CREDIT:
+	default:
+		return fmt.Errorf("invalid dividendMethod %q", info.DividendMethod)
+	}
+
+	if len(info.Dividends) > 0 && info.isHalfYear() {
+		return errors.New("dividends are not filed on the half-year return")
+	}
+	for _, dividend := range info.Dividends {
+		if dividend.Amount < 0 {
+			return errors.New("dividend amount must not be negative")
+		}
+		if dividend.CorporateTaxRate < 0 || dividend.CorporateTaxRate >= money.RateScale {
+			return errors.New("corporateTaxRate must be at least 0 and less than 1")
+		}
+	}
+	return nil
+}
+
+func dividendDetails(dividends []Dividend) *DividendDetails {
+	details := &DividendDetails{}
+	for _, dividend := range dividends {
+		details.Amount += dividend.Amount
+		details.Withholding += dividend.Amount.MulRate(DIVIDEND_WITHHOLDING_RATE)
+		details.TaxCredit += dividend.taxCredit()
+	}
+	details.GrossIncome = details.Amount + details.TaxCredit
+	return details
+}
+
+// withDividendIncome adds the grossed-up dividend to the income of the return as 40(4)
+// income, which is part of the alternative tax base.
+func (t TaxInformation) withDividendIncome(grossIncome money.Money) TaxInformation {
+	if len(t.Incomes) == 0 {
+		t.TotalIncome += grossIncome
+		t.dividendIncome += grossIncome
+		return t
+	}
+
+	incomes := append([]Income{}, t.Incomes...)
+	t.Incomes = append(incomes, Income{IncomeType: DIVIDEND_INCOME_TYPE, Amount: grossIncome})
+	if t.TotalIncome != 0 {
+		t.TotalIncome += grossIncome
+	}
+	return t
+}
+
+// calculateDividends assesses the return under both dividend methods. Unless the taxpayer
+// chose a method, the one with the lower total tax is applied, including the dividend
+// withholding that is final under one method and credited under the other.
+func (t TaxCalculator) calculateDividends(info TaxInformation) CalculateTaxDetails {
+	dividend := dividendDetails(info.Dividends)
+
+	finalWithholding := t.assess(info, 0)
+	taxCredit := t.assess(info.withDividendIncome(dividend.GrossIncome), dividend.Withholding+dividend.TaxCredit)
+	dividend.FinalWithholdingTax = finalWithholding.assessedTax() + dividend.Withholding
+	dividend.TaxCreditTax = taxCredit.assessedTax() - dividend.TaxCredit
+
+	dividend.Method = info.DividendMethod
+	if dividend.Method == "" {
+		dividend.Method = DIVIDEND_METHOD_FINAL_WITHHOLDING
+		if dividend.TaxCreditTax < dividend.FinalWithholdingTax {
+			dividend.Method = DIVIDEND_METHOD_TAX_CREDIT
+		}
+	}
+
+	details := finalWithholding
+	if dividend.Method == DIVIDEND_METHOD_TAX_CREDIT {
+		details = taxCredit
+	}
+	details.dividend = dividend
+	return details
+}
+
+// dividendCredits returns the return as assessed under the applied dividend method and
+// the dividend withholding and tax credit it credits.
+func (d CalculateTaxDetails) dividendCredits(info TaxInformation) (TaxInformation, money.Money) {
+	if d.dividend == nil || d.dividend.Method != DIVIDEND_METHOD_TAX_CREDIT {
+		return info, 0
+	}
+	return info.withDividendIncome(d.dividend.GrossIncome), d.dividend.Withholding + d.dividend.TaxCredit
+}
@@ -0,0 +1,145 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestDividendTaxCredit(t *testing.T) {
+	dividend := []Dividend{{Amount: money.FromBaht(100_000), CorporateTaxRate: money.MustParseRate("0.20")}}
+
+	t.Run("given 15% marginal rate should claim the tax credit", func(t *testing.T) {
+		details := newDefaultTaxCalculator().calculate(TaxInformation{
+			Incomes:   []Income{{IncomeType: "40(1)", Amount: money.FromBaht(1_000_000)}},
+			Dividends: dividend,
+		})
+
+		// 840,000 + 125,000 grossed-up dividend, tax 104,750 less 25,000 credit and 10,000 withheld
+		want := DividendDetails{
+			Method:              DIVIDEND_METHOD_TAX_CREDIT,
+			Amount:              money.FromBaht(100_000),
+			Withholding:         money.FromBaht(10_000),
+			TaxCredit:           money.FromBaht(25_000),
+			GrossIncome:         money.FromBaht(125_000),
+			FinalWithholdingTax: money.FromBaht(96_000),
+			TaxCreditTax:        money.FromBaht(79_750),
+		}
+		if *details.dividend != want {
+			t.Errorf("invalid dividend details: got %+v want %+v", *details.dividend, want)
+		}
+		if details.tax != money.FromBaht(69_750) {
+			t.Errorf("invalid tax: got %v want %v", details.tax, money.FromBaht(69_750))
+		}
+	})
+
+	t.Run("given 35% marginal rate should keep the final withholding", func(t *testing.T) {
+		details := newDefaultTaxCalculator().calculate(TaxInformation{
+			Incomes:   []Income{{IncomeType: "40(1)", Amount: money.FromBaht(5_000_000)}},
+			Dividends: dividend,
+		})
+
+		if details.dividend.Method != DIVIDEND_METHOD_FINAL_WITHHOLDING {
+			t.Errorf("invalid dividend method: got %v want %v", details.dividend.Method, DIVIDEND_METHOD_FINAL_WITHHOLDING)
+		}
+		if details.tax != money.FromBaht(1_304_000) {
+			t.Errorf("invalid tax: got %v want %v", details.tax, money.FromBaht(1_304_000))
+		}
+	})
+
+	t.Run("given chosen method should apply it even when it costs more", func(t *testing.T) {
+		details := newDefaultTaxCalculator().calculate(TaxInformation{
+			Incomes:        []Income{{IncomeType: "40(1)", Amount: money.FromBaht(1_000_000)}},
+			Dividends:      dividend,
+			DividendMethod: DIVIDEND_METHOD_FINAL_WITHHOLDING,
+		})
+
+		if details.tax != money.FromBaht(86_000) {
+			t.Errorf("invalid tax: got %v want %v", details.tax, money.FromBaht(86_000))
+		}
+	})
+
+	t.Run("given credits above the tax should refund them", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(`{
+				"totalIncome": 200000.0,
+				"dividends": [{ "amount": 100000.0, "corporateTaxRate": 0.20 }]
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := &TaxCalculationResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		// 265,000 taxable income, tax 11,500 against 35,000 of credits
+		if res.TaxRefund != money.FromBaht(23_500) {
+			t.Errorf("invalid tax refund: got %v want %v", res.TaxRefund, money.FromBaht(23_500))
+		}
+		if res.Dividend == nil || res.Dividend.Method != DIVIDEND_METHOD_TAX_CREDIT {
+			t.Errorf("invalid dividend details: got %+v", res.Dividend)
+		}
+	})
+
+	t.Run("given corporate tax rate of 100% should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(`{
+				"totalIncome": 200000.0,
+				"dividends": [{ "amount": 100000.0, "corporateTaxRate": 1 }]
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestDividendAlternativeTax(t *testing.T) {
+	t.Run("given the same dividend with total income or incomes should assess the same tax", func(t *testing.T) {
+		dividends := []Dividend{{Amount: money.FromBaht(2_000_000), CorporateTaxRate: money.MustParseRate("0.20")}}
+		calculator := newDefaultTaxCalculator()
+
+		// 1,100,000 of salary is 1,000,000 after the 100,000 expense cap.
+		withTotalIncome := calculator.calculate(TaxInformation{
+			TotalIncome:    money.FromBaht(1_000_000),
+			Dividends:      dividends,
+			DividendMethod: DIVIDEND_METHOD_TAX_CREDIT,
+		})
+		withIncomes := calculator.calculate(TaxInformation{
+			Incomes:        []Income{{IncomeType: "40(1)", Amount: money.FromBaht(1_100_000)}},
+			Dividends:      dividends,
+			DividendMethod: DIVIDEND_METHOD_TAX_CREDIT,
+		})
+
+		// 0.5% of the 2,500,000 grossed-up dividend
+		if withTotalIncome.alternativeTax != money.FromBaht(12_500) || withIncomes.alternativeTax != money.FromBaht(12_500) {
+			t.Errorf("invalid alternative tax: got %v and %v want %v", withTotalIncome.alternativeTax, withIncomes.alternativeTax, money.FromBaht(12_500))
+		}
+		if withTotalIncome.tax != withIncomes.tax {
+			t.Errorf("invalid tax: got %v and %v", withTotalIncome.tax, withIncomes.tax)
+		}
+	})
+}
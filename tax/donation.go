@@ -0,0 +1,100 @@
+package tax
+
+import (
+	"fmt"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+// donationMultipliers holds how many times a donation counts towards the deduction,
+// "" is a general donation.
+var donationMultipliers = map[string]int64{
+	"":          1,
+	"general":   1,
+	"education": 2,
+	"sports":    2,
+	"hospital":  2,
+}
+
+// DonationDeduction reports how much of a single donation was deducted.
+type DonationDeduction struct {
+	Category   string      `json:"category"`
+	Amount     money.Money `json:"amount"`
+	Multiplier int64       `json:"multiplier"`
+	Counted    money.Money `json:"counted"`
+	Deductible money.Money `json:"deductible"`
+}
+
+func validateDonation(claim Allowance) error {
+	if _, ok := donationMultipliers[claim.Category]; !ok {
+		return fmt.Errorf("invalid donation category %q", claim.Category)
+	}
+	return nil
+}
+
+func countedDonation(claim Allowance) money.Money {
+	return claim.Amount.Mul(donationMultipliers[claim.Category])
+}
+
+// donationAllowance applies the category multipliers and then limits the total to a
+// percentage of the income left after every other allowance.
+func donationAllowance(claims []Allowance, ctx AllowanceContext) (money.Money, string) {
+	counted := money.Money(0)
+	for _, claim := range claims {
+		counted += countedDonation(claim)
+	}
+
+	limit := money.Max(ctx.NetIncome, 0).MulRate(ctx.Cap("DONATION_INCOME_PERCENT").Percent())
+	return limitTo(counted, limit, "DONATION_INCOME_PERCENT")
+}
+
+// donationDeductions spreads the allowed donation deduction of the return's allowance
+// deductions over the donations in proportion to their counted amount, the rounding
+// remainder goes to the last one.
+func donationDeductions(info TaxInformation, deductions []AllowanceDeduction) []DonationDeduction {
+	claims := info.allowancesByType("donation")
+	if len(claims) == 0 {
+		return nil
+	}
+
+	allowed := money.Money(0)
+	for _, deduction := range deductions {
+		if deduction.Type == "donation" {
+			allowed = deduction.Allowed
+		}
+	}
+
+	totalCounted := money.Money(0)
+	for _, claim := range claims {
+		totalCounted += countedDonation(claim)
+	}
+
+	donations := []DonationDeduction{}
+	remaining := allowed
+	for i, claim := range claims {
+		counted := countedDonation(claim)
+
+		deductible := remaining
+		if i < len(claims)-1 {
+			deductible = 0
+			if totalCounted > 0 {
+				deductible = money.Min(allowed.Share(counted, totalCounted), remaining)
+			}
+		}
+		remaining -= deductible
+
+		category := claim.Category
+		if category == "" {
+			category = "general"
+		}
+		donations = append(donations, DonationDeduction{
+			Category:   category,
+			Amount:     claim.Amount,
+			Multiplier: donationMultipliers[claim.Category],
+			Counted:    counted,
+			Deductible: deductible,
+		})
+	}
+
+	return donations
+}
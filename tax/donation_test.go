@@ -0,0 +1,76 @@
+package tax
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+func TestDonationDeductions(t *testing.T) {
+	t.Run("given double deduction donations should count them twice within the 10% limit", func(t *testing.T) {
+		calculator := newDefaultTaxCalculator()
+		info := TaxInformation{
+			TotalIncome: money.FromBaht(1_000_000),
+			Allowances: []Allowance{
+				{AllowanceType: "k-receipt", Amount: money.FromBaht(40_000)},
+				{AllowanceType: "donation", Amount: money.FromBaht(10_000)},
+				{AllowanceType: "donation", Category: "education", Amount: money.FromBaht(10_000)},
+			},
+		}
+
+		// 1,000,000 - 60,000 - 40,000 = 900,000, limit 90,000, counted 30,000
+		if got := allowedByType(calculator.allowanceDeductions(info))["donation"]; got != money.FromBaht(30_000) {
+			t.Errorf("invalid donation deduction: got %v want %v", got, money.FromBaht(30_000))
+		}
+
+		expected := []DonationDeduction{
+			{Category: "general", Amount: money.FromBaht(10_000), Multiplier: 1, Counted: money.FromBaht(10_000), Deductible: money.FromBaht(10_000)},
+			{Category: "education", Amount: money.FromBaht(10_000), Multiplier: 2, Counted: money.FromBaht(20_000), Deductible: money.FromBaht(20_000)},
+		}
+		if got := calculator.calculate(info).donations; !reflect.DeepEqual(got, expected) {
+			t.Errorf("invalid donations: got %v want %v", got, expected)
+		}
+	})
+
+	t.Run("given donations above the limit should spread the limit over the donations", func(t *testing.T) {
+		calculator := newDefaultTaxCalculator()
+		info := TaxInformation{
+			TotalIncome: money.FromBaht(360_000),
+			Allowances: []Allowance{
+				{AllowanceType: "donation", Amount: money.FromBaht(20_000)},
+				{AllowanceType: "donation", Category: "hospital", Amount: money.FromBaht(20_000)},
+			},
+		}
+
+		// 360,000 - 60,000 = 300,000, limit 30,000 of 60,000 counted
+		donations := calculator.calculate(info).donations
+		if donations[0].Deductible != money.FromBaht(10_000) || donations[1].Deductible != money.FromBaht(20_000) {
+			t.Errorf("invalid donations: got %v", donations)
+		}
+	})
+
+	t.Run("given large donations should spread the limit without overflowing", func(t *testing.T) {
+		calculator := newDefaultTaxCalculator()
+		info := TaxInformation{
+			TotalIncome: money.FromBaht(500_060_000),
+			Allowances: []Allowance{
+				{AllowanceType: "donation", Amount: money.FromBaht(40_000_000)},
+				{AllowanceType: "donation", Category: "education", Amount: money.FromBaht(20_000_000)},
+			},
+		}
+
+		// 500,060,000 - 60,000 = 500,000,000, limit 50,000,000 of 80,000,000 counted
+		donations := calculator.calculate(info).donations
+		if donations[0].Deductible != money.FromBaht(25_000_000) || donations[1].Deductible != money.FromBaht(25_000_000) {
+			t.Errorf("invalid donations: got %v", donations)
+		}
+	})
+
+	t.Run("given unknown donation category should return error", func(t *testing.T) {
+		err := validateAllowance([]Allowance{{AllowanceType: "donation", Category: "temple-fair"}})
+		if err == nil {
+			t.Errorf("expected error for unknown donation category")
+		}
+	})
+}
@@ -0,0 +1,104 @@
+package tax
+
+import "github.com/bytesbanana/assessment-tax/money"
+
+// Step codes are stable so clients can localise the explanation.
+const (
+	STEP_GROSS_INCOME       = "GROSS_INCOME"
+	STEP_INCOME_EXPENSE     = "INCOME_EXPENSE"
+	STEP_NET_INCOME         = "NET_INCOME"
+	STEP_PERSONAL_DEDUCTION = "PERSONAL_DEDUCTION"
+	STEP_ALLOWANCE          = "ALLOWANCE"
+	STEP_TAXABLE_INCOME     = "TAXABLE_INCOME"
+	STEP_TAX_BRACKET        = "TAX_BRACKET"
+	STEP_PROGRESSIVE_TAX    = "PROGRESSIVE_TAX"
+	STEP_ALTERNATIVE_TAX    = "ALTERNATIVE_TAX"
+	STEP_TAX                = "TAX"
+	STEP_WHT                = "WHT"
+	STEP_INTERIM_TAX        = "INTERIM_TAX"
+	STEP_DIVIDEND_CREDIT    = "DIVIDEND_CREDIT"
+	STEP_TAX_PAYABLE        = "TAX_PAYABLE"
+	STEP_TAX_REFUND         = "TAX_REFUND"
+)
+
+// ExplanationStep is one step of a calculation trace. Subject is the income type,
+// allowance type, bracket level or tax method the step is about.
+type ExplanationStep struct {
+	Code    string       `json:"code"`
+	Subject string       `json:"subject,omitempty"`
+	Claimed *money.Money `json:"claimed,omitempty"`
+	Limit   string       `json:"limit,omitempty"`
+	Base    *money.Money `json:"base,omitempty"`
+	Rate    *money.Rate  `json:"rate,omitempty"`
+	Amount  money.Money  `json:"amount"`
+}
+
+// explain traces how the calculation arrived at the details, it reads every amount from
+// the details rather than recalculating it.
+func (t TaxCalculator) explain(info TaxInformation, details CalculateTaxDetails) []ExplanationStep {
+	info, dividendCredits := details.dividendCredits(info)
+
+	steps := []ExplanationStep{
+		{Code: STEP_GROSS_INCOME, Amount: info.grossIncome()},
+	}
+
+	if details.income != nil {
+		for _, income := range details.income.Incomes {
+			amount := income.Amount
+			steps = append(steps, ExplanationStep{
+				Code:    STEP_INCOME_EXPENSE,
+				Subject: income.IncomeType,
+				Base:    &amount,
+				Amount:  income.Expense,
+			})
+		}
+	}
+
+	steps = append(steps,
+		ExplanationStep{Code: STEP_NET_INCOME, Amount: details.netIncome},
+		ExplanationStep{Code: STEP_PERSONAL_DEDUCTION, Amount: t.rules.PersonalDeduction},
+	)
+
+	for _, deduction := range details.allowances {
+		claimed := deduction.Claimed
+		steps = append(steps, ExplanationStep{
+			Code:    STEP_ALLOWANCE,
+			Subject: deduction.Type,
+			Claimed: &claimed,
+			Limit:   deduction.Limit,
+			Amount:  deduction.Allowed,
+		})
+	}
+
+	taxableIncome := details.taxableIncome
+	steps = append(steps, ExplanationStep{Code: STEP_TAXABLE_INCOME, Amount: taxableIncome})
+
+	for level, bracket := range t.rules.Brackets {
+		base := money.Max(money.Min(taxableIncome, bracket.MaxIncome)-bracket.MinIncome, 0)
+		rate := bracket.Rate
+		steps = append(steps, ExplanationStep{
+			Code:    STEP_TAX_BRACKET,
+			Subject: details.taxLevel[level].Level,
+			Base:    &base,
+			Rate:    &rate,
+			Amount:  details.taxLevel[level].Tax,
+		})
+	}
+
+	steps = append(steps,
+		ExplanationStep{Code: STEP_PROGRESSIVE_TAX, Amount: details.progressiveTax},
+		ExplanationStep{Code: STEP_ALTERNATIVE_TAX, Amount: details.alternativeTax},
+		ExplanationStep{Code: STEP_TAX, Subject: details.taxMethod, Amount: details.assessedTax()},
+		ExplanationStep{Code: STEP_WHT, Amount: info.WHT},
+		ExplanationStep{Code: STEP_INTERIM_TAX, Amount: info.InterimTax},
+	)
+	if details.dividend != nil {
+		steps = append(steps, ExplanationStep{Code: STEP_DIVIDEND_CREDIT, Subject: details.dividend.Method, Amount: dividendCredits})
+	}
+	steps = append(steps,
+		ExplanationStep{Code: STEP_TAX_PAYABLE, Amount: details.tax},
+		ExplanationStep{Code: STEP_TAX_REFUND, Amount: details.taxRefund},
+	)
+
+	return steps
+}
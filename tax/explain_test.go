@@ -0,0 +1,98 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestCalculateTaxExplanation(t *testing.T) {
+	t.Run("given explain=true should return calculation steps", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			reqJSON := `{
+				"totalIncome": 500000.0,
+				"wht": 30000.0,
+				"allowances": [
+					{ "allowanceType": "k-receipt", "amount": 200000.0 }
+				]
+			}`
+			return httptest.NewRequest(http.MethodPost, "/?explain=true", strings.NewReader(reqJSON))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		res := &TaxCalculationResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		steps := map[string]ExplanationStep{}
+		for _, step := range res.Explanation {
+			if step.Code == STEP_TAX_BRACKET && step.Subject != "150,001-500,000" {
+				continue
+			}
+			steps[step.Code] = step
+		}
+
+		allowance := steps[STEP_ALLOWANCE]
+		if allowance.Subject != "k-receipt" || *allowance.Claimed != money.FromBaht(200_000) ||
+			allowance.Amount != money.FromBaht(50_000) || allowance.Limit != "MAX_K_RECEIPT_DEDUCTION" {
+			t.Errorf("invalid allowance step: got %+v", allowance)
+		}
+
+		if steps[STEP_TAXABLE_INCOME].Amount != money.FromBaht(390_000) {
+			t.Errorf("invalid taxable income: got %v want %v", steps[STEP_TAXABLE_INCOME].Amount, money.FromBaht(390_000))
+		}
+
+		bracket := steps[STEP_TAX_BRACKET]
+		if *bracket.Base != money.FromBaht(240_000) || *bracket.Rate != money.MustParseRate("0.1") || bracket.Amount != money.FromBaht(24_000) {
+			t.Errorf("invalid tax bracket step: got %+v", bracket)
+		}
+
+		if steps[STEP_TAX_REFUND].Amount != money.FromBaht(6_000) {
+			t.Errorf("invalid tax refund: got %v want %v", steps[STEP_TAX_REFUND].Amount, money.FromBaht(6_000))
+		}
+	})
+
+	t.Run("given no explain parameter should not return calculation steps", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"totalIncome": 500000.0}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		if strings.Contains(rec.Body.String(), "explanation") {
+			t.Errorf("unexpected explanation in response: %s", rec.Body.String())
+		}
+	})
+
+	t.Run("given invalid explain parameter should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/?explain=maybe", strings.NewReader(`{"totalIncome": 500000.0}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,182 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/labstack/echo/v4"
+)
+
+var (
+	// MAX_GROSS_UP_NET_INCOME bounds the target net income and MAX_GROSS_UP_INCOME the gross
+	// income searched for it, which keeps the search far from overflowing.
+	MAX_GROSS_UP_NET_INCOME = money.FromBaht(1_000_000_000)
+	MAX_GROSS_UP_INCOME     = MAX_GROSS_UP_NET_INCOME.Mul(100)
+
+	ErrNetIncomeUnreachable = errors.New("netIncome cannot be reached under the tax rules")
+)
+
+type (
+	// GrossUpRequest asks for the gross income that leaves NetIncome after tax.
+	GrossUpRequest struct {
+		TaxYear   int         `json:"taxYear"`
+		NetIncome money.Money `json:"netIncome"`
+		// IncomeType is the Section 40 category of the gross income, 40(1) by default.
+		IncomeType string      `json:"incomeType,omitempty"`
+		Allowances []Allowance `json:"allowances"`
+	}
+
+	MarginalBracket struct {
+		Level string     `json:"level"`
+		Rate  money.Rate `json:"rate"`
+	}
+
+	GrossUpResponse struct {
+		GrossIncome     money.Money     `json:"grossIncome"`
+		Tax             money.Money     `json:"tax"`
+		NetIncome       money.Money     `json:"netIncome"`
+		MarginalBracket MarginalBracket `json:"marginalBracket"`
+	}
+)
+
+func (r GrossUpRequest) taxInformation(grossIncome money.Money) TaxInformation {
+	incomeType := r.IncomeType
+	if incomeType == "" {
+		incomeType = "40(1)"
+	}
+
+	return TaxInformation{
+		TaxYear:    r.TaxYear,
+		Incomes:    []Income{{IncomeType: incomeType, Amount: grossIncome}},
+		Allowances: r.Allowances,
+	}
+}
+
+func (r GrossUpRequest) validate() error {
+	if r.NetIncome <= 0 {
+		return errors.New("netIncome must be greater than 0")
+	}
+	if r.NetIncome > MAX_GROSS_UP_NET_INCOME {
+		return fmt.Errorf("netIncome must not be greater than %s", MAX_GROSS_UP_NET_INCOME)
+	}
+	if err := validateIncomes(r.taxInformation(0)); err != nil {
+		return err
+	}
+	return validateAllowance(r.Allowances)
+}
+
+// grossUp finds the lowest gross income whose after-tax income reaches the target.
+// After-tax income grows with gross income except where the alternative tax starts to
+// apply, it jumps from zero to over MAX_ALTERNATIVE_TAX_EXEMPT there. Each side of that
+// gross income is searched on its own, so a binary search over satang gives the exact answer.
+func (t TaxCalculator) grossUp(req GrossUpRequest) (GrossUpResponse, error) {
+	reaches := func(grossIncome money.Money) bool {
+		return grossIncome-t.calculate(req.taxInformation(grossIncome)).tax >= req.NetIncome
+	}
+
+	// Tax is never negative, so the gross income is at least the target.
+	low := req.NetIncome
+	if threshold, ok := t.alternativeTaxThreshold(req); ok && threshold > low {
+		if reaches(threshold - 1) {
+			return t.grossUpResponse(req, lowestGross(low, threshold-1, reaches)), nil
+		}
+		low = threshold
+	}
+
+	high := low
+	for !reaches(high) {
+		if high >= MAX_GROSS_UP_INCOME {
+			return GrossUpResponse{}, ErrNetIncomeUnreachable
+		}
+		high = money.Min(high.Mul(2), MAX_GROSS_UP_INCOME)
+	}
+
+	return t.grossUpResponse(req, lowestGross(low, high, reaches)), nil
+}
+
+// alternativeTaxThreshold returns the lowest gross income the alternative tax applies to,
+// ok is false when it never applies to the income type.
+func (t TaxCalculator) alternativeTaxThreshold(req GrossUpRequest) (threshold money.Money, ok bool) {
+	applies := func(grossIncome money.Money) bool {
+		return t.calAlternativeTax(req.taxInformation(grossIncome)) > 0
+	}
+	if !applies(MAX_GROSS_UP_INCOME) {
+		return 0, false
+	}
+	return lowestGross(0, MAX_GROSS_UP_INCOME, applies), true
+}
+
+// lowestGross returns the lowest gross income between low and high that satisfies ok,
+// ok must hold for high and for every gross income above one it holds for.
+func lowestGross(low, high money.Money, ok func(money.Money) bool) money.Money {
+	for low < high {
+		mid := low + (high-low)/2
+		if ok(mid) {
+			high = mid
+		} else {
+			low = mid + 1
+		}
+	}
+	return low
+}
+
+func (t TaxCalculator) grossUpResponse(req GrossUpRequest, grossIncome money.Money) GrossUpResponse {
+	details := t.calculate(req.taxInformation(grossIncome))
+
+	return GrossUpResponse{
+		GrossIncome:     grossIncome,
+		Tax:             details.tax,
+		NetIncome:       grossIncome - details.tax,
+		MarginalBracket: t.marginalBracket(details.taxableIncome),
+	}
+}
+
+// marginalBracket returns the bracket the last baht of taxable income falls in.
+func (t TaxCalculator) marginalBracket(taxableIncome money.Money) MarginalBracket {
+	marginal := t.rules.Brackets[0]
+	for _, bracket := range t.rules.Brackets {
+		if taxableIncome > bracket.MinIncome {
+			marginal = bracket
+		}
+	}
+
+	return MarginalBracket{
+		Level: marginal.Label(),
+		Rate:  marginal.Rate,
+	}
+}
+
+func (h *Handler) GrossUp(c echo.Context) error {
+	var req GrossUpRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	err = req.validate()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	info := req.taxInformation(0)
+	taxYear := info.taxYear()
+	taxCalculator, err := h.newTaxCalculator(taxYear, info.taxDate())
+	if err != nil {
+		return taxRulesError(c, taxYear, err)
+	}
+
+	res, err := taxCalculator.grossUp(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
@@ -0,0 +1,149 @@
+package tax
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestGrossUp(t *testing.T) {
+	t.Run("given target net income should return the gross income that yields it", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/gross-up", strings.NewReader(`{"netIncome": 600000.0}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.GrossUp(c)
+		if err != nil {
+			t.Errorf("unable to gross up: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := &GrossUpResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		if res.NetIncome != money.FromBaht(600_000) || res.GrossIncome-res.Tax != res.NetIncome {
+			t.Errorf("invalid net income: got %+v", res)
+		}
+		if res.MarginalBracket.Level != "150,001-500,000" {
+			t.Errorf("invalid marginal bracket: got %v", res.MarginalBracket)
+		}
+
+		calculator := newDefaultTaxCalculator()
+		lower := calculator.calculate(TaxInformation{Incomes: []Income{{IncomeType: "40(1)", Amount: res.GrossIncome - 1}}})
+		if res.GrossIncome-1-lower.tax >= money.FromBaht(600_000) {
+			t.Errorf("gross income is not the lowest: got %v", res.GrossIncome)
+		}
+	})
+
+	t.Run("given target net income in a higher bracket should gross up through the brackets", func(t *testing.T) {
+		res, err := newDefaultTaxCalculator().grossUp(GrossUpRequest{NetIncome: money.FromBaht(2_000_000)})
+		if err != nil {
+			t.Fatalf("unable to gross up: %v", err)
+		}
+
+		if res.NetIncome < money.FromBaht(2_000_000) || res.NetIncome > money.FromBaht(2_000_000)+1 {
+			t.Errorf("invalid net income: got %v", res.NetIncome)
+		}
+		if res.MarginalBracket.Rate != money.MustParseRate("0.35") {
+			t.Errorf("invalid marginal rate: got %v", res.MarginalBracket.Rate)
+		}
+	})
+
+	t.Run("given target net income just below where the alternative tax applies should return the lowest gross income", func(t *testing.T) {
+		// 40(8) income of 999,000 is 399,600 after expenses and 139,600 after the personal
+		// deduction and RMF, so no tax is due until the alternative tax applies above 1,000,000.
+		res, err := newDefaultTaxCalculator().grossUp(GrossUpRequest{
+			NetIncome:  money.FromBaht(999_000),
+			IncomeType: "40(8)",
+			Allowances: []Allowance{{AllowanceType: "rmf", Amount: money.FromBaht(200_000)}},
+		})
+		if err != nil {
+			t.Fatalf("unable to gross up: %v", err)
+		}
+
+		if res.GrossIncome != money.FromBaht(999_000) || res.Tax != 0 {
+			t.Errorf("invalid gross up: got %+v", res)
+		}
+	})
+
+	t.Run("given target net income above where the alternative tax applies should gross up past it", func(t *testing.T) {
+		req := GrossUpRequest{
+			NetIncome:  money.FromBaht(1_000_100),
+			IncomeType: "40(8)",
+			Allowances: []Allowance{{AllowanceType: "rmf", Amount: money.FromBaht(200_000)}},
+		}
+		calculator := newDefaultTaxCalculator()
+		res, err := calculator.grossUp(req)
+		if err != nil {
+			t.Fatalf("unable to gross up: %v", err)
+		}
+
+		if res.NetIncome < req.NetIncome || res.Tax <= MAX_ALTERNATIVE_TAX_EXEMPT {
+			t.Errorf("invalid gross up: got %+v", res)
+		}
+		lower := calculator.calculate(req.taxInformation(res.GrossIncome - 1))
+		if res.GrossIncome-1-lower.tax >= req.NetIncome {
+			t.Errorf("gross income is not the lowest: got %v", res.GrossIncome)
+		}
+	})
+
+	t.Run("given target net income above the limit should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/gross-up", strings.NewReader(`{"netIncome": 90000000000000000}`))
+		})
+
+		err := (&Handler{}).GrossUp(c)
+		if err != nil {
+			t.Errorf("unable to gross up: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given brackets that never leave the target net income should return error", func(t *testing.T) {
+		calculator := NewTaxCalculator(TaxRules{
+			TaxYear:           DEFAULT_TAX_YEAR,
+			PersonalDeduction: DEFAULT_PERSONAL_DEDUCTION,
+			Caps:              CapDefaults(),
+			Brackets: []TaxBracket{
+				{MinIncome: 0, MaxIncome: money.FromBaht(100_000), Rate: 0},
+				{MinIncome: money.FromBaht(100_000), MaxIncome: money.Unlimited, Rate: money.RateScale},
+			},
+		})
+
+		_, err := calculator.grossUp(GrossUpRequest{NetIncome: money.FromBaht(500_000)})
+		if !errors.Is(err, ErrNetIncomeUnreachable) {
+			t.Errorf("invalid error: got %v want %v", err, ErrNetIncomeUnreachable)
+		}
+	})
+
+	t.Run("given zero net income should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/gross-up", strings.NewReader(`{"netIncome": 0}`))
+		})
+
+		err := (&Handler{}).GrossUp(c)
+		if err != nil {
+			t.Errorf("unable to gross up: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,75 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+const (
+	PERIOD_FULL_YEAR = "full-year"
+	PERIOD_HALF_YEAR = "half-year"
+)
+
+// halfYearIncomeTypes are the incomes filed on the half-year (PND 94) return.
+var halfYearIncomeTypes = map[string]bool{
+	"40(5)": true,
+	"40(6)": true,
+	"40(7)": true,
+	"40(8)": true,
+}
+
+// validPeriod checks that the period is one of the accepted periods, the full year by default.
+func validPeriod(period string) error {
+	switch period {
+	case "", PERIOD_FULL_YEAR, PERIOD_HALF_YEAR:
+		return nil
+	}
+	return fmt.Errorf("invalid period %q", period)
+}
+
+func validatePeriod(info TaxInformation) error {
+	if err := validPeriod(info.Period); err != nil {
+		return err
+	}
+	if !info.isHalfYear() {
+		return nil
+	}
+
+	if len(info.Incomes) == 0 {
+		return errors.New("half-year calculation requires incomes")
+	}
+	for _, income := range info.Incomes {
+		if !halfYearIncomeTypes[income.IncomeType] {
+			return fmt.Errorf("income %s is not filed on the half-year return", income.IncomeType)
+		}
+	}
+	if info.InterimTax != 0 {
+		return errors.New("interimTax can only be credited in a full-year calculation")
+	}
+	return nil
+}
+
+// halfYear returns a calculator for the half-year (PND 94) return, which grants half of
+// the personal deduction and of every allowance and expense cap. Percentage limits,
+// the keys ending in _PERCENT, are kept as they are.
+func (t TaxCalculator) halfYear() TaxCalculator {
+	rules := t.rules
+	rules.HalfYear = true
+	rules.PersonalDeduction = rules.PersonalDeduction.Div(2)
+
+	rules.Caps = map[string]money.Money{}
+	for key, value := range CapDefaults() {
+		if v, ok := t.rules.Caps[key]; ok {
+			value = v
+		}
+		if !strings.HasSuffix(key, "_PERCENT") {
+			value = value.Div(2)
+		}
+		rules.Caps[key] = value
+	}
+
+	return NewTaxCalculator(rules)
+}
@@ -0,0 +1,81 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestHalfYearCalculation(t *testing.T) {
+	calculateTax := func(t *testing.T, reqJSON string) (*httptest.ResponseRecorder, *TaxCalculationResponse) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(reqJSON))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		res := &TaxCalculationResponse{}
+		if rec.Code == http.StatusOK {
+			err = json.Unmarshal(rec.Body.Bytes(), res)
+			if err != nil {
+				t.Errorf("unable to unmarshal response: %v", err)
+			}
+		}
+		return rec, res
+	}
+
+	t.Run("given half-year period should halve the allowances", func(t *testing.T) {
+		rec, res := calculateTax(t, `{
+			"period": "half-year",
+			"incomes": [{ "incomeType": "40(8)", "amount": 600000.0 }],
+			"allowances": [{ "allowanceType": "child", "birthYear": 2560 }]
+		}`)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		// 600,000 - 360,000 - 30,000 - 15,000 = 195,000
+		if res.Tax != money.FromBaht(4_500) {
+			t.Errorf("invalid interim tax: got %v want %v", res.Tax, money.FromBaht(4_500))
+		}
+	})
+
+	t.Run("given interim tax should credit it in the full-year calculation", func(t *testing.T) {
+		rec, res := calculateTax(t, `{
+			"incomes": [{ "incomeType": "40(8)", "amount": 1200000.0 }],
+			"allowances": [{ "allowanceType": "child", "birthYear": 2560 }],
+			"wht": 1000.0,
+			"interimTax": 4500.0
+		}`)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		// 1,200,000 - 720,000 - 60,000 - 30,000 = 390,000, tax 24,000
+		if res.Tax != money.FromBaht(18_500) {
+			t.Errorf("invalid tax: got %v want %v", res.Tax, money.FromBaht(18_500))
+		}
+	})
+
+	t.Run("given salary in half-year period should return 400", func(t *testing.T) {
+		rec, _ := calculateTax(t, `{
+			"period": "half-year",
+			"incomes": [{ "incomeType": "40(1)", "amount": 600000.0 }]
+		}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
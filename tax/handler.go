@@ -1,39 +1,63 @@
 package tax
 
 import (
+	"database/sql"
 	"encoding/csv"
 	"errors"
+	"fmt"
 	"net/http"
 	"strconv"
+	"time"
 
+	"github.com/bytesbanana/assessment-tax/money"
 	"github.com/bytesbanana/assessment-tax/postgres"
 	"github.com/labstack/echo/v4"
 )
 
-var ACCEPT_ALLOWANCE_TYPES = map[string]string{
-	"k-receipt": "k-receipt",
-	"donation":  "donation",
-}
-
 type (
 	Allowance struct {
-		AllowanceType string  `json:"allowanceType"`
-		Amount        float64 `json:"amount"`
+		AllowanceType string      `json:"allowanceType"`
+		Amount        money.Money `json:"amount"`
+		// Category is the donation category, e.g. "education" donations count twice.
+		Category string `json:"category,omitempty"`
+		// BirthYear is the Buddhist era birth year of a claimed child.
+		BirthYear int `json:"birthYear,omitempty"`
+		// Age is the age of a claimed parent.
+		Age int `json:"age,omitempty"`
 	}
 
 	TaxLevel struct {
-		Level string  `json:"level"`
-		Tax   float64 `json:"tax"`
+		Level string      `json:"level"`
+		Tax   money.Money `json:"tax"`
 	}
 
 	TaxCalculationResponse struct {
-		Tax       float64    `json:"tax"`
-		TaxRefund float64    `json:"taxRefund"`
-		TaxLevel  []TaxLevel `json:"taxLevel"`
+		// AssessmentID identifies the stored assessment of the calculation.
+		AssessmentID int         `json:"assessmentId,omitempty"`
+		Tax          money.Money `json:"tax"`
+		TaxRefund    money.Money `json:"taxRefund"`
+		TaxLevel     []TaxLevel  `json:"taxLevel"`
+		// ProgressiveTax and AlternativeTax are the tax before WHT under each method,
+		// TaxMethod tells which of them was applied.
+		ProgressiveTax money.Money         `json:"progressiveTax"`
+		AlternativeTax money.Money         `json:"alternativeTax"`
+		TaxMethod      string              `json:"taxMethod"`
+		Income         *IncomeDetails      `json:"income,omitempty"`
+		Donations      []DonationDeduction `json:"donations,omitempty"`
+		Explanation    []ExplanationStep   `json:"explanation,omitempty"`
+		Instalments    []Instalment        `json:"instalments,omitempty"`
+		Dividend       *DividendDetails    `json:"dividend,omitempty"`
 	}
 
 	Storer interface {
-		GetTaxConfig(key string) (*postgres.TaxConfig, error)
+		GetTaxRuleSet(taxYear int) (*postgres.TaxRuleSet, error)
+		GetTaxConfig(taxYear int, key string, at time.Time) (*postgres.TaxConfig, error)
+		ListTaxConfigs(taxYear int) ([]postgres.TaxConfig, error)
+		GetTaxBrackets(taxYear int) ([]postgres.TaxBracket, error)
+		CreateTaxAssessment(assessment postgres.TaxAssessment) (*postgres.TaxAssessment, error)
+		CreateTaxAssessments(assessments []postgres.TaxAssessment) ([]postgres.TaxAssessment, error)
+		GetTaxAssessment(id int) (*postgres.TaxAssessment, error)
+		ListTaxAssessments(filter postgres.TaxAssessmentFilter) ([]postgres.TaxAssessment, error)
 	}
 
 	Handler struct {
@@ -45,6 +69,8 @@ type (
 	}
 )
 
+var ErrTaxYearNotSupported = errors.New("tax year is not supported")
+
 func New(db Storer) *Handler {
 
 	return &Handler{
@@ -52,15 +78,6 @@ func New(db Storer) *Handler {
 	}
 }
 
-func validateAllowance(allowances []Allowance) error {
-	for _, al := range allowances {
-		if ACCEPT_ALLOWANCE_TYPES[al.AllowanceType] == "" {
-			return errors.New("invalid allowance type")
-		}
-	}
-	return nil
-}
-
 func (h *Handler) CalculateTax(c echo.Context) error {
 
 	var req TaxInformation
@@ -71,37 +88,142 @@ func (h *Handler) CalculateTax(c echo.Context) error {
 		})
 	}
 
-	err = validateAllowance(req.Allowances)
+	explain, err := boolQueryParam(c, "explain")
 	if err != nil {
 		return c.JSON(http.StatusBadRequest, &Err{
-			Message: "invalid  allowance type",
+			Message: err.Error(),
+		})
+	}
+	withInstalments, err := boolQueryParam(c, "instalments")
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
 		})
 	}
 
-	personalDeducation := h.getConfigValue("PERSONAL_DEDUCTION", 60_000)
-	maxKReceiptDeduction := h.getConfigValue("MAX_K_RECEIPT_DEDUCTION", 50_000)
-	taxCalculator := NewTaxCalculator(personalDeducation, maxKReceiptDeduction)
+	err = validateTaxInformation(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	taxCalculator, err := h.newTaxCalculator(req.taxYear(), req.taxDate())
+	if err != nil {
+		return taxRulesError(c, req.taxYear(), err)
+	}
+	if req.isHalfYear() {
+		taxCalculator = taxCalculator.halfYear()
+	}
 
 	taxDetails := taxCalculator.calculate(req)
 
-	return c.JSON(http.StatusOK, TaxCalculationResponse{
-		Tax:       taxDetails.tax,
-		TaxRefund: taxDetails.taxRefund,
-		TaxLevel:  taxDetails.taxLevel,
+	res := TaxCalculationResponse{
+		Tax:            taxDetails.tax,
+		TaxRefund:      taxDetails.taxRefund,
+		TaxLevel:       taxDetails.taxLevel,
+		ProgressiveTax: taxDetails.progressiveTax,
+		AlternativeTax: taxDetails.alternativeTax,
+		TaxMethod:      taxDetails.taxMethod,
+		Income:         taxDetails.income,
+		Donations:      taxDetails.donations,
+		Dividend:       taxDetails.dividend,
+	}
+	if explain {
+		res.Explanation = taxCalculator.explain(req, taxDetails)
+	}
+	if withInstalments {
+		// Returns below the instalment threshold are paid in full, so the plan is left out.
+		res.Instalments, _ = instalments(taxDetails.tax, filingDeadline(req.taxYear(), req.isHalfYear()))
+	}
+
+	err = h.storeAssessment(ASSESSMENT_SOURCE_API, requestAPIKeyID(c), taxCalculator.rules, req, &res)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to store assessment",
+		})
+	}
+
+	return c.JSON(http.StatusOK, res)
+}
+
+func boolQueryParam(c echo.Context, name string) (bool, error) {
+	if c.QueryParam(name) == "" {
+		return false, nil
+	}
+	value, err := strconv.ParseBool(c.QueryParam(name))
+	if err != nil {
+		return false, fmt.Errorf("invalid %s parameter", name)
+	}
+	return value, nil
+}
+
+// newTaxCalculator resolves the rule set of the tax year with the config values in force
+// on the tax date. Config values missing from the rule set fall back to the statutory
+// defaults, but a tax year without a rule set or valid brackets is rejected.
+func (h *Handler) newTaxCalculator(taxYear int, taxDate time.Time) (TaxCalculator, error) {
+	ruleSet, err := h.storer.GetTaxRuleSet(taxYear)
+	if errors.Is(err, sql.ErrNoRows) {
+		return TaxCalculator{}, ErrTaxYearNotSupported
+	}
+	if err != nil {
+		return TaxCalculator{}, err
+	}
+
+	brackets, err := h.getTaxBrackets(taxYear)
+	if err != nil {
+		return TaxCalculator{}, err
+	}
+
+	caps := map[string]money.Money{}
+	for key, defaultValue := range CapDefaults() {
+		caps[key] = h.getConfigValue(taxYear, taxDate, key, defaultValue)
+	}
+
+	return NewTaxCalculator(TaxRules{
+		TaxYear:           taxYear,
+		RuleSet:           ruleSet.Name,
+		TaxDate:           taxDate.Format(DATE_FORMAT),
+		PersonalDeduction: h.getConfigValue(taxYear, taxDate, "PERSONAL_DEDUCTION", DEFAULT_PERSONAL_DEDUCTION),
+		Caps:              caps,
+		Brackets:          brackets,
+	}), nil
+}
+
+func taxRulesError(c echo.Context, taxYear int, err error) error {
+	if errors.Is(err, ErrTaxYearNotSupported) {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: fmt.Sprintf("tax year %d is not supported", taxYear),
+		})
+	}
+
+	return c.JSON(http.StatusInternalServerError, &Err{
+		Message: "unable to load tax rules",
 	})
 }
 
-func (h *Handler) getConfigValue(configName string, defaultValue float64) float64 {
+func (h *Handler) getConfigValue(taxYear int, taxDate time.Time, configName string, defaultValue money.Money) money.Money {
 	result := defaultValue
 
-	config, err := h.storer.GetTaxConfig(configName)
-	if err == nil {
+	config, err := h.storer.GetTaxConfig(taxYear, configName, taxDate)
+	if err == nil && config != nil {
 		result = config.Value
 	}
 
 	return result
 }
 
+// getTaxBrackets loads the bracket set of the tax year, a year whose brackets cannot be
+// loaded or are invalid is an error rather than being taxed on another year's brackets.
+func (h *Handler) getTaxBrackets(taxYear int) ([]TaxBracket, error) {
+	rows, err := h.storer.GetTaxBrackets(taxYear)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewTaxBrackets(rows)
+}
+
 func (h *Handler) CalculateTaxFromTaxFile(c echo.Context) error {
 	// Source
 	taxFile, err := c.FormFile("taxFile")
@@ -128,11 +250,13 @@ func (h *Handler) CalculateTaxFromTaxFile(c echo.Context) error {
 	}
 	headers := records[0]
 
-	personalDeducation := h.getConfigValue("PERSONAL_DEDUCTION", 60_000)
-	maxKReceiptDeduction := h.getConfigValue("MAX_K_RECEIPT_DEDUCTION", 50_000)
-	taxCalculator := NewTaxCalculator(personalDeducation, maxKReceiptDeduction)
-
-	taxDetails := []CalculateTaxDetails{}
+	type rulesKey struct {
+		taxYear int
+		taxDate string
+	}
+	taxCalculators := map[rulesKey]TaxCalculator{}
+	taxes := []TaxCalculationResponse{}
+	assessments := []postgres.TaxAssessment{}
 
 	for _, row := range records[1:] {
 		taxInfo := TaxInformation{
@@ -144,7 +268,28 @@ func (h *Handler) CalculateTaxFromTaxFile(c echo.Context) error {
 			},
 		}
 		for ic, col := range row {
-			data, err := strconv.ParseFloat(col, 64)
+			if headers[ic] == "taxYear" {
+				taxYear, err := strconv.Atoi(col)
+				if err != nil {
+					return c.JSON(http.StatusBadRequest, &Err{
+						Message: "invalid data type in the csv file",
+					})
+				}
+				taxInfo.TaxYear = taxYear
+				continue
+			}
+			if headers[ic] == "taxDate" {
+				_, err := time.Parse(DATE_FORMAT, col)
+				if err != nil {
+					return c.JSON(http.StatusBadRequest, &Err{
+						Message: "invalid data type in the csv file",
+					})
+				}
+				taxInfo.TaxDate = col
+				continue
+			}
+
+			data, err := money.Parse(col)
 			if err != nil {
 				return c.JSON(http.StatusBadRequest, &Err{
 					Message: "invalid data type in the csv file",
@@ -160,18 +305,54 @@ func (h *Handler) CalculateTaxFromTaxFile(c echo.Context) error {
 			}
 		}
 
-		taxDetails = append(taxDetails, taxCalculator.calculate(taxInfo))
-	}
+		if err := validateTaxDate(taxInfo.taxYear(), taxInfo.TaxDate); err != nil {
+			return c.JSON(http.StatusBadRequest, &Err{
+				Message: err.Error(),
+			})
+		}
 
-	taxes := []TaxCalculationResponse{}
+		taxYear, taxDate := taxInfo.taxYear(), taxInfo.taxDate()
+		key := rulesKey{taxYear: taxYear, taxDate: taxDate.Format(DATE_FORMAT)}
+		taxCalculator, ok := taxCalculators[key]
+		if !ok {
+			taxCalculator, err = h.newTaxCalculator(taxYear, taxDate)
+			if err != nil {
+				return taxRulesError(c, taxYear, err)
+			}
+			taxCalculators[key] = taxCalculator
+		}
 
-	for _, td := range taxDetails {
-		taxes = append(taxes, TaxCalculationResponse{
-			Tax:       td.tax,
-			TaxRefund: td.taxRefund,
-			TaxLevel:  td.taxLevel,
+		td := taxCalculator.calculate(taxInfo)
+		res := TaxCalculationResponse{
+			Tax:            td.tax,
+			TaxRefund:      td.taxRefund,
+			TaxLevel:       td.taxLevel,
+			ProgressiveTax: td.progressiveTax,
+			AlternativeTax: td.alternativeTax,
+			TaxMethod:      td.taxMethod,
+		}
+
+		assessment, err := newTaxAssessment(ASSESSMENT_SOURCE_CSV, requestAPIKeyID(c), taxCalculator.rules, taxInfo, res)
+		if err != nil {
+			return c.JSON(http.StatusInternalServerError, &Err{
+				Message: "unable to store assessment",
+			})
+		}
+		taxes = append(taxes, res)
+		assessments = append(assessments, assessment)
+	}
+
+	// The rows are stored together so a failed upload can be retried without storing
+	// any of its rows twice.
+	stored, err := h.storer.CreateTaxAssessments(assessments)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &Err{
+			Message: "unable to store assessment",
 		})
 	}
+	for i := range taxes {
+		taxes[i].AssessmentID = stored[i].ID
+	}
 
 	return c.JSON(http.StatusOK, struct {
 		Taxes []TaxCalculationResponse `json:"taxes"`
@@ -0,0 +1,246 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	FILING_SEPARATE = "separate"
+	FILING_COMBINED = "combined"
+
+	RETURN_TAXPAYER = "taxpayer"
+	RETURN_SPOUSE   = "spouse"
+	RETURN_JOINT    = "joint"
+)
+
+type (
+	// HouseholdRequest describes a married couple, each spouse as their own TaxInformation.
+	// The tax year of the request applies to both of them, a spouse may leave it out or
+	// give the same year.
+	HouseholdRequest struct {
+		TaxYear  int            `json:"taxYear"`
+		Taxpayer TaxInformation `json:"taxpayer"`
+		Spouse   TaxInformation `json:"spouse"`
+	}
+
+	HouseholdReturn struct {
+		Filer     string      `json:"filer"`
+		Tax       money.Money `json:"tax"`
+		TaxRefund money.Money `json:"taxRefund"`
+		// Liability is the tax borne on the return before WHT and interim tax are credited.
+		Liability money.Money `json:"liability"`
+	}
+
+	HouseholdScenario struct {
+		Filing    string            `json:"filing"`
+		Liability money.Money       `json:"liability"`
+		Returns   []HouseholdReturn `json:"returns"`
+	}
+
+	HouseholdResponse struct {
+		Separate HouseholdScenario `json:"separate"`
+		Combined HouseholdScenario `json:"combined"`
+		// Cheaper is the filing with the lower liability, separate filing on a tie.
+		Cheaper string `json:"cheaper"`
+	}
+)
+
+func (t *TaxInformation) hasIncome() bool {
+	return t.grossIncome() > 0 || len(t.Dividends) > 0
+}
+
+func (t *TaxInformation) claimsSpouse() bool {
+	return len(t.allowancesByType("spouse")) > 0
+}
+
+// withSpouseAllowance claims the spouse allowance, unless it is claimed already.
+func (t TaxInformation) withSpouseAllowance() TaxInformation {
+	if t.claimsSpouse() {
+		return t
+	}
+	t.Allowances = append(append([]Allowance{}, t.Allowances...), Allowance{AllowanceType: "spouse"})
+	return t
+}
+
+func (r HouseholdRequest) validate() error {
+	if r.Taxpayer.isHalfYear() || r.Spouse.isHalfYear() {
+		return errors.New("household calculation is for the full-year return only")
+	}
+	household := r.info()
+	if err := sameTaxYear(RETURN_TAXPAYER, r.Taxpayer, household.taxYear()); err != nil {
+		return err
+	}
+	if err := sameTaxYear(RETURN_SPOUSE, r.Spouse, household.taxYear()); err != nil {
+		return err
+	}
+
+	taxpayer, spouse := r.Taxpayer, r.Spouse
+	taxpayer.TaxYear, spouse.TaxYear = r.TaxYear, r.TaxYear
+	if err := validateTaxInformation(taxpayer); err != nil {
+		return fmt.Errorf("taxpayer: %w", err)
+	}
+	if err := validateTaxInformation(spouse); err != nil {
+		return fmt.Errorf("spouse: %w", err)
+	}
+	if _, err := shared("dividendMethod", r.Taxpayer.DividendMethod, r.Spouse.DividendMethod); err != nil {
+		return err
+	}
+	if _, err := shared("taxDate", r.Taxpayer.TaxDate, r.Spouse.TaxDate); err != nil {
+		return err
+	}
+	if !r.Taxpayer.hasIncome() || !r.Spouse.hasIncome() {
+		return nil
+	}
+	if r.Taxpayer.claimsSpouse() || r.Spouse.claimsSpouse() {
+		return errors.New("spouse allowance only applies to a spouse without income")
+	}
+	if (len(r.Taxpayer.Incomes) == 0) != (len(r.Spouse.Incomes) == 0) {
+		return errors.New("taxpayer and spouse must both give either incomes or totalIncome")
+	}
+	return nil
+}
+
+// sameTaxYear rejects a tax year of one spouse other than the household's.
+func sameTaxYear(filer string, info TaxInformation, taxYear int) error {
+	if info.TaxYear != 0 && info.TaxYear != taxYear {
+		return fmt.Errorf("%s: taxYear %d must match the household taxYear %d", filer, info.TaxYear, taxYear)
+	}
+	return nil
+}
+
+// shared returns the value of a field that applies to both spouses, either of them may
+// leave it out but they must not give different values.
+func shared(field string, taxpayer string, spouse string) (string, error) {
+	if taxpayer != "" && spouse != "" && taxpayer != spouse {
+		return "", fmt.Errorf("taxpayer and spouse must give the same %s", field)
+	}
+	if taxpayer != "" {
+		return taxpayer, nil
+	}
+	return spouse, nil
+}
+
+// info returns the fields that apply to the whole household.
+func (r HouseholdRequest) info() TaxInformation {
+	dividendMethod, _ := shared("dividendMethod", r.Taxpayer.DividendMethod, r.Spouse.DividendMethod)
+	taxDate, _ := shared("taxDate", r.Taxpayer.TaxDate, r.Spouse.TaxDate)
+	return TaxInformation{
+		TaxYear:        r.TaxYear,
+		TaxDate:        taxDate,
+		DividendMethod: dividendMethod,
+	}
+}
+
+// combined merges both spouses into a joint return. The spouse's personal deduction is
+// granted as the spouse allowance and the allowance caps apply to the joint return.
+func (r HouseholdRequest) combined() TaxInformation {
+	taxpayer, spouse := r.Taxpayer, r.Spouse
+
+	joint := r.info()
+	joint.TotalIncome = taxpayer.TotalIncome + spouse.TotalIncome
+	joint.Incomes = append(append([]Income{}, taxpayer.Incomes...), spouse.Incomes...)
+	joint.WHT = taxpayer.WHT + spouse.WHT
+	joint.InterimTax = taxpayer.InterimTax + spouse.InterimTax
+	joint.Dividends = append(append([]Dividend{}, taxpayer.Dividends...), spouse.Dividends...)
+	for _, allowance := range append(append([]Allowance{}, taxpayer.Allowances...), spouse.Allowances...) {
+		if allowance.AllowanceType != "spouse" {
+			joint.Allowances = append(joint.Allowances, allowance)
+		}
+	}
+	if len(joint.Incomes) > 0 {
+		joint.TotalIncome = 0
+	} else {
+		joint.Incomes = nil
+	}
+	return joint.withSpouseAllowance()
+}
+
+// liability is the tax borne on the return, including the final dividend withholding
+// and net of the dividend tax credit.
+func (d CalculateTaxDetails) liability() money.Money {
+	if d.dividend == nil {
+		return d.assessedTax()
+	}
+	if d.dividend.Method == DIVIDEND_METHOD_TAX_CREDIT {
+		return d.dividend.TaxCreditTax
+	}
+	return d.dividend.FinalWithholdingTax
+}
+
+func (t TaxCalculator) householdReturn(filer string, info TaxInformation) HouseholdReturn {
+	details := t.calculate(info)
+	return HouseholdReturn{
+		Filer:     filer,
+		Tax:       details.tax,
+		TaxRefund: details.taxRefund,
+		Liability: details.liability(),
+	}
+}
+
+func newHouseholdScenario(filing string, returns ...HouseholdReturn) HouseholdScenario {
+	scenario := HouseholdScenario{Filing: filing, Returns: returns}
+	for _, ret := range returns {
+		scenario.Liability += ret.Liability
+	}
+	return scenario
+}
+
+// household compares separate returns with a joint return. A spouse without income does
+// not file separately, the other spouse claims the spouse allowance instead.
+func (t TaxCalculator) household(req HouseholdRequest) HouseholdResponse {
+	taxpayer, spouse := req.Taxpayer, req.Spouse
+	taxpayer.TaxYear, spouse.TaxYear = req.TaxYear, req.TaxYear
+
+	separate := []HouseholdReturn{}
+	switch {
+	case !spouse.hasIncome():
+		separate = append(separate, t.householdReturn(RETURN_TAXPAYER, taxpayer.withSpouseAllowance()))
+	case !taxpayer.hasIncome():
+		separate = append(separate, t.householdReturn(RETURN_SPOUSE, spouse.withSpouseAllowance()))
+	default:
+		separate = append(separate,
+			t.householdReturn(RETURN_TAXPAYER, taxpayer),
+			t.householdReturn(RETURN_SPOUSE, spouse),
+		)
+	}
+
+	res := HouseholdResponse{
+		Separate: newHouseholdScenario(FILING_SEPARATE, separate...),
+		Combined: newHouseholdScenario(FILING_COMBINED, t.householdReturn(RETURN_JOINT, req.combined())),
+		Cheaper:  FILING_SEPARATE,
+	}
+	if res.Combined.Liability < res.Separate.Liability {
+		res.Cheaper = FILING_COMBINED
+	}
+	return res
+}
+
+func (h *Handler) CalculateHousehold(c echo.Context) error {
+	var req HouseholdRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	err = req.validate()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	info := req.info()
+	taxCalculator, err := h.newTaxCalculator(info.taxYear(), info.taxDate())
+	if err != nil {
+		return taxRulesError(c, info.taxYear(), err)
+	}
+
+	return c.JSON(http.StatusOK, taxCalculator.household(req))
+}
@@ -0,0 +1,185 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestHousehold(t *testing.T) {
+	t.Run("given spouse without income should claim the spouse allowance", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/household", strings.NewReader(`{
+				"taxpayer": { "totalIncome": 500000.0 },
+				"spouse": {}
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateHousehold(c)
+		if err != nil {
+			t.Errorf("unable to calculate household: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := &HouseholdResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		// 500,000 - 60,000 - 60,000 = 380,000, tax 23,000
+		if len(res.Separate.Returns) != 1 || res.Separate.Returns[0].Filer != RETURN_TAXPAYER {
+			t.Errorf("invalid separate returns: got %+v", res.Separate.Returns)
+		}
+		if res.Separate.Liability != money.FromBaht(23_000) || res.Combined.Liability != money.FromBaht(23_000) {
+			t.Errorf("invalid liability: got %v and %v want %v", res.Separate.Liability, res.Combined.Liability, money.FromBaht(23_000))
+		}
+		if res.Cheaper != FILING_SEPARATE {
+			t.Errorf("invalid cheaper filing: got %v want %v", res.Cheaper, FILING_SEPARATE)
+		}
+	})
+
+	t.Run("given both spouses earning should file separately", func(t *testing.T) {
+		res := newDefaultTaxCalculator().household(HouseholdRequest{
+			Taxpayer: TaxInformation{TotalIncome: money.FromBaht(500_000)},
+			Spouse:   TaxInformation{TotalIncome: money.FromBaht(300_000)},
+		})
+
+		// separate 29,000 + 9,000, combined 800,000 - 120,000 = 680,000 taxed 62,000
+		if res.Separate.Liability != money.FromBaht(38_000) {
+			t.Errorf("invalid separate liability: got %v want %v", res.Separate.Liability, money.FromBaht(38_000))
+		}
+		if res.Combined.Liability != money.FromBaht(62_000) {
+			t.Errorf("invalid combined liability: got %v want %v", res.Combined.Liability, money.FromBaht(62_000))
+		}
+		if res.Cheaper != FILING_SEPARATE {
+			t.Errorf("invalid cheaper filing: got %v want %v", res.Cheaper, FILING_SEPARATE)
+		}
+	})
+
+	t.Run("given spouse earning below the personal deduction should file combined", func(t *testing.T) {
+		res := newDefaultTaxCalculator().household(HouseholdRequest{
+			Taxpayer: TaxInformation{TotalIncome: money.FromBaht(500_000)},
+			Spouse:   TaxInformation{TotalIncome: money.FromBaht(30_000)},
+		})
+
+		// separate 29,000, combined 530,000 - 120,000 = 410,000 taxed 26,000
+		if res.Combined.Liability != money.FromBaht(26_000) {
+			t.Errorf("invalid combined liability: got %v want %v", res.Combined.Liability, money.FromBaht(26_000))
+		}
+		if res.Cheaper != FILING_COMBINED {
+			t.Errorf("invalid cheaper filing: got %v want %v", res.Cheaper, FILING_COMBINED)
+		}
+	})
+
+	t.Run("given spouse allowance while both spouses earn should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/household", strings.NewReader(`{
+				"taxpayer": { "totalIncome": 500000.0, "allowances": [{ "allowanceType": "spouse" }] },
+				"spouse": { "totalIncome": 300000.0 }
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateHousehold(c)
+		if err != nil {
+			t.Errorf("unable to calculate household: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given spouse dividend method conflicting with the taxpayer's should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/household", strings.NewReader(`{
+				"taxpayer": { "totalIncome": 500000.0, "dividendMethod": "tax-credit" },
+				"spouse": { "totalIncome": 300000.0, "dividendMethod": "final-withholding" }
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateHousehold(c)
+		if err != nil {
+			t.Errorf("unable to calculate household: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given dividend method from the spouse only should apply it to the combined return", func(t *testing.T) {
+		req := HouseholdRequest{
+			Taxpayer: TaxInformation{TotalIncome: money.FromBaht(500_000)},
+			Spouse: TaxInformation{
+				TotalIncome:    money.FromBaht(300_000),
+				Dividends:      []Dividend{{Amount: money.FromBaht(100_000), CorporateTaxRate: money.MustParseRate("0.20")}},
+				DividendMethod: DIVIDEND_METHOD_FINAL_WITHHOLDING,
+			},
+		}
+		if err := req.validate(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if got := req.combined().DividendMethod; got != DIVIDEND_METHOD_FINAL_WITHHOLDING {
+			t.Errorf("invalid dividend method: got %q want %q", got, DIVIDEND_METHOD_FINAL_WITHHOLDING)
+		}
+	})
+
+	t.Run("given spouse tax date conflicting with the taxpayer's should return error", func(t *testing.T) {
+		req := HouseholdRequest{
+			Taxpayer: TaxInformation{TotalIncome: money.FromBaht(500_000), TaxDate: "2024-06-01"},
+			Spouse:   TaxInformation{TotalIncome: money.FromBaht(300_000), TaxDate: "2024-12-01"},
+		}
+
+		if err := req.validate(); err == nil {
+			t.Errorf("expected error for conflicting tax dates")
+		}
+	})
+
+	t.Run("given spouse tax year different from the household's should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/household", strings.NewReader(`{
+				"taxYear": 2567,
+				"taxpayer": { "totalIncome": 500000.0, "taxYear": 2567 },
+				"spouse": { "totalIncome": 300000.0, "taxYear": 2566 }
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateHousehold(c)
+		if err != nil {
+			t.Errorf("unable to calculate household: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given spouse tax year without a household tax year should return error unless it is the default", func(t *testing.T) {
+		req := HouseholdRequest{
+			Taxpayer: TaxInformation{TotalIncome: money.FromBaht(500_000), TaxYear: DEFAULT_TAX_YEAR},
+			Spouse:   TaxInformation{TotalIncome: money.FromBaht(300_000)},
+		}
+		if err := req.validate(); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+
+		req.Spouse.TaxYear = DEFAULT_TAX_YEAR - 1
+		if err := req.validate(); err == nil {
+			t.Errorf("expected error for a tax year other than the household's")
+		}
+	})
+}
@@ -0,0 +1,301 @@
+package tax
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"sort"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+const (
+	// MOST_AFFECTED_COUNT is the number of assessments listed in an impact report.
+	MOST_AFFECTED_COUNT = 10
+
+	// An impact analysis recomputes the latest DEFAULT_IMPACT_SAMPLE assessments unless
+	// asked for another sample size, up to MAX_IMPACT_SAMPLE.
+	DEFAULT_IMPACT_SAMPLE = 1_000
+	MAX_IMPACT_SAMPLE     = 10_000
+)
+
+var ErrUnknownConfigKey = errors.New("unknown config key")
+
+type (
+	// ConfigChange is a proposed value of a config key of a tax year. It changes the base
+	// value unless EffectiveFrom is set, then it is scheduled from EffectiveFrom until
+	// EffectiveTo, both inclusive, or open-ended when EffectiveTo is nil.
+	ConfigChange struct {
+		TaxYear       int
+		Key           string
+		Value         money.Money
+		EffectiveFrom *time.Time
+		EffectiveTo   *time.Time
+	}
+
+	// configValues are the base value of a config key in a tax year and its scheduled values.
+	configValues struct {
+		base      money.Money
+		schedules []postgres.TaxConfig
+	}
+
+	// AssessmentImpact compares the tax of a stored assessment, net of its refund,
+	// under the current and the proposed rules.
+	AssessmentImpact struct {
+		AssessmentID int         `json:"assessmentId"`
+		CurrentTax   money.Money `json:"currentTax"`
+		ProposedTax  money.Money `json:"proposedTax"`
+		Delta        money.Money `json:"delta"`
+	}
+
+	// ImpactReport sums the impacts of a change. CurrentValue is the value it replaces, the
+	// base value or the value in force when the scheduled change starts.
+	ImpactReport struct {
+		TaxYear       int                `json:"taxYear"`
+		Key           string             `json:"key"`
+		CurrentValue  money.Money        `json:"currentValue"`
+		ProposedValue money.Money        `json:"proposedValue"`
+		Assessments   int                `json:"assessments"`
+		Increased     int                `json:"increased"`
+		Decreased     int                `json:"decreased"`
+		CurrentTotal  money.Money        `json:"currentTotal"`
+		ProposedTotal money.Money        `json:"proposedTotal"`
+		TotalDelta    money.Money        `json:"totalDelta"`
+		AverageDelta  money.Money        `json:"averageDelta"`
+		MostAffected  []AssessmentImpact `json:"mostAffected"`
+	}
+)
+
+// inForce returns the config in force on the date like Storer.GetTaxConfig, the latest
+// scheduled value covering it or nil for the base value.
+func (v configValues) inForce(date time.Time) *postgres.TaxConfig {
+	var latest *postgres.TaxConfig
+	for i, schedule := range v.schedules {
+		if date.Before(*schedule.EffectiveFrom) || (schedule.EffectiveTo != nil && date.After(*schedule.EffectiveTo)) {
+			continue
+		}
+		if latest == nil || schedule.EffectiveFrom.After(*latest.EffectiveFrom) {
+			latest = &v.schedules[i]
+		}
+	}
+	return latest
+}
+
+// valueOn returns the value in force on the date.
+func (v configValues) valueOn(date time.Time) money.Money {
+	if config := v.inForce(date); config != nil {
+		return config.Value
+	}
+	return v.base
+}
+
+// covers reports whether the changed value would be in force on the date. A base value
+// is overridden by the scheduled values and a new schedule by a later one.
+func (c ConfigChange) covers(values configValues, date time.Time) bool {
+	inForce := values.inForce(date)
+	if c.EffectiveFrom == nil {
+		return inForce == nil
+	}
+	if date.Before(*c.EffectiveFrom) || (c.EffectiveTo != nil && date.After(*c.EffectiveTo)) {
+		return false
+	}
+	return inForce == nil || !inForce.EffectiveFrom.After(*c.EffectiveFrom)
+}
+
+// configValues loads the base and the scheduled values of the key in the tax year.
+func (h *Handler) configValues(taxYear int, key string) (configValues, error) {
+	values := configValues{base: DEFAULT_PERSONAL_DEDUCTION}
+	if key != "PERSONAL_DEDUCTION" {
+		defaultValue, ok := CapDefaults()[key]
+		if !ok {
+			return values, ErrUnknownConfigKey
+		}
+		values.base = defaultValue
+	}
+
+	configs, err := h.storer.ListTaxConfigs(taxYear)
+	if err != nil {
+		return values, err
+	}
+	for _, config := range configs {
+		if config.Key != key {
+			continue
+		}
+		if config.EffectiveFrom == nil {
+			values.base = config.Value
+		} else {
+			values.schedules = append(values.schedules, config)
+		}
+	}
+	return values, nil
+}
+
+// assessmentTaxDate is the date the config values of a stored assessment were resolved on,
+// read from its rules. Assessments stored without it fall back to their input.
+func assessmentTaxDate(assessment postgres.TaxAssessment, info TaxInformation) time.Time {
+	var rules TaxRules
+	if json.Unmarshal(assessment.Rules, &rules) == nil {
+		if date, err := time.Parse(DATE_FORMAT, rules.TaxDate); err == nil {
+			return date
+		}
+	}
+	if info.TaxDate != "" || assessment.CreatedAt == nil {
+		return info.taxDate()
+	}
+	return DefaultTaxDate(info.taxYear(), *assessment.CreatedAt)
+}
+
+// withConfig returns a calculator with the config key set to value, the key is one
+// checked by configValues.
+func (t TaxCalculator) withConfig(key string, value money.Money) TaxCalculator {
+	rules := t.rules
+	if key == "PERSONAL_DEDUCTION" {
+		rules.PersonalDeduction = value
+		return NewTaxCalculator(rules)
+	}
+
+	rules.Caps = map[string]money.Money{}
+	for k, v := range t.rules.Caps {
+		rules.Caps[k] = v
+	}
+	rules.Caps[key] = value
+	return NewTaxCalculator(rules)
+}
+
+// netTax is the tax of the assessment input less its refund.
+func (t TaxCalculator) netTax(info TaxInformation) money.Money {
+	if info.isHalfYear() {
+		t = t.halfYear()
+	}
+	details := t.calculate(info)
+	return details.tax - details.taxRefund
+}
+
+// storedAssessments returns the latest limit assessments of the tax year, paging back
+// from the latest by ID.
+func (h *Handler) storedAssessments(taxYear int, limit int) ([]postgres.TaxAssessment, error) {
+	assessments := []postgres.TaxAssessment{}
+	filter := postgres.TaxAssessmentFilter{TaxYear: taxYear}
+	for len(assessments) < limit {
+		filter.Limit = min(MAX_ASSESSMENT_LIMIT, limit-len(assessments))
+		page, err := h.storer.ListTaxAssessments(filter)
+		if err != nil {
+			return nil, err
+		}
+
+		assessments = append(assessments, page...)
+		if len(page) < filter.Limit {
+			break
+		}
+		filter.BeforeID = page[len(page)-1].ID
+	}
+	return assessments, nil
+}
+
+// impactSample returns the number of assessments to recompute for the requested sample,
+// DEFAULT_IMPACT_SAMPLE when it is 0 and at most MAX_IMPACT_SAMPLE.
+func impactSample(sample int) int {
+	if sample <= 0 {
+		return DEFAULT_IMPACT_SAMPLE
+	}
+	return min(sample, MAX_IMPACT_SAMPLE)
+}
+
+// AnalyseImpact recomputes stored assessments of the tax year without applying the change,
+// each with the rules in force on its own tax date and with the change where it would be
+// in force on that date. limit caps the number of assessments recomputed, the latest
+// first, see impactSample.
+func (h *Handler) AnalyseImpact(change ConfigChange, limit int) (*ImpactReport, error) {
+	_, err := h.storer.GetTaxRuleSet(change.TaxYear)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrTaxYearNotSupported
+	}
+	if err != nil {
+		return nil, err
+	}
+	values, err := h.configValues(change.TaxYear, change.Key)
+	if err != nil {
+		return nil, err
+	}
+
+	assessments, err := h.storedAssessments(change.TaxYear, impactSample(limit))
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImpactReport{
+		TaxYear:       change.TaxYear,
+		Key:           change.Key,
+		CurrentValue:  values.base,
+		ProposedValue: change.Value,
+		MostAffected:  []AssessmentImpact{},
+	}
+	if change.EffectiveFrom != nil {
+		report.CurrentValue = values.valueOn(*change.EffectiveFrom)
+	}
+
+	// Assessments of the same tax date share their rules.
+	type impactRules struct {
+		current  TaxCalculator
+		proposed TaxCalculator
+	}
+	rulesOn := map[string]impactRules{}
+	impacts := []AssessmentImpact{}
+	for _, assessment := range assessments {
+		var info TaxInformation
+		err := json.Unmarshal(assessment.Input, &info)
+		if err != nil {
+			return nil, err
+		}
+		info.TaxYear = change.TaxYear
+
+		taxDate := assessmentTaxDate(assessment, info)
+		rules, ok := rulesOn[taxDate.Format(DATE_FORMAT)]
+		if !ok {
+			rules.current, err = h.newTaxCalculator(change.TaxYear, taxDate)
+			if err != nil {
+				return nil, err
+			}
+			rules.proposed = rules.current
+			if change.covers(values, taxDate) {
+				rules.proposed = rules.current.withConfig(change.Key, change.Value)
+			}
+			rulesOn[taxDate.Format(DATE_FORMAT)] = rules
+		}
+		current, proposed := rules.current, rules.proposed
+
+		impact := AssessmentImpact{
+			AssessmentID: assessment.ID,
+			CurrentTax:   current.netTax(info),
+			ProposedTax:  proposed.netTax(info),
+		}
+		impact.Delta = impact.ProposedTax - impact.CurrentTax
+
+		report.Assessments++
+		report.CurrentTotal += impact.CurrentTax
+		report.ProposedTotal += impact.ProposedTax
+		if impact.Delta > 0 {
+			report.Increased++
+		}
+		if impact.Delta < 0 {
+			report.Decreased++
+		}
+		if impact.Delta != 0 {
+			impacts = append(impacts, impact)
+		}
+	}
+
+	report.TotalDelta = report.ProposedTotal - report.CurrentTotal
+	if report.Assessments > 0 {
+		report.AverageDelta = report.TotalDelta.Div(int64(report.Assessments))
+	}
+
+	sort.SliceStable(impacts, func(i, j int) bool {
+		return impacts[i].Delta.Abs() > impacts[j].Delta.Abs()
+	})
+	report.MostAffected = append(report.MostAffected, impacts[:min(len(impacts), MOST_AFFECTED_COUNT)]...)
+
+	return report, nil
+}
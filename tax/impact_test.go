@@ -0,0 +1,164 @@
+package tax
+
+import (
+	"testing"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestAnalyseImpact(t *testing.T) {
+	newStub := func() *StubTaxHandler {
+		return &StubTaxHandler{assessments: []postgres.TaxAssessment{
+			{ID: 1, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 500000.0}`)},
+			{ID: 2, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 200000.0}`)},
+			{ID: 3, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 1000000.0}`)},
+		}}
+	}
+
+	t.Run("given higher personal deduction should report the decrease in tax", func(t *testing.T) {
+		t.Parallel()
+		h := New(newStub())
+
+		report, err := h.AnalyseImpact(ConfigChange{
+			TaxYear: DEFAULT_TAX_YEAR,
+			Key:     "PERSONAL_DEDUCTION",
+			Value:   money.FromBaht(70_000),
+		}, 0)
+		if err != nil {
+			t.Fatalf("unable to analyse impact: %v", err)
+		}
+
+		if report.CurrentValue != DEFAULT_PERSONAL_DEDUCTION {
+			t.Errorf("invalid current value: got %v want %v", report.CurrentValue, DEFAULT_PERSONAL_DEDUCTION)
+		}
+		if report.Assessments != 3 || report.Decreased != 2 || report.Increased != 0 {
+			t.Errorf("invalid counts: got %+v", report)
+		}
+		if report.TotalDelta != money.FromBaht(-2_500) {
+			t.Errorf("invalid total delta: got %v want %v", report.TotalDelta, money.FromBaht(-2_500))
+		}
+
+		want := []AssessmentImpact{
+			{AssessmentID: 3, CurrentTax: money.FromBaht(101_000), ProposedTax: money.FromBaht(99_500), Delta: money.FromBaht(-1_500)},
+			{AssessmentID: 1, CurrentTax: money.FromBaht(29_000), ProposedTax: money.FromBaht(28_000), Delta: money.FromBaht(-1_000)},
+		}
+		if len(report.MostAffected) != len(want) || report.MostAffected[0] != want[0] || report.MostAffected[1] != want[1] {
+			t.Errorf("invalid most affected: got %+v want %+v", report.MostAffected, want)
+		}
+	})
+
+	t.Run("given sample should recompute the latest assessments only", func(t *testing.T) {
+		t.Parallel()
+		h := New(newStub())
+
+		report, err := h.AnalyseImpact(ConfigChange{
+			TaxYear: DEFAULT_TAX_YEAR,
+			Key:     "PERSONAL_DEDUCTION",
+			Value:   money.FromBaht(70_000),
+		}, 2)
+		if err != nil {
+			t.Fatalf("unable to analyse impact: %v", err)
+		}
+
+		if report.Assessments != 2 || report.TotalDelta != money.FromBaht(-1_500) {
+			t.Errorf("invalid sampled report: got %+v", report)
+		}
+	})
+
+	t.Run("given more assessments than a page should page back through all of them once", func(t *testing.T) {
+		t.Parallel()
+		stub := &StubTaxHandler{}
+		for id := 1; id <= MAX_ASSESSMENT_LIMIT*2+50; id++ {
+			stub.assessments = append(stub.assessments, postgres.TaxAssessment{ID: id, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 500000.0}`)})
+		}
+		h := New(stub)
+
+		report, err := h.AnalyseImpact(ConfigChange{
+			TaxYear: DEFAULT_TAX_YEAR,
+			Key:     "PERSONAL_DEDUCTION",
+			Value:   money.FromBaht(70_000),
+		}, 0)
+		if err != nil {
+			t.Fatalf("unable to analyse impact: %v", err)
+		}
+
+		if report.Assessments != len(stub.assessments) || report.Decreased != len(stub.assessments) {
+			t.Errorf("invalid counts: got %+v", report)
+		}
+	})
+
+	t.Run("given base value change should skip assessments dated within a schedule", func(t *testing.T) {
+		t.Parallel()
+		scheduleFrom := time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)
+		scheduleTo := time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC)
+		h := New(&StubTaxHandler{
+			schedules: []postgres.TaxConfig{
+				{Key: "PERSONAL_DEDUCTION", Value: money.FromBaht(60_000), EffectiveFrom: &scheduleFrom, EffectiveTo: &scheduleTo},
+			},
+			assessments: []postgres.TaxAssessment{
+				{ID: 1, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 500000.0}`), Rules: []byte(`{"taxDate": "2024-03-01"}`)},
+				{ID: 2, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 500000.0}`), Rules: []byte(`{"taxDate": "2024-08-01"}`)},
+			},
+		})
+
+		report, err := h.AnalyseImpact(ConfigChange{
+			TaxYear: DEFAULT_TAX_YEAR,
+			Key:     "PERSONAL_DEDUCTION",
+			Value:   money.FromBaht(70_000),
+		}, 0)
+		if err != nil {
+			t.Fatalf("unable to analyse impact: %v", err)
+		}
+
+		if report.Decreased != 1 || report.Increased != 0 || report.TotalDelta != money.FromBaht(-1_000) {
+			t.Errorf("invalid report: got %+v", report)
+		}
+		if len(report.MostAffected) != 1 || report.MostAffected[0].AssessmentID != 1 {
+			t.Errorf("invalid most affected: got %+v", report.MostAffected)
+		}
+	})
+
+	t.Run("given scheduled change should recompute assessments dated within its window only", func(t *testing.T) {
+		t.Parallel()
+		effectiveFrom := time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)
+		effectiveTo := time.Date(2024, time.September, 30, 0, 0, 0, 0, time.UTC)
+		h := New(&StubTaxHandler{assessments: []postgres.TaxAssessment{
+			{ID: 1, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 500000.0}`), Rules: []byte(`{"taxDate": "2024-03-01"}`)},
+			{ID: 2, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 500000.0}`), Rules: []byte(`{"taxDate": "2024-08-01"}`)},
+			{ID: 3, TaxYear: DEFAULT_TAX_YEAR, Input: []byte(`{"totalIncome": 500000.0, "taxDate": "2024-10-01"}`)},
+		}})
+
+		report, err := h.AnalyseImpact(ConfigChange{
+			TaxYear:       DEFAULT_TAX_YEAR,
+			Key:           "PERSONAL_DEDUCTION",
+			Value:         money.FromBaht(70_000),
+			EffectiveFrom: &effectiveFrom,
+			EffectiveTo:   &effectiveTo,
+		}, 0)
+		if err != nil {
+			t.Fatalf("unable to analyse impact: %v", err)
+		}
+
+		if report.CurrentValue != DEFAULT_PERSONAL_DEDUCTION {
+			t.Errorf("invalid current value: got %v want %v", report.CurrentValue, DEFAULT_PERSONAL_DEDUCTION)
+		}
+		if report.Decreased != 1 || report.Increased != 0 || report.TotalDelta != money.FromBaht(-1_000) {
+			t.Errorf("invalid report: got %+v", report)
+		}
+		if len(report.MostAffected) != 1 || report.MostAffected[0].AssessmentID != 2 {
+			t.Errorf("invalid most affected: got %+v", report.MostAffected)
+		}
+	})
+
+	t.Run("given unknown config key should return an error", func(t *testing.T) {
+		t.Parallel()
+		h := New(newStub())
+
+		_, err := h.AnalyseImpact(ConfigChange{TaxYear: DEFAULT_TAX_YEAR, Key: "UNKNOWN"}, 0)
+		if err != ErrUnknownConfigKey {
+			t.Errorf("invalid error: got %v want %v", err, ErrUnknownConfigKey)
+		}
+	})
+}
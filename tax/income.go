@@ -0,0 +1,193 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
+const (
+	EXPENSE_METHOD_STANDARD = "standard"
+	EXPENSE_METHOD_ACTUAL   = "actual"
+)
+
+type (
+	// Income is an assessable income tagged by its Section 40 category, e.g. "40(1)".
+	Income struct {
+		IncomeType string      `json:"incomeType"`
+		SubType    string      `json:"subType,omitempty"`
+		Amount     money.Money `json:"amount"`
+		// ExpenseMethod is either "standard" (default) or "actual" for categories that allow it.
+		ExpenseMethod string      `json:"expenseMethod,omitempty"`
+		ActualExpense money.Money `json:"actualExpense,omitempty"`
+	}
+
+	IncomeBreakdown struct {
+		IncomeType string      `json:"incomeType"`
+		SubType    string      `json:"subType,omitempty"`
+		Amount     money.Money `json:"amount"`
+		Expense    money.Money `json:"expense"`
+		NetIncome  money.Money `json:"netIncome"`
+	}
+
+	IncomeDetails struct {
+		GrossIncome  money.Money       `json:"grossIncome"`
+		TotalExpense money.Money       `json:"totalExpense"`
+		NetIncome    money.Money       `json:"netIncome"`
+		Incomes      []IncomeBreakdown `json:"incomes"`
+	}
+
+	incomeCategory struct {
+		// expenseRates maps sub types to their standard expense rate, "" is the default.
+		expenseRates map[string]money.Rate
+		// capKey limits the standard expense of all categories sharing the key.
+		capKey      string
+		allowActual bool
+		salary      bool
+	}
+)
+
+var incomeCategories = map[string]incomeCategory{
+	"40(1)": {
+		expenseRates: map[string]money.Rate{"": money.MustParseRate("0.50")},
+		capKey:       "MAX_EMPLOYMENT_EXPENSE_DEDUCTION",
+		salary:       true,
+	},
+	"40(2)": {
+		expenseRates: map[string]money.Rate{"": money.MustParseRate("0.50")},
+		capKey:       "MAX_EMPLOYMENT_EXPENSE_DEDUCTION",
+	},
+	"40(3)": {
+		expenseRates: map[string]money.Rate{"": money.MustParseRate("0.50")},
+		capKey:       "MAX_ROYALTY_EXPENSE_DEDUCTION",
+	},
+	"40(4)": {
+		expenseRates: map[string]money.Rate{"": 0},
+	},
+	"40(5)": {
+		expenseRates: map[string]money.Rate{
+			"building":          money.MustParseRate("0.30"),
+			"agricultural-land": money.MustParseRate("0.20"),
+			"land":              money.MustParseRate("0.15"),
+			"vehicle":           money.MustParseRate("0.30"),
+			"other":             money.MustParseRate("0.10"),
+		},
+		allowActual: true,
+	},
+	"40(6)": {
+		expenseRates: map[string]money.Rate{
+			"":        money.MustParseRate("0.30"),
+			"medical": money.MustParseRate("0.60"),
+		},
+		allowActual: true,
+	},
+	"40(7)": {
+		expenseRates: map[string]money.Rate{"": money.MustParseRate("0.60")},
+		allowActual:  true,
+	},
+	"40(8)": {
+		expenseRates: map[string]money.Rate{"": money.MustParseRate("0.60")},
+		allowActual:  true,
+	},
+}
+
+var expenseCapDefaults = map[string]money.Money{
+	"MAX_EMPLOYMENT_EXPENSE_DEDUCTION": money.FromBaht(100_000),
+	"MAX_ROYALTY_EXPENSE_DEDUCTION":    money.FromBaht(100_000),
+}
+
+var ErrInvalidIncomeType = errors.New("invalid income type")
+
+func validateIncomes(info TaxInformation) error {
+	if len(info.Incomes) == 0 {
+		return nil
+	}
+
+	gross := money.Money(0)
+	for _, income := range info.Incomes {
+		category, ok := incomeCategories[income.IncomeType]
+		if !ok {
+			return ErrInvalidIncomeType
+		}
+		if _, ok := category.expenseRates[income.SubType]; !ok {
+			return fmt.Errorf("invalid sub type %q for income %s", income.SubType, income.IncomeType)
+		}
+		if income.Amount < 0 || income.ActualExpense < 0 {
+			return fmt.Errorf("%s amount must not be negative", income.IncomeType)
+		}
+
+		switch income.ExpenseMethod {
+		case "", EXPENSE_METHOD_STANDARD:
+		case EXPENSE_METHOD_ACTUAL:
+			if !category.allowActual {
+				return fmt.Errorf("income %s does not allow actual expenses", income.IncomeType)
+			}
+			if income.ActualExpense > income.Amount {
+				return fmt.Errorf("actual expense of %s must not exceed its amount", income.IncomeType)
+			}
+		default:
+			return fmt.Errorf("invalid expense method %q", income.ExpenseMethod)
+		}
+
+		gross += income.Amount
+	}
+
+	if info.TotalIncome != 0 && info.TotalIncome != gross {
+		return errors.New("totalIncome must equal the sum of incomes")
+	}
+
+	return nil
+}
+
+// incomeDetails applies the expense deduction of each income category. It returns nil when
+// the request only has totalIncome, which is then taxed as already net of expenses.
+func (t TaxCalculator) incomeDetails(info TaxInformation) *IncomeDetails {
+	if len(info.Incomes) == 0 {
+		return nil
+	}
+
+	details := &IncomeDetails{Incomes: []IncomeBreakdown{}}
+	capUsed := map[string]money.Money{}
+	for _, income := range info.Incomes {
+		category := incomeCategories[income.IncomeType]
+
+		var expense money.Money
+		if income.ExpenseMethod == EXPENSE_METHOD_ACTUAL {
+			expense = income.ActualExpense
+		} else {
+			expense = income.Amount.MulRate(category.expenseRates[income.SubType])
+			if category.capKey != "" {
+				headroom := money.Max(t.cap(category.capKey)-capUsed[category.capKey], 0)
+				expense = money.Min(expense, headroom)
+				capUsed[category.capKey] += expense
+			}
+		}
+
+		details.Incomes = append(details.Incomes, IncomeBreakdown{
+			IncomeType: income.IncomeType,
+			SubType:    income.SubType,
+			Amount:     income.Amount,
+			Expense:    expense,
+			NetIncome:  income.Amount - expense,
+		})
+		details.GrossIncome += income.Amount
+		details.TotalExpense += expense
+	}
+	details.NetIncome = details.GrossIncome - details.TotalExpense
+
+	return details
+}
+
+// grossIncome is the assessable income before expenses.
+func (t *TaxInformation) grossIncome() money.Money {
+	if len(t.Incomes) == 0 {
+		return t.TotalIncome
+	}
+
+	gross := money.Money(0)
+	for _, income := range t.Incomes {
+		gross += income.Amount
+	}
+	return gross
+}
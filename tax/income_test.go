@@ -0,0 +1,237 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestIncomeDetails(t *testing.T) {
+	testCases := []struct {
+		name            string
+		incomes         []Income
+		expectedExpense money.Money
+	}{
+		{
+			name: "given salary and fees should cap the combined expense at 100,000",
+			incomes: []Income{
+				{IncomeType: "40(1)", Amount: money.FromBaht(600_000)},
+				{IncomeType: "40(2)", Amount: money.FromBaht(200_000)},
+			},
+			expectedExpense: money.FromBaht(100_000),
+		},
+		{
+			name: "given business income should deduct 60% standard expense",
+			incomes: []Income{
+				{IncomeType: "40(8)", Amount: money.FromBaht(1_000_000)},
+			},
+			expectedExpense: money.FromBaht(600_000),
+		},
+		{
+			name: "given building and land rental should deduct the rate of each property",
+			incomes: []Income{
+				{IncomeType: "40(5)", SubType: "building", Amount: money.FromBaht(100_000)},
+				{IncomeType: "40(5)", SubType: "land", Amount: money.FromBaht(100_000)},
+			},
+			expectedExpense: money.FromBaht(45_000),
+		},
+		{
+			name: "given actual expense should deduct the actual expense",
+			incomes: []Income{
+				{IncomeType: "40(7)", Amount: money.FromBaht(500_000), ExpenseMethod: "actual", ActualExpense: money.FromBaht(400_000)},
+			},
+			expectedExpense: money.FromBaht(400_000),
+		},
+		{
+			name: "given interest should not deduct expense",
+			incomes: []Income{
+				{IncomeType: "40(4)", Amount: money.FromBaht(50_000)},
+			},
+			expectedExpense: 0,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			details := newDefaultTaxCalculator().incomeDetails(TaxInformation{Incomes: tc.incomes})
+
+			if details.TotalExpense != tc.expectedExpense {
+				t.Errorf("invalid expense: got %v want %v", details.TotalExpense, tc.expectedExpense)
+			}
+			if details.NetIncome != details.GrossIncome-tc.expectedExpense {
+				t.Errorf("invalid net income: got %v want %v", details.NetIncome, details.GrossIncome-tc.expectedExpense)
+			}
+		})
+	}
+}
+
+func TestValidateIncomes(t *testing.T) {
+	testCases := []struct {
+		name string
+		info TaxInformation
+	}{
+		{
+			name: "given unknown income type should return error",
+			info: TaxInformation{Incomes: []Income{{IncomeType: "40(9)"}}},
+		},
+		{
+			name: "given actual expense for salary should return error",
+			info: TaxInformation{Incomes: []Income{{IncomeType: "40(1)", ExpenseMethod: "actual"}}},
+		},
+		{
+			name: "given rental without property type should return error",
+			info: TaxInformation{Incomes: []Income{{IncomeType: "40(5)", Amount: money.FromBaht(1)}}},
+		},
+		{
+			name: "given total income different from incomes should return error",
+			info: TaxInformation{
+				TotalIncome: money.FromBaht(1),
+				Incomes:     []Income{{IncomeType: "40(1)", Amount: money.FromBaht(2)}},
+			},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if err := validateIncomes(tc.info); err == nil {
+				t.Errorf("expected error")
+			}
+		})
+	}
+}
+
+func TestCalculateTaxWithIncomes(t *testing.T) {
+	c, rec := setup(t, func() *http.Request {
+		reqJSON := `{
+			"incomes": [
+				{ "incomeType": "40(1)", "amount": 600000.0 },
+				{ "incomeType": "40(8)", "amount": 500000.0 }
+			]
+		}`
+		return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(reqJSON))
+	})
+
+	h := New(&StubTaxHandler{
+		configs: map[string]*postgres.TaxConfig{},
+	})
+	err := h.CalculateTax(c)
+	if err != nil {
+		t.Errorf("unable to calculate tax: %v", err)
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("invalid status code: got %v want %v",
+			rec.Code, http.StatusOK)
+	}
+
+	res := &TaxCalculationResponse{}
+	err = json.Unmarshal(rec.Body.Bytes(), res)
+	if err != nil {
+		t.Errorf("unable to unmarshal response: %v", err)
+	}
+
+	// 1,100,000 - 100,000 (40(1)) - 300,000 (40(8)) - 60,000 = 640,000
+	if res.Income == nil || res.Income.NetIncome != money.FromBaht(700_000) {
+		t.Fatalf("invalid income details: got %+v", res.Income)
+	}
+	if res.Tax != money.FromBaht(56_000) {
+		t.Errorf("invalid tax: got %v want %v", res.Tax, money.FromBaht(56_000))
+	}
+}
+
+func TestAlternativeTax(t *testing.T) {
+	testCases := []struct {
+		name           string
+		incomes        []Income
+		expectedTax    money.Money
+		expectedMethod string
+	}{
+		{
+			name: "given business with low profit should apply 0.5% of gross income",
+			incomes: []Income{
+				{IncomeType: "40(8)", Amount: money.FromBaht(3_000_000), ExpenseMethod: "actual", ActualExpense: money.FromBaht(2_900_000)},
+			},
+			expectedTax:    money.FromBaht(15_000),
+			expectedMethod: TAX_METHOD_ALTERNATIVE,
+		},
+		{
+			name: "given alternative tax of 5,000 or less should apply progressive tax",
+			incomes: []Income{
+				{IncomeType: "40(8)", Amount: money.FromBaht(1_000_000), ExpenseMethod: "actual", ActualExpense: money.FromBaht(1_000_000)},
+			},
+			expectedTax:    0,
+			expectedMethod: TAX_METHOD_PROGRESSIVE,
+		},
+		{
+			name: "given salary only should apply progressive tax",
+			incomes: []Income{
+				{IncomeType: "40(1)", Amount: money.FromBaht(3_000_000)},
+			},
+			expectedTax:    money.FromBaht(604_000),
+			expectedMethod: TAX_METHOD_PROGRESSIVE,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			details := newDefaultTaxCalculator().calculate(TaxInformation{Incomes: tc.incomes})
+
+			if details.tax != tc.expectedTax {
+				t.Errorf("invalid tax: got %v want %v", details.tax, tc.expectedTax)
+			}
+			if details.taxMethod != tc.expectedMethod {
+				t.Errorf("invalid tax method: got %v want %v", details.taxMethod, tc.expectedMethod)
+			}
+		})
+	}
+}
+
+func TestSubjectToAlternativeTax(t *testing.T) {
+	testCases := []struct {
+		name            string
+		halfYear        bool
+		nonSalaryIncome money.Money
+		expected        bool
+	}{
+		{
+			name:            "given exactly 120,000 of non-salary income should not be subject to the alternative tax",
+			nonSalaryIncome: money.FromBaht(120_000),
+			expected:        false,
+		},
+		{
+			name:            "given 120,000.01 of non-salary income should be subject to the alternative tax",
+			nonSalaryIncome: money.FromBaht(120_000) + 1,
+			expected:        true,
+		},
+		{
+			name:            "given exactly 60,000 of non-salary income on the half-year return should not be subject to the alternative tax",
+			halfYear:        true,
+			nonSalaryIncome: money.FromBaht(60_000),
+			expected:        false,
+		},
+		{
+			name:            "given 60,000.01 of non-salary income on the half-year return should be subject to the alternative tax",
+			halfYear:        true,
+			nonSalaryIncome: money.FromBaht(60_000) + 1,
+			expected:        true,
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			calculator := newDefaultTaxCalculator()
+			if tc.halfYear {
+				calculator = calculator.halfYear()
+			}
+
+			if got := calculator.subjectToAlternativeTax(tc.nonSalaryIncome); got != tc.expected {
+				t.Errorf("invalid alternative tax threshold: got %v want %v", got, tc.expected)
+			}
+		})
+	}
+}
@@ -0,0 +1,121 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/labstack/echo/v4"
+)
+
+const INSTALMENT_COUNT = 3
+
+// MIN_INSTALMENT_TAX is the tax a return must exceed to be paid in instalments.
+var MIN_INSTALMENT_TAX = money.FromBaht(3_000)
+
+var (
+	ErrInstalmentTaxTooLow = fmt.Errorf("tax of %v or less cannot be paid in instalments", MIN_INSTALMENT_TAX)
+	ErrInstalmentFiledLate = errors.New("only returns filed by the deadline can be paid in instalments")
+)
+
+type (
+	Instalment struct {
+		Number  int         `json:"number"`
+		DueDate string      `json:"dueDate"`
+		Amount  money.Money `json:"amount"`
+	}
+
+	// InstalmentRequest takes the tax of a calculation result and the date the return is filed.
+	InstalmentRequest struct {
+		TaxYear    int         `json:"taxYear"`
+		Period     string      `json:"period"`
+		Tax        money.Money `json:"tax"`
+		FilingDate string      `json:"filingDate"`
+	}
+
+	InstalmentPlan struct {
+		Tax         money.Money  `json:"tax"`
+		Deadline    string       `json:"deadline"`
+		Instalments []Instalment `json:"instalments"`
+	}
+)
+
+func (r InstalmentRequest) taxInformation() TaxInformation {
+	return TaxInformation{TaxYear: r.TaxYear, Period: r.Period}
+}
+
+// instalments splits the tax into three instalments. The first is due with the return,
+// the others at the end of each of the following months. Rounding goes to the last one.
+func instalments(tax money.Money, deadline time.Time) ([]Instalment, error) {
+	if tax <= MIN_INSTALMENT_TAX {
+		return nil, ErrInstalmentTaxTooLow
+	}
+
+	amount := tax.Div(INSTALMENT_COUNT)
+	plan := []Instalment{}
+	for i := 0; i < INSTALMENT_COUNT; i++ {
+		dueDate := time.Date(deadline.Year(), deadline.Month()+time.Month(i)+1, 0, 0, 0, 0, 0, time.UTC)
+		if i == INSTALMENT_COUNT-1 {
+			amount = tax - amount.Mul(INSTALMENT_COUNT-1)
+		}
+		plan = append(plan, Instalment{
+			Number:  i + 1,
+			DueDate: dueDate.Format(DATE_FORMAT),
+			Amount:  amount,
+		})
+	}
+	return plan, nil
+}
+
+func (h *Handler) CalculateInstalments(c echo.Context) error {
+	var req InstalmentRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	filingDate, err := parseFilingDate(req.FilingDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	// Only the tax is given, so the incomes of a half-year return cannot be checked.
+	info := req.taxInformation()
+	err = validPeriod(info.Period)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	_, err = h.newTaxCalculator(info.taxYear(), info.taxDate())
+	if err != nil {
+		return taxRulesError(c, info.taxYear(), err)
+	}
+
+	deadline := filingDeadline(info.taxYear(), info.isHalfYear())
+	if filingDate.After(deadline) {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: ErrInstalmentFiledLate.Error(),
+		})
+	}
+
+	plan, err := instalments(req.Tax, deadline)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	return c.JSON(http.StatusOK, InstalmentPlan{
+		Tax:         req.Tax,
+		Deadline:    deadline.Format(DATE_FORMAT),
+		Instalments: plan,
+	})
+}
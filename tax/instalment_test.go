@@ -0,0 +1,133 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestInstalments(t *testing.T) {
+	calculateInstalments := func(t *testing.T, reqJSON string) (*httptest.ResponseRecorder, *InstalmentPlan) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/instalments", strings.NewReader(reqJSON))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateInstalments(c)
+		if err != nil {
+			t.Errorf("unable to calculate instalments: %v", err)
+		}
+
+		res := &InstalmentPlan{}
+		if rec.Code == http.StatusOK {
+			err = json.Unmarshal(rec.Body.Bytes(), res)
+			if err != nil {
+				t.Errorf("unable to unmarshal response: %v", err)
+			}
+		}
+		return rec, res
+	}
+
+	t.Run("given tax filed on time should split it into three instalments", func(t *testing.T) {
+		rec, res := calculateInstalments(t, `{"tax": 29000.0, "filingDate": "2025-03-15"}`)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		want := []Instalment{
+			{Number: 1, DueDate: "2025-03-31", Amount: money.MustParse("9666.67")},
+			{Number: 2, DueDate: "2025-04-30", Amount: money.MustParse("9666.67")},
+			{Number: 3, DueDate: "2025-05-31", Amount: money.MustParse("9666.66")},
+		}
+		if len(res.Instalments) != len(want) {
+			t.Fatalf("invalid instalments: got %v want %v", res.Instalments, want)
+		}
+		for i := range want {
+			if res.Instalments[i] != want[i] {
+				t.Errorf("invalid instalment %d: got %v want %v", i+1, res.Instalments[i], want[i])
+			}
+		}
+	})
+
+	t.Run("given half-year return should follow the half-year deadline", func(t *testing.T) {
+		rec, res := calculateInstalments(t, `{"tax": 9000.0, "period": "half-year", "filingDate": "2024-09-01"}`)
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+		if res.Instalments[2].DueDate != "2024-11-30" {
+			t.Errorf("invalid last due date: got %v want %v", res.Instalments[2].DueDate, "2024-11-30")
+		}
+	})
+
+	t.Run("given tax of 3,000 or less should return 400", func(t *testing.T) {
+		rec, _ := calculateInstalments(t, `{"tax": 3000.0, "filingDate": "2025-03-15"}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given return filed after the deadline should return 400", func(t *testing.T) {
+		rec, _ := calculateInstalments(t, `{"tax": 29000.0, "filingDate": "2025-04-01"}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given unknown period should return 400", func(t *testing.T) {
+		rec, _ := calculateInstalments(t, `{"tax": 29000.0, "period": "quarter", "filingDate": "2025-03-15"}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestCalculateTaxWithInstalments(t *testing.T) {
+	calculateTax := func(t *testing.T, reqJSON string) *TaxCalculationResponse {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/calculations?instalments=true", strings.NewReader(reqJSON))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := &TaxCalculationResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+		return res
+	}
+
+	t.Run("given tax owing should return the instalment schedule", func(t *testing.T) {
+		res := calculateTax(t, `{"totalIncome": 500000.0}`)
+
+		if len(res.Instalments) != INSTALMENT_COUNT {
+			t.Errorf("invalid instalments: got %v", res.Instalments)
+		}
+	})
+
+	t.Run("given tax below the threshold should leave the schedule out", func(t *testing.T) {
+		res := calculateTax(t, `{"totalIncome": 200000.0}`)
+
+		if res.Instalments != nil {
+			t.Errorf("unexpected instalments: got %v", res.Instalments)
+		}
+	})
+}
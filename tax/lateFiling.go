@@ -0,0 +1,177 @@
+package tax
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/labstack/echo/v4"
+)
+
+const (
+	DATE_FORMAT = "2006-01-02"
+
+	// Tax years are Buddhist era years, BUDDHIST_ERA_OFFSET ahead of the Common Era.
+	BUDDHIST_ERA_OFFSET = 543
+
+	// Returns filed within LATE_FILING_GRACE_DAYS of the deadline are settled with the lower fine.
+	LATE_FILING_GRACE_DAYS = 7
+
+	// MAX_FILING_YEARS_LATE bounds the filing date, the surcharge reaches the tax long before.
+	MAX_FILING_YEARS_LATE = 10
+)
+
+var (
+	// SURCHARGE_RATE is charged on the unpaid tax for every month, or part of a month, after the deadline.
+	SURCHARGE_RATE = money.MustParseRate("0.015")
+
+	LATE_FILING_FINE_WITHIN_GRACE = money.FromBaht(100)
+	LATE_FILING_FINE              = money.FromBaht(200)
+)
+
+type (
+	// LateFilingRequest is a tax calculation request with the date the return is filed and paid.
+	LateFilingRequest struct {
+		TaxInformation
+		FilingDate string `json:"filingDate"`
+	}
+
+	SurchargeMonth struct {
+		Month     int         `json:"month"`
+		From      string      `json:"from"`
+		To        string      `json:"to"`
+		Surcharge money.Money `json:"surcharge"`
+		// TotalSurcharge is the surcharge owed when paying within the month.
+		TotalSurcharge money.Money `json:"totalSurcharge"`
+	}
+
+	LateFilingResponse struct {
+		Tax        money.Money      `json:"tax"`
+		Deadline   string           `json:"deadline"`
+		FilingDate string           `json:"filingDate"`
+		DaysLate   int              `json:"daysLate"`
+		MonthsLate int              `json:"monthsLate"`
+		Surcharge  money.Money      `json:"surcharge"`
+		Penalty    money.Money      `json:"penalty"`
+		Total      money.Money      `json:"total"`
+		Schedule   []SurchargeMonth `json:"schedule"`
+	}
+)
+
+// filingDeadline is the statutory deadline of the return: 31 March of the following year
+// for the full-year return and 30 September of the tax year for the half-year return.
+func filingDeadline(taxYear int, halfYear bool) time.Time {
+	year := taxYear - BUDDHIST_ERA_OFFSET
+	if halfYear {
+		return time.Date(year, time.September, 30, 0, 0, 0, 0, time.UTC)
+	}
+	return time.Date(year+1, time.March, 31, 0, 0, 0, 0, time.UTC)
+}
+
+func parseFilingDate(value string) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, errors.New("filingDate is required")
+	}
+	date, err := time.Parse(DATE_FORMAT, value)
+	if err != nil {
+		return time.Time{}, errors.New("filingDate must be formatted as YYYY-MM-DD")
+	}
+	return date, nil
+}
+
+// validateFilingDate rejects filing dates more than MAX_FILING_YEARS_LATE after the deadline.
+func validateFilingDate(deadline, filingDate time.Time) error {
+	if filingDate.After(deadline.AddDate(MAX_FILING_YEARS_LATE, 0, 0)) {
+		return fmt.Errorf("filingDate must be within %d years of the deadline %s", MAX_FILING_YEARS_LATE, deadline.Format(DATE_FORMAT))
+	}
+	return nil
+}
+
+// lateFiling works out the surcharge and fine of a return filed after the deadline.
+// The surcharge accrues monthly from the day after the deadline and never exceeds the tax,
+// the schedule ends with the month it reaches the tax.
+func lateFiling(tax money.Money, deadline, filingDate time.Time) LateFilingResponse {
+	res := LateFilingResponse{
+		Tax:        tax,
+		Deadline:   deadline.Format(DATE_FORMAT),
+		FilingDate: filingDate.Format(DATE_FORMAT),
+		Schedule:   []SurchargeMonth{},
+	}
+	if !filingDate.After(deadline) {
+		res.Total = tax
+		return res
+	}
+
+	res.DaysLate = int(filingDate.Sub(deadline).Hours() / 24)
+	res.Penalty = LATE_FILING_FINE
+	if res.DaysLate <= LATE_FILING_GRACE_DAYS {
+		res.Penalty = LATE_FILING_FINE_WITHIN_GRACE
+	}
+
+	start := deadline.AddDate(0, 0, 1)
+	for month := 1; !filingDate.Before(start.AddDate(0, month-1, 0)); month++ {
+		res.MonthsLate = month
+		if res.Surcharge == tax {
+			continue
+		}
+
+		surcharge := money.Min(tax.MulRate(SURCHARGE_RATE), tax-res.Surcharge)
+		res.Surcharge += surcharge
+		res.Schedule = append(res.Schedule, SurchargeMonth{
+			Month:          month,
+			From:           start.AddDate(0, month-1, 0).Format(DATE_FORMAT),
+			To:             start.AddDate(0, month, -1).Format(DATE_FORMAT),
+			Surcharge:      surcharge,
+			TotalSurcharge: res.Surcharge,
+		})
+	}
+
+	res.Total = tax + res.Surcharge + res.Penalty
+	return res
+}
+
+func (h *Handler) CalculateLateFiling(c echo.Context) error {
+	var req LateFilingRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	filingDate, err := parseFilingDate(req.FilingDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	err = validateTaxInformation(req.TaxInformation)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	taxCalculator, err := h.newTaxCalculator(req.taxYear(), req.taxDate())
+	if err != nil {
+		return taxRulesError(c, req.taxYear(), err)
+	}
+	if req.isHalfYear() {
+		taxCalculator = taxCalculator.halfYear()
+	}
+
+	deadline := filingDeadline(req.taxYear(), req.isHalfYear())
+	err = validateFilingDate(deadline, filingDate)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	taxDetails := taxCalculator.calculate(req.TaxInformation)
+
+	return c.JSON(http.StatusOK, lateFiling(taxDetails.tax, deadline, filingDate))
+}
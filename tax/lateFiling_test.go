@@ -0,0 +1,136 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestLateFiling(t *testing.T) {
+	t.Run("given return filed late should add the monthly surcharge and fine", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/late-filing", strings.NewReader(`{
+				"totalIncome": 500000.0,
+				"filingDate": "2025-06-15"
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateLateFiling(c)
+		if err != nil {
+			t.Errorf("unable to calculate late filing: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := &LateFilingResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		// tax 29,000, three months at 1.5% from 1 April 2025
+		if res.Deadline != "2025-03-31" {
+			t.Errorf("invalid deadline: got %v want %v", res.Deadline, "2025-03-31")
+		}
+		if res.MonthsLate != 3 || len(res.Schedule) != 3 {
+			t.Errorf("invalid months late: got %v want %v", res.MonthsLate, 3)
+		}
+		if res.Surcharge != money.FromBaht(1_305) {
+			t.Errorf("invalid surcharge: got %v want %v", res.Surcharge, money.FromBaht(1_305))
+		}
+		if res.Penalty != LATE_FILING_FINE {
+			t.Errorf("invalid penalty: got %v want %v", res.Penalty, LATE_FILING_FINE)
+		}
+		if res.Total != money.FromBaht(30_505) {
+			t.Errorf("invalid total: got %v want %v", res.Total, money.FromBaht(30_505))
+		}
+	})
+
+	t.Run("given invalid filing date should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/late-filing", strings.NewReader(`{
+				"totalIncome": 500000.0,
+				"filingDate": "15/06/2025"
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateLateFiling(c)
+		if err != nil {
+			t.Errorf("unable to calculate late filing: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given return filed on time should not charge anything", func(t *testing.T) {
+		deadline := filingDeadline(2567, true)
+		res := lateFiling(money.FromBaht(1_000), deadline, deadline)
+
+		if deadline.Format(DATE_FORMAT) != "2024-09-30" {
+			t.Errorf("invalid half-year deadline: got %v want %v", deadline.Format(DATE_FORMAT), "2024-09-30")
+		}
+		if res.Surcharge != 0 || res.Penalty != 0 || res.Total != money.FromBaht(1_000) {
+			t.Errorf("invalid charges: got %+v", res)
+		}
+	})
+
+	t.Run("given return filed within the grace days should charge the lower fine", func(t *testing.T) {
+		deadline := filingDeadline(2567, false)
+		res := lateFiling(money.FromBaht(1_000), deadline, deadline.AddDate(0, 0, LATE_FILING_GRACE_DAYS))
+
+		if res.Penalty != LATE_FILING_FINE_WITHIN_GRACE {
+			t.Errorf("invalid penalty: got %v want %v", res.Penalty, LATE_FILING_FINE_WITHIN_GRACE)
+		}
+		if res.MonthsLate != 1 {
+			t.Errorf("invalid months late: got %v want %v", res.MonthsLate, 1)
+		}
+	})
+
+	t.Run("given years of late payment should cap the surcharge at the tax", func(t *testing.T) {
+		deadline := filingDeadline(2567, false)
+		res := lateFiling(money.FromBaht(1_000), deadline, deadline.Add(6*365*24*time.Hour))
+
+		if res.Surcharge != money.FromBaht(1_000) {
+			t.Errorf("invalid surcharge: got %v want %v", res.Surcharge, money.FromBaht(1_000))
+		}
+		// 66 months of 15 and 10 in the 67th reach the tax, the schedule ends there.
+		if res.MonthsLate != 72 || len(res.Schedule) != 67 {
+			t.Errorf("invalid months: got %v months late and %v scheduled", res.MonthsLate, len(res.Schedule))
+		}
+		last := res.Schedule[len(res.Schedule)-1]
+		if last.Surcharge != money.FromBaht(10) || last.TotalSurcharge != money.FromBaht(1_000) {
+			t.Errorf("invalid last month: got %+v", last)
+		}
+	})
+
+	t.Run("given filing date far in the future should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/late-filing", strings.NewReader(`{
+				"totalIncome": 500000.0,
+				"filingDate": "9999-12-31"
+			}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculateLateFiling(c)
+		if err != nil {
+			t.Errorf("unable to calculate late filing: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
@@ -0,0 +1,133 @@
+package tax
+
+import (
+	"net/http"
+	"sort"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/labstack/echo/v4"
+)
+
+// optimiseProbe is claimed on top of the current claims to find how much more of an
+// allowance is deductible, it is far above any statutory cap.
+var optimiseProbe = money.FromBaht(1_000_000_000)
+
+type (
+	AllowanceHeadroom struct {
+		AllowanceType string      `json:"allowanceType"`
+		Claimed       money.Money `json:"claimed"`
+		Allowed       money.Money `json:"allowed"`
+		// Headroom is how much more can be claimed before the allowance hits its cap.
+		Headroom money.Money `json:"headroom"`
+		// SavingPerBaht is the tax saved by each extra baht claimed, i.e. the marginal rate.
+		SavingPerBaht money.Rate `json:"savingPerBaht"`
+		// MaxSaving is the tax saved by claiming the whole headroom.
+		MaxSaving money.Money `json:"maxSaving"`
+	}
+
+	OptimiseResponse struct {
+		Tax             money.Money         `json:"tax"`
+		MarginalBracket MarginalBracket     `json:"marginalBracket"`
+		Allowances      []AllowanceHeadroom `json:"allowances"`
+	}
+)
+
+func allowedOf(deductions []AllowanceDeduction, allowanceType string) (claimed money.Money, allowed money.Money) {
+	for _, deduction := range deductions {
+		if deduction.Type == allowanceType {
+			return deduction.Claimed, deduction.Allowed
+		}
+	}
+	return 0, 0
+}
+
+func withExtraClaim(info TaxInformation, allowanceType string, amount money.Money) TaxInformation {
+	allowances := append([]Allowance{}, info.Allowances...)
+	info.Allowances = append(allowances, Allowance{AllowanceType: allowanceType, Amount: amount})
+	return info
+}
+
+// optimise reports the headroom of every amount based allowance, ranked by the tax
+// its headroom would save.
+func (t TaxCalculator) optimise(info TaxInformation) OptimiseResponse {
+	details := t.calculate(info)
+	deductions := t.allowanceDeductions(info)
+	taxableIncome := t.calDeductedIncome(info)
+	marginal := t.marginalBracket(taxableIncome)
+
+	savingPerBaht := money.Rate(0)
+	if details.taxMethod == TAX_METHOD_PROGRESSIVE && taxableIncome > 0 {
+		savingPerBaht = marginal.Rate
+	}
+
+	headrooms := []AllowanceHeadroom{}
+	for _, rule := range allowanceRules {
+		if rule.Fixed {
+			continue
+		}
+
+		claimed, allowed := allowedOf(deductions, rule.Type)
+		_, maxAllowed := allowedOf(t.allowanceDeductions(withExtraClaim(info, rule.Type, optimiseProbe)), rule.Type)
+		headroom := money.Max(maxAllowed-allowed, 0)
+
+		maxSaving := money.Money(0)
+		if headroom > 0 {
+			maxSaving = money.Max(details.progressiveTax, details.alternativeTax) -
+				t.totalTax(withExtraClaim(info, rule.Type, headroom))
+		}
+
+		headrooms = append(headrooms, AllowanceHeadroom{
+			AllowanceType: rule.Type,
+			Claimed:       claimed,
+			Allowed:       allowed,
+			Headroom:      headroom,
+			SavingPerBaht: savingPerBaht,
+			MaxSaving:     maxSaving,
+		})
+	}
+
+	sort.SliceStable(headrooms, func(i, j int) bool {
+		if headrooms[i].MaxSaving != headrooms[j].MaxSaving {
+			return headrooms[i].MaxSaving > headrooms[j].MaxSaving
+		}
+		return headrooms[i].Headroom > headrooms[j].Headroom
+	})
+
+	return OptimiseResponse{
+		Tax:             details.tax,
+		MarginalBracket: marginal,
+		Allowances:      headrooms,
+	}
+}
+
+// totalTax is the tax liability before WHT.
+func (t TaxCalculator) totalTax(info TaxInformation) money.Money {
+	return t.calculate(info).assessedTax()
+}
+
+func (h *Handler) Optimise(c echo.Context) error {
+	var req TaxInformation
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	err = validateTaxInformation(req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	taxCalculator, err := h.newTaxCalculator(req.taxYear(), req.taxDate())
+	if err != nil {
+		return taxRulesError(c, req.taxYear(), err)
+	}
+	if req.isHalfYear() {
+		taxCalculator = taxCalculator.halfYear()
+	}
+
+	return c.JSON(http.StatusOK, taxCalculator.optimise(req))
+}
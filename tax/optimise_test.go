@@ -0,0 +1,114 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestOptimise(t *testing.T) {
+	t.Run("given allowances below their caps should report headroom ranked by saving", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			reqJSON := `{
+				"totalIncome": 1000000.0,
+				"allowances": [
+					{ "allowanceType": "k-receipt", "amount": 30000.0 },
+					{ "allowanceType": "ssf", "amount": 50000.0 }
+				]
+			}`
+			return httptest.NewRequest(http.MethodPost, "/tax/optimise", strings.NewReader(reqJSON))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.Optimise(c)
+		if err != nil {
+			t.Errorf("unable to optimise: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := &OptimiseResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		headrooms := map[string]AllowanceHeadroom{}
+		for _, headroom := range res.Allowances {
+			headrooms[headroom.AllowanceType] = headroom
+		}
+
+		if headrooms["k-receipt"].Headroom != money.FromBaht(20_000) {
+			t.Errorf("invalid k-receipt headroom: got %v want %v", headrooms["k-receipt"].Headroom, money.FromBaht(20_000))
+		}
+		if headrooms["ssf"].Headroom != money.FromBaht(150_000) {
+			t.Errorf("invalid ssf headroom: got %v want %v", headrooms["ssf"].Headroom, money.FromBaht(150_000))
+		}
+		if headrooms["k-receipt"].SavingPerBaht != money.MustParseRate("0.15") {
+			t.Errorf("invalid saving per baht: got %v want %v", headrooms["k-receipt"].SavingPerBaht, money.MustParseRate("0.15"))
+		}
+		// 1,000,000 - 60,000 - 30,000 - 50,000 = 860,000 taxable, 20,000 more saves 3,000
+		if headrooms["k-receipt"].MaxSaving != money.FromBaht(3_000) {
+			t.Errorf("invalid k-receipt saving: got %v want %v", headrooms["k-receipt"].MaxSaving, money.FromBaht(3_000))
+		}
+		if _, ok := headrooms["spouse"]; ok {
+			t.Errorf("unexpected headroom for per person allowance")
+		}
+
+		for i := 1; i < len(res.Allowances); i++ {
+			if res.Allowances[i].MaxSaving > res.Allowances[i-1].MaxSaving {
+				t.Errorf("allowances are not ranked by saving: %v", res.Allowances)
+			}
+		}
+	})
+
+	t.Run("given half-year period should report headroom against the halved caps", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			reqJSON := `{
+				"period": "half-year",
+				"incomes": [{ "incomeType": "40(8)", "amount": 600000.0 }],
+				"allowances": [{ "allowanceType": "k-receipt", "amount": 10000.0 }]
+			}`
+			return httptest.NewRequest(http.MethodPost, "/tax/optimise", strings.NewReader(reqJSON))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.Optimise(c)
+		if err != nil {
+			t.Errorf("unable to optimise: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Fatalf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := &OptimiseResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		headrooms := map[string]AllowanceHeadroom{}
+		for _, headroom := range res.Allowances {
+			headrooms[headroom.AllowanceType] = headroom
+		}
+
+		// 600,000 - 360,000 - 30,000 - 10,000 = 200,000 taxable, tax 5,000
+		if res.Tax != money.FromBaht(5_000) {
+			t.Errorf("invalid tax: got %v want %v", res.Tax, money.FromBaht(5_000))
+		}
+		if headrooms["k-receipt"].Headroom != money.FromBaht(15_000) {
+			t.Errorf("invalid k-receipt headroom: got %v want %v", headrooms["k-receipt"].Headroom, money.FromBaht(15_000))
+		}
+		if headrooms["ssf"].Headroom != money.FromBaht(100_000) {
+			t.Errorf("invalid ssf headroom: got %v want %v", headrooms["ssf"].Headroom, money.FromBaht(100_000))
+		}
+	})
+}
@@ -0,0 +1,150 @@
+package tax
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/labstack/echo/v4"
+)
+
+const MONTHS_PER_YEAR = 12
+
+type (
+	// PayrollRequest describes the salary of an employee for monthly (PND 1) withholding.
+	// To re-project mid-year, FromMonth is the first month still to be paid and
+	// IncomeToDate/WithheldToDate cover the months before it.
+	PayrollRequest struct {
+		TaxYear       int         `json:"taxYear"`
+		MonthlySalary money.Money `json:"monthlySalary"`
+		Bonus         money.Money `json:"bonus"`
+		// BonusMonth is the month the bonus is paid, December by default.
+		BonusMonth     int         `json:"bonusMonth,omitempty"`
+		Allowances     []Allowance `json:"allowances"`
+		FromMonth      int         `json:"fromMonth,omitempty"`
+		IncomeToDate   money.Money `json:"incomeToDate"`
+		WithheldToDate money.Money `json:"withheldToDate"`
+	}
+
+	MonthlyWithholding struct {
+		Month       int         `json:"month"`
+		Income      money.Money `json:"income"`
+		Withholding money.Money `json:"withholding"`
+	}
+
+	PayrollResponse struct {
+		AnnualIncome   money.Money          `json:"annualIncome"`
+		AnnualTax      money.Money          `json:"annualTax"`
+		WithheldToDate money.Money          `json:"withheldToDate"`
+		Months         []MonthlyWithholding `json:"months"`
+	}
+)
+
+func (r PayrollRequest) fromMonth() int {
+	if r.FromMonth == 0 {
+		return 1
+	}
+	return r.FromMonth
+}
+
+func (r PayrollRequest) bonusMonth() int {
+	if r.BonusMonth == 0 {
+		return MONTHS_PER_YEAR
+	}
+	return r.BonusMonth
+}
+
+func (r PayrollRequest) taxInformation(annualIncome money.Money) TaxInformation {
+	return TaxInformation{
+		TaxYear:    r.TaxYear,
+		Incomes:    []Income{{IncomeType: "40(1)", Amount: annualIncome}},
+		Allowances: r.Allowances,
+	}
+}
+
+func (r PayrollRequest) validate() error {
+	if r.MonthlySalary < 0 || r.Bonus < 0 || r.IncomeToDate < 0 || r.WithheldToDate < 0 {
+		return errors.New("amounts must not be negative")
+	}
+	if r.fromMonth() < 1 || r.fromMonth() > MONTHS_PER_YEAR {
+		return errors.New("fromMonth must be between 1 and 12")
+	}
+	if r.bonusMonth() < 1 || r.bonusMonth() > MONTHS_PER_YEAR {
+		return errors.New("bonusMonth must be between 1 and 12")
+	}
+	return validateAllowance(r.Allowances)
+}
+
+// withholding annualises the remaining salary, spreads the tax on salary evenly over the
+// remaining months and withholds the extra tax caused by the bonus in the bonus month.
+func (t TaxCalculator) withholding(req PayrollRequest) PayrollResponse {
+	remainingMonths := MONTHS_PER_YEAR - req.fromMonth() + 1
+	salaryIncome := req.IncomeToDate + req.MonthlySalary.Mul(int64(remainingMonths))
+
+	annualIncome := salaryIncome
+	bonusPending := req.Bonus > 0 && req.bonusMonth() >= req.fromMonth()
+	if bonusPending {
+		annualIncome += req.Bonus
+	}
+
+	annualTax := t.totalTax(req.taxInformation(annualIncome))
+	salaryTax := t.totalTax(req.taxInformation(salaryIncome))
+	bonusTax := annualTax - salaryTax
+
+	remainingTax := money.Max(salaryTax-req.WithheldToDate, 0)
+	monthlyTax := remainingTax.Div(int64(remainingMonths))
+
+	months := []MonthlyWithholding{}
+	withheld := money.Money(0)
+	for month := req.fromMonth(); month <= MONTHS_PER_YEAR; month++ {
+		withholding := money.Min(monthlyTax, remainingTax-withheld)
+		if month == MONTHS_PER_YEAR {
+			withholding = remainingTax - withheld
+		}
+		withheld += withholding
+
+		income := req.MonthlySalary
+		if bonusPending && month == req.bonusMonth() {
+			income += req.Bonus
+			withholding += bonusTax
+		}
+
+		months = append(months, MonthlyWithholding{
+			Month:       month,
+			Income:      income,
+			Withholding: withholding,
+		})
+	}
+
+	return PayrollResponse{
+		AnnualIncome:   annualIncome,
+		AnnualTax:      annualTax,
+		WithheldToDate: req.WithheldToDate,
+		Months:         months,
+	}
+}
+
+func (h *Handler) CalculatePayrollWithholding(c echo.Context) error {
+	var req PayrollRequest
+	err := c.Bind(&req)
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: "invalid request body",
+		})
+	}
+
+	err = req.validate()
+	if err != nil {
+		return c.JSON(http.StatusBadRequest, &Err{
+			Message: err.Error(),
+		})
+	}
+
+	info := req.taxInformation(0)
+	taxCalculator, err := h.newTaxCalculator(info.taxYear(), info.taxDate())
+	if err != nil {
+		return taxRulesError(c, info.taxYear(), err)
+	}
+
+	return c.JSON(http.StatusOK, taxCalculator.withholding(req))
+}
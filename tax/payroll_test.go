@@ -0,0 +1,109 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func sumWithholding(months []MonthlyWithholding) money.Money {
+	sum := money.Money(0)
+	for _, month := range months {
+		sum += month.Withholding
+	}
+	return sum
+}
+
+func TestPayrollWithholding(t *testing.T) {
+	t.Run("given monthly salary should withhold the annual tax evenly", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/payroll/withholding", strings.NewReader(`{"monthlySalary": 50000.0}`))
+		})
+
+		h := New(&StubTaxHandler{configs: map[string]*postgres.TaxConfig{}})
+		err := h.CalculatePayrollWithholding(c)
+		if err != nil {
+			t.Errorf("unable to calculate withholding: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusOK)
+		}
+
+		res := &PayrollResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		// 600,000 - 100,000 - 60,000 = 440,000, tax 29,000
+		if res.AnnualTax != money.FromBaht(29_000) {
+			t.Errorf("invalid annual tax: got %v want %v", res.AnnualTax, money.FromBaht(29_000))
+		}
+		if len(res.Months) != 12 || res.Months[0].Withholding != money.MustParse("2416.67") {
+			t.Errorf("invalid monthly withholding: got %v", res.Months)
+		}
+		if sumWithholding(res.Months) != res.AnnualTax {
+			t.Errorf("withholding does not add up to annual tax: got %v want %v", sumWithholding(res.Months), res.AnnualTax)
+		}
+	})
+
+	t.Run("given bonus should withhold the extra tax in the bonus month", func(t *testing.T) {
+		res := newDefaultTaxCalculator().withholding(PayrollRequest{
+			MonthlySalary: money.FromBaht(50_000),
+			Bonus:         money.FromBaht(100_000),
+			BonusMonth:    3,
+		})
+
+		// 700,000 - 100,000 - 60,000 = 540,000, tax 41,000 of which 12,000 is caused by the bonus
+		if res.AnnualTax != money.FromBaht(41_000) {
+			t.Errorf("invalid annual tax: got %v want %v", res.AnnualTax, money.FromBaht(41_000))
+		}
+		if res.Months[2].Withholding != res.Months[0].Withholding+money.FromBaht(12_000) {
+			t.Errorf("invalid bonus month withholding: got %v", res.Months[2])
+		}
+		if sumWithholding(res.Months) != res.AnnualTax {
+			t.Errorf("withholding does not add up to annual tax: got %v want %v", sumWithholding(res.Months), res.AnnualTax)
+		}
+	})
+
+	t.Run("given salary raise mid-year should re-project the remaining months", func(t *testing.T) {
+		res := newDefaultTaxCalculator().withholding(PayrollRequest{
+			MonthlySalary:  money.FromBaht(70_000),
+			FromMonth:      7,
+			IncomeToDate:   money.FromBaht(300_000),
+			WithheldToDate: money.MustParse("14500.02"),
+		})
+
+		// 720,000 - 100,000 - 60,000 = 560,000, tax 44,000
+		if res.AnnualTax != money.FromBaht(44_000) {
+			t.Errorf("invalid annual tax: got %v want %v", res.AnnualTax, money.FromBaht(44_000))
+		}
+		if len(res.Months) != 6 {
+			t.Errorf("invalid remaining months: got %v want %v", len(res.Months), 6)
+		}
+		if res.WithheldToDate+sumWithholding(res.Months) != res.AnnualTax {
+			t.Errorf("withholding does not add up to annual tax: got %v want %v", res.WithheldToDate+sumWithholding(res.Months), res.AnnualTax)
+		}
+	})
+
+	t.Run("given invalid month should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/payroll/withholding", strings.NewReader(`{"monthlySalary": 50000.0, "fromMonth": 13}`))
+		})
+
+		err := (&Handler{}).CalculatePayrollWithholding(c)
+		if err != nil {
+			t.Errorf("unable to calculate withholding: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
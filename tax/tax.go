@@ -1,132 +1,363 @@
 package tax
 
-import "math"
-
-var progressiveTax = []struct {
-	minIncome float64
-	maxIncome float64
-	rate      float64
-}{
-	{
-		minIncome: 0,
-		maxIncome: 150_000.0,
-		rate:      0.0,
-	},
-	{
-		minIncome: 150_000.0,
-		maxIncome: 500_000.0,
-		rate:      0.1,
-	},
-	{
-		minIncome: 500_000,
-		maxIncome: 1_000_000.0,
-		rate:      0.15,
-	},
-	{
-		minIncome: 1_000_000.0,
-		maxIncome: 2_000_000.0,
-		rate:      0.20,
-	},
-	{
-		minIncome: 2_000_000,
-		maxIncome: math.MaxFloat64,
-		rate:      0.35,
-	},
-}
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
 
 const (
-	MAX_TAX_LEVEL_0        = 150_000.0
-	MAX_TAX_LEVEL_1        = 500_000.0
-	MAX_TAX_LEVEL_2        = 1_000_000.0
-	MAX_TAX_LEVEL_3        = 2_000_000.0
-	MAX_TAX_LEVEL_1_AMOUNT = 35_000
-	MAX_TAX_LEVEL_2_AMOUNT = 75_000
-	MAX_TAX_LEVEL_3_AMOUNT = 200_000
-	MAX_DONATE_DEDUCTION   = 100_000
+	DEFAULT_TAX_YEAR = 2567
+
+	TAX_METHOD_PROGRESSIVE = "progressive"
+	TAX_METHOD_ALTERNATIVE = "alternative"
 )
 
+var (
+	DEFAULT_PERSONAL_DEDUCTION = money.FromBaht(60_000)
+	DEFAULT_MAX_K_RECEIPT      = money.FromBaht(50_000)
+	// DEFAULT_DONATION_INCOME_PERCENT limits donations to 10% of the income after other allowances.
+	DEFAULT_DONATION_INCOME_PERCENT = money.MustParse("10")
+
+	// The alternative method applies to taxpayers with 40(2)-40(8) income above
+	// MIN_ALTERNATIVE_TAX_INCOME and is waived when it comes to MAX_ALTERNATIVE_TAX_EXEMPT or less.
+	ALTERNATIVE_TAX_RATE       = money.MustParseRate("0.005")
+	MIN_ALTERNATIVE_TAX_INCOME = money.FromBaht(120_000)
+	MAX_ALTERNATIVE_TAX_EXEMPT = money.FromBaht(5_000)
+)
+
+type TaxBracket struct {
+	MinIncome money.Money `json:"minIncome"`
+	MaxIncome money.Money `json:"maxIncome"`
+	Rate      money.Rate  `json:"rate"`
+}
+
+// NewTaxBrackets converts stored brackets into a bracket set, making sure the bands
+// start at zero, are contiguous and end with an open-ended bracket.
+func NewTaxBrackets(rows []postgres.TaxBracket) ([]TaxBracket, error) {
+	if len(rows) == 0 {
+		return nil, errors.New("no tax brackets")
+	}
+
+	brackets := []TaxBracket{}
+	nextMin := money.Money(0)
+	for i, row := range rows {
+		if row.MinIncome != nextMin {
+			return nil, fmt.Errorf("tax bracket %d must start at %v", i, nextMin)
+		}
+		if row.Rate < 0 || row.Rate > money.RateScale {
+			return nil, fmt.Errorf("tax bracket %d has invalid rate %v", i, row.Rate)
+		}
+
+		maxIncome := money.Unlimited
+		if row.MaxIncome != nil {
+			maxIncome = *row.MaxIncome
+		}
+		if maxIncome <= row.MinIncome {
+			return nil, fmt.Errorf("tax bracket %d has invalid bounds", i)
+		}
+		if maxIncome == money.Unlimited && i != len(rows)-1 {
+			return nil, fmt.Errorf("tax bracket %d is open-ended but is not the last bracket", i)
+		}
+
+		brackets = append(brackets, TaxBracket{
+			MinIncome: row.MinIncome,
+			MaxIncome: maxIncome,
+			Rate:      row.Rate,
+		})
+		nextMin = maxIncome
+	}
+
+	if nextMin != money.Unlimited {
+		return nil, errors.New("last tax bracket must be open-ended")
+	}
+
+	return brackets, nil
+}
+
+// Label renders the bracket bounds the way they are shown to users, e.g. "150,001-500,000".
+func (b TaxBracket) Label() string {
+	from := b.MinIncome
+	if from > 0 {
+		from += money.FromBaht(1)
+	}
+
+	if b.MaxIncome == money.Unlimited {
+		return fmt.Sprintf("%s ขึ้นไป", FormatAmount(from))
+	}
+
+	return fmt.Sprintf("%s-%s", FormatAmount(from), FormatAmount(b.MaxIncome))
+}
+
+// FormatAmount formats the whole baht of the amount with thousands separators, e.g. 150,000.
+func FormatAmount(amount money.Money) string {
+	digits := strconv.FormatInt(amount.Baht(), 10)
+
+	result := []byte{}
+	for i := range digits {
+		if i > 0 && (len(digits)-i)%3 == 0 {
+			result = append(result, ',')
+		}
+		result = append(result, digits[i])
+	}
+
+	return string(result)
+}
+
+// TaxRules is the rule set of a single tax year.
+type TaxRules struct {
+	TaxYear int `json:"taxYear"`
+	// RuleSet is the name of the rule set the rules were resolved from and TaxDate
+	// the date the config values were in force on.
+	RuleSet           string      `json:"ruleSet"`
+	TaxDate           string      `json:"taxDate"`
+	PersonalDeduction money.Money `json:"personalDeduction"`
+	// Caps holds the resolved value of every key from CapDefaults.
+	Caps     map[string]money.Money `json:"caps"`
+	Brackets []TaxBracket           `json:"brackets"`
+	// HalfYear is set for the half-year (PND 94) return.
+	HalfYear bool `json:"halfYear"`
+}
+
+// CapDefaults returns every capped config key of the allowances and income expenses
+// with its statutory default.
+func CapDefaults() map[string]money.Money {
+	defaults := AllowanceCapDefaults()
+	for key, value := range expenseCapDefaults {
+		defaults[key] = value
+	}
+	return defaults
+}
+
+// ConfigKey describes a tax_configs key read by the calculator, Default is its
+// statutory value and Percent marks a percentage rather than an amount.
+type ConfigKey struct {
+	Key     string
+	Name    string
+	Default money.Money
+	Percent bool
+}
+
+// configNames names the personal deduction and every key of CapDefaults, in the order
+// they are listed.
+var configNames = []struct{ key, name string }{
+	{"PERSONAL_DEDUCTION", "Personal tax deduction"},
+	{"MAX_K_RECEIPT_DEDUCTION", "Maximum K Receipt deduction"},
+	{"DONATION_INCOME_PERCENT", "Donation deduction limit (% of net income)"},
+
+	{"SPOUSE_DEDUCTION", "Spouse deduction"},
+	{"CHILD_DEDUCTION", "Child deduction"},
+	{"CHILD_BORN_2018_DEDUCTION", "Second child born from 2018 deduction"},
+	{"PARENT_DEDUCTION", "Parent deduction"},
+	{"DISABLED_DEPENDANT_DEDUCTION", "Disabled dependant deduction"},
+
+	{"MAX_HEALTH_INSURANCE_DEDUCTION", "Maximum health insurance deduction"},
+	{"MAX_LIFE_INSURANCE_DEDUCTION", "Maximum life insurance deduction"},
+	{"MAX_INSURANCE_DEDUCTION", "Maximum life and health insurance deduction"},
+	{"MAX_PARENTS_HEALTH_INSURANCE_DEDUCTION", "Maximum parents' health insurance deduction"},
+	{"MAX_SOCIAL_SECURITY_DEDUCTION", "Maximum social security deduction"},
+	{"MAX_HOME_LOAN_INTEREST_DEDUCTION", "Maximum home loan interest deduction"},
+
+	{"MAX_SSF_DEDUCTION", "Maximum SSF deduction"},
+	{"MAX_RMF_DEDUCTION", "Maximum RMF deduction"},
+	{"MAX_PVD_DEDUCTION", "Maximum PVD deduction"},
+	{"MAX_GPF_DEDUCTION", "Maximum GPF deduction"},
+	{"MAX_RETIREMENT_SAVINGS_DEDUCTION", "Maximum retirement savings deduction"},
+	{"MAX_THAI_ESG_DEDUCTION", "Maximum Thai ESG deduction"},
+
+	{"MAX_EMPLOYMENT_EXPENSE_DEDUCTION", "Maximum 40(1)-40(2) expense deduction"},
+	{"MAX_ROYALTY_EXPENSE_DEDUCTION", "Maximum 40(3) expense deduction"},
+}
+
+// ConfigKeys returns the personal deduction and every key of CapDefaults with its name
+// and default. A key without a name panics, so a new cap can't go unlisted.
+func ConfigKeys() []ConfigKey {
+	defaults := CapDefaults()
+	defaults["PERSONAL_DEDUCTION"] = DEFAULT_PERSONAL_DEDUCTION
+
+	keys := []ConfigKey{}
+	for _, config := range configNames {
+		value, ok := defaults[config.key]
+		if !ok {
+			panic(fmt.Sprintf("config %s is not read by the calculator", config.key))
+		}
+		delete(defaults, config.key)
+		keys = append(keys, ConfigKey{
+			Key:     config.key,
+			Name:    config.name,
+			Default: value,
+			Percent: strings.HasSuffix(config.key, "_PERCENT"),
+		})
+	}
+	for key := range defaults {
+		panic(fmt.Sprintf("config %s has no name", key))
+	}
+	return keys
+}
+
 type TaxCalculator struct {
-	personalDededucation float64
-	maxKReceiptDeduction float64
+	rules TaxRules
 }
 
-func NewTaxCalculator(personalDeductation float64, maxKReceiptDeduction float64) TaxCalculator {
+func NewTaxCalculator(rules TaxRules) TaxCalculator {
 	return TaxCalculator{
-		personalDededucation: personalDeductation,
-		maxKReceiptDeduction: maxKReceiptDeduction,
+		rules: rules,
 	}
 }
 
 type CalculateTaxDetails struct {
-	tax       float64
-	taxRefund float64
+	tax       money.Money
+	taxRefund money.Money
 	taxLevel  []TaxLevel
+	income    *IncomeDetails
+	// netIncome is the income after expenses and taxableIncome what is left of it after
+	// the personal deduction and the allowances.
+	netIncome      money.Money
+	allowances     []AllowanceDeduction
+	taxableIncome  money.Money
+	donations      []DonationDeduction
+	progressiveTax money.Money
+	alternativeTax money.Money
+	taxMethod      string
+	dividend       *DividendDetails
 }
 
-func NewTaxDetails() CalculateTaxDetails {
+// assessedTax is the tax under the applied method, before any credits.
+func (d CalculateTaxDetails) assessedTax() money.Money {
+	return money.Max(d.progressiveTax, d.alternativeTax)
+}
+
+func NewTaxDetails(brackets []TaxBracket) CalculateTaxDetails {
+	taxLevel := []TaxLevel{}
+	for _, bracket := range brackets {
+		taxLevel = append(taxLevel, TaxLevel{
+			Level: bracket.Label(),
+			Tax:   0,
+		})
+	}
+
 	return CalculateTaxDetails{
 		tax:       0,
 		taxRefund: 0,
-		taxLevel: []TaxLevel{
-			{
-				Level: "0-150,000",
-				Tax:   0.0,
-			},
-			{
-				Level: "150,001-500,000",
-				Tax:   0.0,
-			},
-			{
-				Level: "500,001-1,000,000",
-				Tax:   0.0,
-			},
-			{
-				Level: "1,000,001-2,000,000",
-				Tax:   0.0,
-			},
-			{
-				Level: "2,000,001 ขึ้นไป",
-				Tax:   0.0,
-			},
-		},
+		taxLevel:  taxLevel,
+		taxMethod: TAX_METHOD_PROGRESSIVE,
 	}
 }
 
 func (t TaxCalculator) calculate(info TaxInformation) CalculateTaxDetails {
-	income := t.calDeductedIncome(info)
+	if len(info.Dividends) > 0 {
+		return t.calculateDividends(info)
+	}
+	return t.assess(info, 0)
+}
 
-	details := NewTaxDetails()
+// assess computes the tax of the return, crediting the WHT, the interim tax and
+// dividendCredits, the dividend withholding and tax credit of a dividend claimed on it.
+func (t TaxCalculator) assess(info TaxInformation, dividendCredits money.Money) CalculateTaxDetails {
+	details := NewTaxDetails(t.rules.Brackets)
+	details.income = t.incomeDetails(info)
+	details.netIncome = info.TotalIncome
+	if details.income != nil {
+		details.netIncome = details.income.NetIncome
+	}
+	details.allowances = t.deductAllowances(info, details.netIncome)
+	details.taxableIncome = t.deductedIncome(details.netIncome, details.allowances)
+	details.donations = donationDeductions(info, details.allowances)
 
-	for level, proTax := range progressiveTax {
-		if income > proTax.minIncome {
-			max := (proTax.maxIncome - proTax.minIncome) * proTax.rate
-			tax := math.Min((income-proTax.minIncome)*proTax.rate, max)
-			details.tax += tax
+	income := details.taxableIncome
+	for level, bracket := range t.rules.Brackets {
+		if income > bracket.MinIncome {
+			tax := (money.Min(income, bracket.MaxIncome) - bracket.MinIncome).MulRate(bracket.Rate)
+			details.progressiveTax += tax
 			details.taxLevel[level].Tax = tax
 		}
 	}
 
-	details.taxRefund = t.calTaxRefund(details.tax, info.WHT)
-	details.tax = math.Max(details.tax-info.WHT, 0)
+	details.tax = details.progressiveTax
+	details.alternativeTax = t.calAlternativeTax(info)
+	if details.alternativeTax > details.progressiveTax {
+		details.tax = details.alternativeTax
+		details.taxMethod = TAX_METHOD_ALTERNATIVE
+	}
+
+	credits := info.WHT + info.InterimTax + dividendCredits
+	details.taxRefund = t.calTaxRefund(details.tax, credits)
+	details.tax = money.Max(details.tax-credits, 0)
 
 	return details
 }
 
-func (t TaxCalculator) calDeductedIncome(info TaxInformation) float64 {
-	baseDeduction := info.TotalIncome - t.personalDededucation
+func (t TaxCalculator) calDeductedIncome(info TaxInformation) money.Money {
+	netIncome := t.netIncome(info)
+	return t.deductedIncome(netIncome, t.deductAllowances(info, netIncome))
+}
 
-	kReceiptSum := info.sumAllowanceByType(ACCEPT_ALLOWANCE_TYPES["k-receipt"])
-	donationSum := info.sumAllowanceByType(ACCEPT_ALLOWANCE_TYPES["donation"])
+// deductedIncome is the taxable income left of netIncome after the personal deduction
+// and the allowance deductions.
+func (t TaxCalculator) deductedIncome(netIncome money.Money, deductions []AllowanceDeduction) money.Money {
+	income := netIncome - t.rules.PersonalDeduction
+	for _, deduction := range deductions {
+		income -= deduction.Allowed
+	}
+	return income
+}
 
-	donationDeduction := math.Min(donationSum, MAX_DONATE_DEDUCTION)
-	kRecieptDeduction := math.Min(kReceiptSum, t.maxKReceiptDeduction)
+// netIncome is the assessable income after expenses.
+func (t TaxCalculator) netIncome(info TaxInformation) money.Money {
+	if incomeDetails := t.incomeDetails(info); incomeDetails != nil {
+		return incomeDetails.NetIncome
+	}
+	return info.TotalIncome
+}
 
-	return baseDeduction - donationDeduction - kRecieptDeduction
+// calAlternativeTax computes 0.5% of the gross 40(2)-40(8) income, it is zero when the
+// taxpayer is not subject to the alternative method.
+func (t TaxCalculator) calAlternativeTax(info TaxInformation) money.Money {
+	nonSalaryIncome := info.dividendIncome
+	for _, income := range info.Incomes {
+		if !incomeCategories[income.IncomeType].salary {
+			nonSalaryIncome += income.Amount
+		}
+	}
+
+	if !t.subjectToAlternativeTax(nonSalaryIncome) {
+		return 0
+	}
+
+	tax := nonSalaryIncome.MulRate(ALTERNATIVE_TAX_RATE)
+	if tax <= MAX_ALTERNATIVE_TAX_EXEMPT {
+		return 0
+	}
+
+	return tax
+}
+
+// subjectToAlternativeTax reports whether the 40(2)-40(8) income is above
+// MIN_ALTERNATIVE_TAX_INCOME, halved on the half-year return.
+func (t TaxCalculator) subjectToAlternativeTax(nonSalaryIncome money.Money) bool {
+	minIncome := MIN_ALTERNATIVE_TAX_INCOME
+	if t.rules.HalfYear {
+		minIncome = minIncome.Div(2)
+	}
+	return nonSalaryIncome > minIncome
+}
+
+// cap returns the resolved value of a capped config key.
+func (t TaxCalculator) cap(key string) money.Money {
+	if value, ok := t.rules.Caps[key]; ok {
+		return value
+	}
+	return CapDefaults()[key]
 }
 
-func (t TaxCalculator) calTaxRefund(tax float64, wht float64) float64 {
-	if wht <= tax {
+// calTaxRefund refunds the credits, WHT, interim tax and dividend credits, paid above the tax.
+func (t TaxCalculator) calTaxRefund(tax money.Money, credits money.Money) money.Money {
+	if credits <= tax {
 		return 0
 	}
-	return math.Max(wht-tax, 0)
+	return money.Max(credits-tax, 0)
 }
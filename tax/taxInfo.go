@@ -1,17 +1,125 @@
 package tax
 
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+)
+
 type TaxInformation struct {
-	TotalIncome float64     `json:"totalIncome"`
-	WHT         float64     `json:"wht"`
+	TaxYear int `json:"taxYear"`
+	// TotalIncome is taxed as income already net of expenses unless Incomes are given.
+	TotalIncome money.Money `json:"totalIncome"`
+	Incomes     []Income    `json:"incomes,omitempty"`
+	WHT         money.Money `json:"wht"`
 	Allowances  []Allowance `json:"allowances"`
+	// Period is "full-year" (default) or "half-year" for the PND 94 interim return.
+	Period string `json:"period,omitempty"`
+	// InterimTax is the tax paid on the half-year return, credited like WHT.
+	InterimTax money.Money `json:"interimTax"`
+	Dividends  []Dividend  `json:"dividends,omitempty"`
+	// DividendMethod forces "final-withholding" or "tax-credit", by default the cheaper one applies.
+	DividendMethod string `json:"dividendMethod,omitempty"`
+	// TaxDate selects the config values in force on the date, formatted as YYYY-MM-DD.
+	// It must fall in the tax year and defaults to the day of the calculation, or to the
+	// nearest day of the tax year when the calculation is made outside it.
+	TaxDate string `json:"taxDate,omitempty"`
+
+	// dividendIncome is the grossed-up dividend added to TotalIncome when no Incomes are
+	// given. It is 40(4) income, so it counts toward the alternative tax either way.
+	dividendIncome money.Money
+}
+
+// taxYear returns the requested tax year, falling back to DEFAULT_TAX_YEAR when omitted.
+func (t *TaxInformation) taxYear() int {
+	if t.TaxYear == 0 {
+		return DEFAULT_TAX_YEAR
+	}
+	return t.TaxYear
+}
+
+// taxDate returns the requested tax date, falling back to DefaultTaxDate when omitted or invalid.
+func (t *TaxInformation) taxDate() time.Time {
+	date, err := time.Parse(DATE_FORMAT, t.TaxDate)
+	if err != nil {
+		return DefaultTaxDate(t.taxYear(), time.Now())
+	}
+	return date
+}
+
+// TaxYearDates returns the first and the last day of the tax year, which follows the
+// calendar year.
+func TaxYearDates(taxYear int) (first time.Time, last time.Time) {
+	year := taxYear - BUDDHIST_ERA_OFFSET
+	return time.Date(year, time.January, 1, 0, 0, 0, 0, time.UTC), time.Date(year, time.December, 31, 0, 0, 0, 0, time.UTC)
+}
+
+// DefaultTaxDate is the day of now, moved to the nearest day of the tax year so a past
+// tax year is calculated with the config values in force at its end.
+func DefaultTaxDate(taxYear int, now time.Time) time.Time {
+	first, last := TaxYearDates(taxYear)
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	if today.Before(first) {
+		return first
+	}
+	if today.After(last) {
+		return last
+	}
+	return today
 }
 
-func (t *TaxInformation) sumAllowanceByType(allowanceType string) float64 {
-	kReceiptSum := 0.0
+// validateTaxDate checks the tax date is a date of the tax year, an empty date is valid.
+func validateTaxDate(taxYear int, taxDate string) error {
+	if taxDate == "" {
+		return nil
+	}
+	date, err := time.Parse(DATE_FORMAT, taxDate)
+	if err != nil {
+		return errors.New("taxDate must be formatted as YYYY-MM-DD")
+	}
+	first, last := TaxYearDates(taxYear)
+	if date.Before(first) || date.After(last) {
+		return fmt.Errorf("taxDate must be within tax year %d", taxYear)
+	}
+	return nil
+}
+
+func validateTaxInformation(info TaxInformation) error {
+	if err := validateTaxDate(info.taxYear(), info.TaxDate); err != nil {
+		return err
+	}
+	if err := validateAllowance(info.Allowances); err != nil {
+		return err
+	}
+	if err := validateIncomes(info); err != nil {
+		return err
+	}
+	if err := validatePeriod(info); err != nil {
+		return err
+	}
+	return validateDividends(info)
+}
+
+func (t *TaxInformation) isHalfYear() bool {
+	return t.Period == PERIOD_HALF_YEAR
+}
+
+func (t *TaxInformation) allowancesByType(allowanceType string) []Allowance {
+	claims := []Allowance{}
 	for _, allowance := range t.Allowances {
 		if allowance.AllowanceType == allowanceType {
-			kReceiptSum += allowance.Amount
+			claims = append(claims, allowance)
 		}
 	}
-	return kReceiptSum
+	return claims
+}
+
+// birthYear returns the Buddhist era birth year, converting years given in the Common Era.
+func (a Allowance) birthYear() int {
+	if a.BirthYear > 0 && a.BirthYear < 2400 {
+		return a.BirthYear + BUDDHIST_ERA_OFFSET
+	}
+	return a.BirthYear
 }
@@ -1,6 +1,7 @@
 package tax
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"net/http"
@@ -8,29 +9,179 @@ import (
 	"os"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
+	"time"
 
+	"github.com/bytesbanana/assessment-tax/money"
 	"github.com/bytesbanana/assessment-tax/postgres"
 	"github.com/labstack/echo/v4"
 )
 
 type TestCase struct {
-	Income           float64     `json:"income"`
-	Wht              float64     `json:"wht"`
+	Income           money.Money `json:"income"`
+	Wht              money.Money `json:"wht"`
 	Allowances       []Allowance `json:"allowances"`
-	TaxRefund        float64     `json:"taxRefund"`
-	ExpectedTax      float64     `json:"expectedTax"`
+	TaxRefund        money.Money `json:"taxRefund"`
+	ExpectedTax      money.Money `json:"expectedTax"`
 	ExpectedTaxLevel []TaxLevel  `json:"expectedTaxLevel"`
 }
 
 type StubTaxHandler struct {
-	configs map[string]*postgres.TaxConfig
+	configs     map[string]*postgres.TaxConfig
+	yearConfigs map[int]map[string]*postgres.TaxConfig
+	brackets    []postgres.TaxBracket
+	bracketsErr error
+	// schedules are the scheduled config values, they override configs while in force.
+	schedules []postgres.TaxConfig
+
+	mu          sync.Mutex
+	assessments []postgres.TaxAssessment
+	storeErr    error
 }
 
-func (t *StubTaxHandler) GetTaxConfig(key string) (*postgres.TaxConfig, error) {
+// defaultTaxBrackets is the statutory bracket set, the stub serves it when no brackets are set.
+var defaultTaxBrackets = []TaxBracket{
+	{
+		MinIncome: 0,
+		MaxIncome: money.FromBaht(150_000),
+		Rate:      money.MustParseRate("0"),
+	},
+	{
+		MinIncome: money.FromBaht(150_000),
+		MaxIncome: money.FromBaht(500_000),
+		Rate:      money.MustParseRate("0.10"),
+	},
+	{
+		MinIncome: money.FromBaht(500_000),
+		MaxIncome: money.FromBaht(1_000_000),
+		Rate:      money.MustParseRate("0.15"),
+	},
+	{
+		MinIncome: money.FromBaht(1_000_000),
+		MaxIncome: money.FromBaht(2_000_000),
+		Rate:      money.MustParseRate("0.20"),
+	},
+	{
+		MinIncome: money.FromBaht(2_000_000),
+		MaxIncome: money.Unlimited,
+		Rate:      money.MustParseRate("0.35"),
+	},
+}
+
+func (t *StubTaxHandler) GetTaxRuleSet(taxYear int) (*postgres.TaxRuleSet, error) {
+	if taxYear != DEFAULT_TAX_YEAR && t.yearConfigs[taxYear] == nil {
+		return nil, sql.ErrNoRows
+	}
+	return &postgres.TaxRuleSet{TaxYear: taxYear}, nil
+}
+
+func (t *StubTaxHandler) GetTaxConfig(taxYear int, key string, at time.Time) (*postgres.TaxConfig, error) {
+	for i := len(t.schedules) - 1; i >= 0; i-- {
+		schedule := t.schedules[i]
+		if schedule.Key == key && !at.Before(*schedule.EffectiveFrom) && (schedule.EffectiveTo == nil || !at.After(*schedule.EffectiveTo)) {
+			return &schedule, nil
+		}
+	}
+	if configs, ok := t.yearConfigs[taxYear]; ok {
+		return configs[key], nil
+	}
 	return t.configs[key], nil
 }
 
+func (t *StubTaxHandler) ListTaxConfigs(taxYear int) ([]postgres.TaxConfig, error) {
+	configs, ok := t.yearConfigs[taxYear]
+	if !ok {
+		configs = t.configs
+	}
+	rows := []postgres.TaxConfig{}
+	for key, config := range configs {
+		if config == nil {
+			continue
+		}
+		row := *config
+		row.Key = key
+		rows = append(rows, row)
+	}
+	return append(rows, t.schedules...), nil
+}
+
+func (t *StubTaxHandler) GetTaxBrackets(taxYear int) ([]postgres.TaxBracket, error) {
+	if t.bracketsErr != nil {
+		return nil, t.bracketsErr
+	}
+	if t.brackets == nil {
+		rows := []postgres.TaxBracket{}
+		for _, bracket := range defaultTaxBrackets {
+			row := postgres.TaxBracket{MinIncome: bracket.MinIncome, Rate: bracket.Rate}
+			if bracket.MaxIncome != money.Unlimited {
+				row.MaxIncome = moneyPtr(bracket.MaxIncome)
+			}
+			rows = append(rows, row)
+		}
+		return rows, nil
+	}
+	return t.brackets, nil
+}
+
+func (t *StubTaxHandler) CreateTaxAssessment(assessment postgres.TaxAssessment) (*postgres.TaxAssessment, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.storeErr != nil {
+		return nil, t.storeErr
+	}
+	assessment.ID = len(t.assessments) + 1
+	t.assessments = append(t.assessments, assessment)
+	return &assessment, nil
+}
+
+func (t *StubTaxHandler) CreateTaxAssessments(assessments []postgres.TaxAssessment) ([]postgres.TaxAssessment, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if t.storeErr != nil {
+		return nil, t.storeErr
+	}
+	stored := []postgres.TaxAssessment{}
+	for _, assessment := range assessments {
+		assessment.ID = len(t.assessments) + len(stored) + 1
+		stored = append(stored, assessment)
+	}
+	t.assessments = append(t.assessments, stored...)
+	return stored, nil
+}
+
+func (t *StubTaxHandler) GetTaxAssessment(id int) (*postgres.TaxAssessment, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	if id < 1 || id > len(t.assessments) {
+		return nil, sql.ErrNoRows
+	}
+	return &t.assessments[id-1], nil
+}
+
+func (t *StubTaxHandler) ListTaxAssessments(filter postgres.TaxAssessmentFilter) ([]postgres.TaxAssessment, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	assessments := []postgres.TaxAssessment{}
+	for i := len(t.assessments) - 1; i >= 0; i-- {
+		assessment := t.assessments[i]
+		if (filter.APIKeyID == 0 || (assessment.APIKeyID != nil && *assessment.APIKeyID == filter.APIKeyID)) &&
+			(filter.TaxYear == 0 || assessment.TaxYear == filter.TaxYear) && (filter.Source == "" || assessment.Source == filter.Source) &&
+			(filter.BeforeID == 0 || assessment.ID < filter.BeforeID) {
+			assessments = append(assessments, assessment)
+		}
+	}
+	if filter.Offset >= len(assessments) {
+		return []postgres.TaxAssessment{}, nil
+	}
+	assessments = assessments[filter.Offset:]
+	return assessments[:min(len(assessments), filter.Limit)], nil
+}
+
 func setup(t *testing.T, buildRequestFunc func() *http.Request) (echo.Context, *httptest.ResponseRecorder) {
 	t.Parallel()
 	e := echo.New()
@@ -57,14 +208,6 @@ func loadTestCasesFromFile(filePath string) ([]TestCase, error) {
 	return testCases, nil
 }
 
-func sumAllowances(allowances []Allowance) float64 {
-	sum := 0.0
-	for _, allowance := range allowances {
-		sum += allowance.Amount
-	}
-	return sum
-}
-
 func TestRequestValidtion(t *testing.T) {
 
 	t.Run("given invalid request should return 400", func(t *testing.T) {
@@ -78,9 +221,9 @@ func TestRequestValidtion(t *testing.T) {
 		h := New(&StubTaxHandler{
 			configs: map[string]*postgres.TaxConfig{
 				"PERSONAL_DEDUCTION": {
-					Value: 60_000,
+					Value: money.FromBaht(60_000),
 				}, "MAX_K_RECEIPT_DEDUCTION": {
-					Value: 50_000,
+					Value: money.FromBaht(50_000),
 				},
 			},
 		})
@@ -132,21 +275,21 @@ func TestTotalIncomeTaxCalculation(t *testing.T) {
 	t.Run("total income calculation", func(t *testing.T) {
 
 		for _, tc := range testCases {
-			name := fmt.Sprintf("given total income %.2f should return tax amount %.2f with tax level", tc.Income, tc.ExpectedTax)
+			name := fmt.Sprintf("given total income %v should return tax amount %v with tax level", tc.Income, tc.ExpectedTax)
 			t.Run(name, func(t *testing.T) {
 
 				c, rec := setup(t, func() *http.Request {
-					reqJSON := fmt.Sprintf(`{"totalIncome": %f}`, tc.Income)
+					reqJSON := fmt.Sprintf(`{"totalIncome": %v}`, tc.Income)
 					return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(reqJSON))
 				})
 
 				h := New(&StubTaxHandler{
 					configs: map[string]*postgres.TaxConfig{
 						"PERSONAL_DEDUCTION": {
-							Value: 60_000,
+							Value: money.FromBaht(60_000),
 						},
 						"MAX_K_RECEIPT_DEDUCTION": {
-							Value: 50_000,
+							Value: money.FromBaht(50_000),
 						},
 					},
 				})
@@ -197,7 +340,7 @@ func TestTotalIncomeWHTTaxCalculation(t *testing.T) {
 	t.Run("total income + WHT calculation", func(t *testing.T) {
 
 		for _, tc := range testCases {
-			name := fmt.Sprintf("given total income %.2f and WHT %.2f should return tax amount %.2f",
+			name := fmt.Sprintf("given total income %v and WHT %v should return tax amount %v",
 				tc.Income,
 				tc.Wht,
 				tc.ExpectedTax)
@@ -205,8 +348,8 @@ func TestTotalIncomeWHTTaxCalculation(t *testing.T) {
 
 				c, rec := setup(t, func() *http.Request {
 					reqJSON := fmt.Sprintf(`{
-						"totalIncome": %f,
-						"wht": %f
+						"totalIncome": %v,
+						"wht": %v
 					}`, tc.Income, tc.Wht)
 					return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(reqJSON))
 				})
@@ -214,10 +357,10 @@ func TestTotalIncomeWHTTaxCalculation(t *testing.T) {
 				h := New(&StubTaxHandler{
 					configs: map[string]*postgres.TaxConfig{
 						"PERSONAL_DEDUCTION": {
-							Value: 60_000,
+							Value: money.FromBaht(60_000),
 						},
 						"MAX_K_RECEIPT_DEDUCTION": {
-							Value: 50_000,
+							Value: money.FromBaht(50_000),
 						},
 					},
 				})
@@ -272,7 +415,7 @@ func TestTotalIncomeWithAllowancesTaxCalculation(t *testing.T) {
 				return
 			}
 
-			name := fmt.Sprintf("given total income %.2f and allowances %.2f should return tax amount %.2f",
+			name := fmt.Sprintf("given total income %v and allowances %v should return tax amount %v",
 				tc.Income,
 				sumAllowances(tc.Allowances),
 				tc.ExpectedTax)
@@ -281,8 +424,8 @@ func TestTotalIncomeWithAllowancesTaxCalculation(t *testing.T) {
 
 				c, rec := setup(t, func() *http.Request {
 					reqJSON := fmt.Sprintf(`{
-						"totalIncome": %f,
-						"wht": %f,
+						"totalIncome": %v,
+						"wht": %v,
 						"allowances": %s
 					}`,
 						tc.Income,
@@ -295,10 +438,10 @@ func TestTotalIncomeWithAllowancesTaxCalculation(t *testing.T) {
 				h := New(&StubTaxHandler{
 					configs: map[string]*postgres.TaxConfig{
 						"PERSONAL_DEDUCTION": {
-							Value: 60_000,
+							Value: money.FromBaht(60_000),
 						},
 						"MAX_K_RECEIPT_DEDUCTION": {
-							Value: 50_000,
+							Value: money.FromBaht(50_000),
 						},
 					},
 				})
@@ -337,3 +480,19 @@ func TestTotalIncomeWithAllowancesTaxCalculation(t *testing.T) {
 	})
 
 }
+
+func TestConfigKeys(t *testing.T) {
+	keys := ConfigKeys()
+
+	if len(keys) != len(CapDefaults())+1 {
+		t.Errorf("invalid number of config keys: got %v want %v", len(keys), len(CapDefaults())+1)
+	}
+	if keys[0].Key != "PERSONAL_DEDUCTION" || keys[0].Default != DEFAULT_PERSONAL_DEDUCTION || keys[0].Percent {
+		t.Errorf("invalid personal deduction: got %+v", keys[0])
+	}
+	for _, key := range keys {
+		if key.Percent != (key.Key == "DONATION_INCOME_PERCENT") {
+			t.Errorf("invalid percent of %v: got %v", key.Key, key.Percent)
+		}
+	}
+}
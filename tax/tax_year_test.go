@@ -0,0 +1,181 @@
+package tax
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/bytesbanana/assessment-tax/money"
+	"github.com/bytesbanana/assessment-tax/postgres"
+)
+
+func TestTaxYearRuleSet(t *testing.T) {
+	stub := func() *StubTaxHandler {
+		return &StubTaxHandler{
+			configs: map[string]*postgres.TaxConfig{
+				"PERSONAL_DEDUCTION": {
+					Value: money.FromBaht(60_000),
+				},
+			},
+			yearConfigs: map[int]map[string]*postgres.TaxConfig{
+				2566: {
+					"PERSONAL_DEDUCTION": {
+						Value: money.FromBaht(30_000),
+					},
+					"DONATION_INCOME_PERCENT": {
+						Value: money.MustParse("5"),
+					},
+				},
+			},
+		}
+	}
+
+	t.Run("given tax year with rule set should use the rule set of that year", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			reqJSON := `{
+				"taxYear": 2566,
+				"totalIncome": 500000.0,
+				"allowances": [
+					{
+						"allowanceType": "donation",
+						"amount": 50000.0
+					}
+				]
+			}`
+			return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(reqJSON))
+		})
+
+		err := New(stub()).CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		if rec.Code != http.StatusOK {
+			t.Errorf("invalid status code: got %v want %v",
+				rec.Code, http.StatusOK)
+		}
+
+		res := &TaxCalculationResponse{}
+		err = json.Unmarshal(rec.Body.Bytes(), res)
+		if err != nil {
+			t.Errorf("unable to unmarshal response: %v", err)
+		}
+
+		// 500,000 - 30,000 - 23,500 (5% donation limit) = 446,500
+		if res.Tax != money.FromBaht(29_650) {
+			t.Errorf("invalid tax: got %v want %v", res.Tax, money.FromBaht(29_650))
+		}
+	})
+
+	t.Run("given tax year without rule set should return 400", func(t *testing.T) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"taxYear": 2550, "totalIncome": 500000.0}`))
+		})
+
+		err := New(stub()).CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v",
+				rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestEffectiveDatedConfig(t *testing.T) {
+	calculateTax := func(t *testing.T, reqJSON string) (*httptest.ResponseRecorder, *TaxCalculationResponse) {
+		c, rec := setup(t, func() *http.Request {
+			return httptest.NewRequest(http.MethodPost, "/tax/calculations", strings.NewReader(reqJSON))
+		})
+
+		effectiveFrom := time.Date(2024, time.July, 1, 0, 0, 0, 0, time.UTC)
+		h := New(&StubTaxHandler{
+			configs: map[string]*postgres.TaxConfig{},
+			schedules: []postgres.TaxConfig{
+				{Key: "PERSONAL_DEDUCTION", Value: money.FromBaht(70_000), EffectiveFrom: &effectiveFrom},
+			},
+		})
+		err := h.CalculateTax(c)
+		if err != nil {
+			t.Errorf("unable to calculate tax: %v", err)
+		}
+
+		res := &TaxCalculationResponse{}
+		if rec.Code == http.StatusOK {
+			err = json.Unmarshal(rec.Body.Bytes(), res)
+			if err != nil {
+				t.Errorf("unable to unmarshal response: %v", err)
+			}
+		}
+		return rec, res
+	}
+
+	t.Run("given tax date before the scheduled change should use the base value", func(t *testing.T) {
+		_, res := calculateTax(t, `{"totalIncome": 500000.0, "taxDate": "2024-06-30"}`)
+
+		if res.Tax != money.FromBaht(29_000) {
+			t.Errorf("invalid tax: got %v want %v", res.Tax, money.FromBaht(29_000))
+		}
+	})
+
+	t.Run("given tax date from the scheduled change should use the scheduled value", func(t *testing.T) {
+		_, res := calculateTax(t, `{"totalIncome": 500000.0, "taxDate": "2024-07-01"}`)
+
+		if res.Tax != money.FromBaht(28_000) {
+			t.Errorf("invalid tax: got %v want %v", res.Tax, money.FromBaht(28_000))
+		}
+	})
+
+	t.Run("given invalid tax date should return 400", func(t *testing.T) {
+		rec, _ := calculateTax(t, `{"totalIncome": 500000.0, "taxDate": "01/07/2024"}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+
+	t.Run("given tax date outside the tax year should return 400", func(t *testing.T) {
+		rec, _ := calculateTax(t, `{"totalIncome": 500000.0, "taxDate": "2025-01-01"}`)
+
+		if rec.Code != http.StatusBadRequest {
+			t.Errorf("invalid status code: got %v want %v", rec.Code, http.StatusBadRequest)
+		}
+	})
+}
+
+func TestDefaultTaxDate(t *testing.T) {
+	testCases := []struct {
+		name string
+		now  time.Time
+		want time.Time
+	}{
+		{
+			name: "given calculation after the tax year should use its last day",
+			now:  time.Date(2026, time.March, 15, 10, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.December, 31, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "given calculation during the tax year should use the day of the calculation",
+			now:  time.Date(2024, time.August, 20, 23, 30, 0, 0, time.UTC),
+			want: time.Date(2024, time.August, 20, 0, 0, 0, 0, time.UTC),
+		},
+		{
+			name: "given calculation before the tax year should use its first day",
+			now:  time.Date(2023, time.November, 1, 0, 0, 0, 0, time.UTC),
+			want: time.Date(2024, time.January, 1, 0, 0, 0, 0, time.UTC),
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := DefaultTaxDate(2567, tc.now); !got.Equal(tc.want) {
+				t.Errorf("invalid tax date: got %v want %v", got, tc.want)
+			}
+		})
+	}
+}
@@ -3,6 +3,7 @@ package tax
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"io"
 	"log"
 	"mime/multipart"
@@ -13,6 +14,7 @@ import (
 	"os"
 	"testing"
 
+	"github.com/bytesbanana/assessment-tax/money"
 	"github.com/bytesbanana/assessment-tax/postgres"
 	"github.com/labstack/echo/v4"
 )
@@ -25,8 +27,11 @@ func TestTaxFileUpdaloadCalculation(t *testing.T) {
 	}{
 		Taxes: []TaxCalculationResponse{
 			{
-				Tax:       29000,
-				TaxRefund: 0,
+				AssessmentID:   1,
+				Tax:            money.FromBaht(29000),
+				TaxRefund:      0,
+				ProgressiveTax: money.FromBaht(29000),
+				TaxMethod:      TAX_METHOD_PROGRESSIVE,
 				TaxLevel: []TaxLevel{
 					{
 						Level: "0-150,000",
@@ -34,7 +39,7 @@ func TestTaxFileUpdaloadCalculation(t *testing.T) {
 					},
 					{
 						Level: "150,001-500,000",
-						Tax:   29000,
+						Tax:   money.FromBaht(29000),
 					},
 					{
 						Level: "500,001-1,000,000",
@@ -51,8 +56,11 @@ func TestTaxFileUpdaloadCalculation(t *testing.T) {
 				},
 			},
 			{
-				Tax:       1000,
-				TaxRefund: 0,
+				AssessmentID:   2,
+				Tax:            money.FromBaht(1000),
+				TaxRefund:      0,
+				ProgressiveTax: money.FromBaht(41000),
+				TaxMethod:      TAX_METHOD_PROGRESSIVE,
 				TaxLevel: []TaxLevel{
 					{
 						Level: "0-150,000",
@@ -60,11 +68,11 @@ func TestTaxFileUpdaloadCalculation(t *testing.T) {
 					},
 					{
 						Level: "150,001-500,000",
-						Tax:   35000,
+						Tax:   money.FromBaht(35000),
 					},
 					{
 						Level: "500,001-1,000,000",
-						Tax:   6000,
+						Tax:   money.FromBaht(6000),
 					},
 					{
 						Level: "1,000,001-2,000,000",
@@ -77,8 +85,11 @@ func TestTaxFileUpdaloadCalculation(t *testing.T) {
 				},
 			},
 			{
-				Tax:       13500,
-				TaxRefund: 0,
+				AssessmentID:   3,
+				Tax:            money.FromBaht(13500),
+				TaxRefund:      0,
+				ProgressiveTax: money.FromBaht(63500),
+				TaxMethod:      TAX_METHOD_PROGRESSIVE,
 				TaxLevel: []TaxLevel{
 					{
 						Level: "0-150,000",
@@ -86,11 +97,11 @@ func TestTaxFileUpdaloadCalculation(t *testing.T) {
 					},
 					{
 						Level: "150,001-500,000",
-						Tax:   35000,
+						Tax:   money.FromBaht(35000),
 					},
 					{
 						Level: "500,001-1,000,000",
-						Tax:   28500,
+						Tax:   money.FromBaht(28500),
 					},
 					{
 						Level: "1,000,001-2,000,000",
@@ -137,10 +148,10 @@ func TestTaxFileUpdaloadCalculation(t *testing.T) {
 	h := New(&StubTaxHandler{
 		configs: map[string]*postgres.TaxConfig{
 			"PERSONAL_DEDUCTION": {
-				Value: 60_000,
+				Value: money.FromBaht(60_000),
 			},
 			"MAX_K_RECEIPT_DEDUCTION": {
-				Value: 50_000,
+				Value: money.FromBaht(50_000),
 			},
 		},
 	})
@@ -170,3 +181,35 @@ func TestTaxFileUpdaloadCalculation(t *testing.T) {
 	}
 
 }
+
+func TestTaxFileUploadStoreFailure(t *testing.T) {
+	t.Parallel()
+
+	body := new(bytes.Buffer)
+	writer := multipart.NewWriter(body)
+	part, _ := writer.CreateFormFile("taxFile", "test.csv")
+	part.Write([]byte("totalIncome,wht,donation\n500000.0,0.0,0.0\n600000.0,40000.0,20000.0\n"))
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/tax/calculations/upload-csv", body)
+	req.Header.Set(echo.HeaderContentType, writer.FormDataContentType())
+	rec := httptest.NewRecorder()
+	c := echo.New().NewContext(req, rec)
+	c.SetPath("/tax/calculations/upload-csv")
+
+	stub := &StubTaxHandler{
+		configs:  map[string]*postgres.TaxConfig{},
+		storeErr: errors.New("connection reset"),
+	}
+	err := New(stub).CalculateTaxFromTaxFile(c)
+	if err != nil {
+		t.Errorf("unable to calculate tax from file: %v", err)
+	}
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("invalid http status: got %v want %v", rec.Code, http.StatusInternalServerError)
+	}
+	if len(stub.assessments) != 0 {
+		t.Errorf("invalid stored assessments: got %v want none", len(stub.assessments))
+	}
+}